@@ -0,0 +1,25 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+)
+
+// What's rendered on the about page, Body is empty when the default in about.html should be shown
+type AboutPage struct {
+	Body template.HTML
+}
+
+// Serves /about/, the Markdown ABOUT_FILE names if it's set, or else the default written into about.html
+func (b *Blog) aboutHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != ABOUT {
+		b.notFoundHandler(w, r)
+		return
+	}
+
+	var page AboutPage
+	if b.config.About != "" {
+		page.Body = RenderMarkdown(b.config.About, b.config.HTMLPolicy)
+	}
+	b.renderTemplate(w, r, "about.html", page)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAboutPage(t *testing.T) {
+	tests := []struct {
+		name    string
+		about   string
+		want    string
+		notWant string
+	}{
+		{name: "default", want: "go-blog is a small blog written in Go"},
+		{name: "from ABOUT_FILE", about: "# About me\n\nI <script>alert(1)</script>write **Go**", want: "<strong>Go</strong>", notWant: "<script>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig(t)
+			config.About = tt.about
+			router := newRouter(newFakeBlog(t, config))
+
+			w := do(router, httptest.NewRequest(http.MethodGet, ABOUT, nil))
+			if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("GET %s responded %d, want %d containing %s:\n%s", ABOUT, w.Code, http.StatusOK, tt.want, w.Body.String())
+			}
+			if tt.notWant != "" && strings.Contains(w.Body.String(), tt.notWant) {
+				t.Errorf("GET %s contains %s", ABOUT, tt.notWant)
+			}
+			if w := do(router, httptest.NewRequest(http.MethodGet, ABOUT+"more", nil)); w.Code != http.StatusNotFound {
+				t.Errorf("GET %smore responded %d, want %d", ABOUT, w.Code, http.StatusNotFound)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Type used to parse the admin dashboard template
+type AdminPage struct {
+	Posts     []Post // Drafts and deleted posts included, most recently edited first
+	CSRFToken string // For the restore and purge forms
+	Flash     string
+}
+
+// Lists every post for authors, drafts included, with links to edit and delete each
+func (b *Blog) adminHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != ADMIN {
+		b.notFoundHandler(w, r)
+		return
+	}
+
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	posts, err := b.store.List(ctx, true)
+	if err != nil {
+		b.renderError(w, r, dbErrorStatus(err), fmt.Errorf("failed to list posts for the dashboard: %w", err))
+		return
+	}
+	b.prepare(posts)
+
+	token, err := csrfToken(w, r)
+	if err != nil {
+		b.renderError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to generate a CSRF token: %w", err))
+		return
+	}
+
+	// Authors expect to see their changes straight away, so never serve this from a cache
+	w.Header().Set("Cache-Control", "no-store")
+	b.renderTemplate(w, r, "admin.html", AdminPage{Posts: posts, CSRFToken: token, Flash: takeFlash(w, r)})
+}
+
+// Brings back the deleted post whose slug follows ADMIN_RESTORE in the url
+func (b *Blog) adminRestoreHandler(w http.ResponseWriter, r *http.Request) {
+	b.changeDeletedPost(w, r, ADMIN_RESTORE, b.store.Restore, "The post was restored")
+}
+
+// Permanently removes the deleted post whose slug follows ADMIN_PURGE in the url
+func (b *Blog) adminPurgeHandler(w http.ResponseWriter, r *http.Request) {
+	b.changeDeletedPost(w, r, ADMIN_PURGE, b.store.Purge, "The post was deleted for good")
+}
+
+// Runs change on the deleted post whose slug follows prefix, then heads back to the dashboard with done as the flash
+func (b *Blog) changeDeletedPost(w http.ResponseWriter, r *http.Request, prefix string, change func(ctx context.Context, slug string) error, done string) {
+	slug := strings.TrimPrefix(r.URL.Path, prefix)
+	if slug == "" || strings.Contains(slug, "/") {
+		b.notFoundHandler(w, r)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "The form couldn't be read, please go back and try again.", http.StatusBadRequest)
+		return
+	}
+	if !validCSRF(r) {
+		http.Error(w, "This form has expired, please go back, refresh and try again.", http.StatusForbidden)
+		return
+	}
+
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	err := change(ctx, slug)
+	if errors.Is(err, errPostNotFound) {
+		b.generateResulTemplate(w, r, http.StatusNotFound, &CRUDResult{Message: "Sorry! There's no deleted post with that slug"})
+		return
+	}
+	if err != nil {
+		logf(ctx, "Failed to change deleted post %q: %v", slug, err)
+		b.generateResulTemplate(w, r, dbErrorStatus(err), &CRUDResult{Message: "Sorry! Something went wrong saving your changes, please try again"})
+		return
+	}
+	b.cache.invalidate()
+
+	setFlash(w, done)
+	http.Redirect(w, r, ADMIN, http.StatusSeeOther)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdminDashboard(t *testing.T) {
+	b := newFakeBlog(t, testConfig(t))
+	createLivePost(t, b.store, "live")
+	createPost(t, b.store, Post{Header: "Half written", Content: "Not yet", Slug: "half-written", Author: "Tester"})
+	time.Sleep(time.Millisecond)
+	if err := b.store.Update(context.Background(), Post{Header: "Post live, edited", Content: "Changed", Slug: "live", Author: "Tester"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	router := newRouter(b)
+
+	w := do(router, adminRequest(http.MethodGet, ADMIN, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s responded %d, want %d", ADMIN, w.Code, http.StatusOK)
+	}
+	body := w.Body.String()
+	for _, want := range []string{"Half written", "Draft", EDIT + "half-written", DELETE + "half-written", EDIT + "live", DELETE + "live", "Published"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("GET %s is missing %q", ADMIN, want)
+		}
+	}
+	if edited, draft := strings.Index(body, "Post live, edited"), strings.Index(body, "Half written"); edited > draft {
+		t.Errorf("GET %s lists the draft before the post edited since, want the most recently edited first", ADMIN)
+	}
+	if got := w.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("GET %s Cache-Control = %q, want no-store", ADMIN, got)
+	}
+
+	if body := do(router, httptest.NewRequest(http.MethodGet, HOME, nil)).Body.String(); strings.Contains(body, "Half written") {
+		t.Errorf("GET %s shows the draft", HOME)
+	}
+	if w := do(router, httptest.NewRequest(http.MethodGet, ADMIN, nil)); w.Code != http.StatusUnauthorized {
+		t.Errorf("GET %s without logging in responded %d, want %d", ADMIN, w.Code, http.StatusUnauthorized)
+	}
+	if w := do(router, adminRequest(http.MethodGet, ADMIN+"nothing-here", nil)); w.Code != http.StatusNotFound {
+		t.Errorf("GET %snothing-here responded %d, want %d", ADMIN, w.Code, http.StatusNotFound)
+	}
+}
+
+func TestRestoreAndPurge(t *testing.T) {
+	b := newTestBlog(t, testConfig(t))
+	createLivePost(t, b.store, "oops")
+	router := newRouter(b)
+	onHome := func() bool {
+		return strings.Contains(do(router, httptest.NewRequest(http.MethodGet, HOME, nil)).Body.String(), POST+"oops")
+	}
+
+	if w := do(router, formRequest(SAVE+SAVE_DELETE, url.Values{"slug": {"oops"}})); w.Code != http.StatusSeeOther {
+		t.Fatalf("POST %s responded %d, want %d", SAVE+SAVE_DELETE, w.Code, http.StatusSeeOther)
+	}
+	if onHome() {
+		t.Errorf("GET %s still shows the deleted post", HOME)
+	}
+	if body := do(router, adminRequest(http.MethodGet, ADMIN, nil)).Body.String(); !strings.Contains(body, "Deleted") || !strings.Contains(body, `action="`+ADMIN_RESTORE+`oops"`) {
+		t.Errorf("GET %s doesn't offer to restore the deleted post", ADMIN)
+	}
+
+	// Without the CSRF token first, which mustn't bring it back
+	req := adminRequest(http.MethodPost, ADMIN_RESTORE+"oops", nil)
+	if w := do(router, req); w.Code != http.StatusForbidden {
+		t.Errorf("POST %soops without a CSRF token responded %d, want %d", ADMIN_RESTORE, w.Code, http.StatusForbidden)
+	}
+	w := do(router, formRequest(ADMIN_RESTORE+"oops", url.Values{}))
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != ADMIN {
+		t.Fatalf("POST %soops responded %d to %q, want %d to %s", ADMIN_RESTORE, w.Code, w.Header().Get("Location"), http.StatusSeeOther, ADMIN)
+	}
+	if !onHome() {
+		t.Errorf("GET %s doesn't show the restored post", HOME)
+	}
+
+	// Only deleted posts can be purged
+	if w := do(router, formRequest(ADMIN_PURGE+"oops", url.Values{})); w.Code != http.StatusNotFound {
+		t.Errorf("POST %soops on a post that isn't deleted responded %d, want %d", ADMIN_PURGE, w.Code, http.StatusNotFound)
+	}
+	do(router, formRequest(SAVE+SAVE_DELETE, url.Values{"slug": {"oops"}}))
+	if w := do(router, formRequest(ADMIN_PURGE+"oops", url.Values{})); w.Code != http.StatusSeeOther {
+		t.Fatalf("POST %soops responded %d, want %d", ADMIN_PURGE, w.Code, http.StatusSeeOther)
+	}
+	if body := do(router, adminRequest(http.MethodGet, ADMIN, nil)).Body.String(); strings.Contains(body, "oops") {
+		t.Errorf("GET %s still lists the purged post", ADMIN)
+	}
+	if w := do(router, formRequest(ADMIN_RESTORE+"oops", url.Values{})); w.Code != http.StatusNotFound {
+		t.Errorf("POST %soops after purging it responded %d, want %d", ADMIN_RESTORE, w.Code, http.StatusNotFound)
+	}
+}
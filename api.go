@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const MAX_API_PAGE_LIMIT = 100 // The most posts /api/posts will list at once, larger ?limit= values are clamped to it
+
+// A page of posts from /api/posts, with enough alongside it to fetch the rest
+type apiPostList struct {
+	Data       []Post `json:"data"`
+	Page       int    `json:"page"`
+	Limit      int    `json:"limit"`
+	TotalPages int    `json:"total_pages"`
+	Total      int    `json:"total"`
+}
+
+// Handles /api/posts, GET lists a page of published posts newest first and POST creates one from a JSON body
+func (b *Blog) apiPostsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		if !b.apiCheckAdmin(w, r) || !readBody(w, r, b.config.MaxBodySize) {
+			return
+		}
+		b.apiCreatePost(w, r)
+		return
+	}
+
+	limit := b.config.PostsPerPage
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 1 {
+			writeJSONError(w, r, http.StatusBadRequest, "The limit must be a whole number of posts, at least 1.")
+			return
+		}
+		if limit > MAX_API_PAGE_LIMIT {
+			limit = MAX_API_PAGE_LIMIT
+		}
+	}
+	page := requestedPage(r)
+
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	homePage, err := b.store.Page(ctx, page, limit, SORT_NEWEST)
+	if err != nil {
+		logf(ctx, "Failed to load page %d of posts: %v", page, err)
+		writeJSONError(w, r, dbErrorStatus(err), "Failed to load the posts.")
+		return
+	}
+	b.prepare(homePage.Posts)
+	list := apiPostList{
+		Data:       homePage.Posts,
+		Page:       homePage.CurrentPage,
+		Limit:      limit,
+		TotalPages: homePage.TotalPages,
+		Total:      homePage.TotalPosts,
+	}
+	if list.Data == nil {
+		// So an empty page is [] rather than null
+		list.Data = []Post{}
+	}
+	writeJSON(w, r, http.StatusOK, list)
+}
+
+// Handles /api/posts/<slug>, reading, updating or deleting that single post
+func (b *Blog) apiPostHandler(w http.ResponseWriter, r *http.Request) {
+	slug := strings.ToLower(strings.TrimPrefix(r.URL.Path, API_POSTS+"/"))
+	if slug == "" || strings.Contains(slug, "/") {
+		writeJSONError(w, r, http.StatusNotFound, "Post not found.")
+		return
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead && (!b.apiCheckAdmin(w, r) || !readBody(w, r, b.config.MaxBodySize)) {
+		return
+	}
+
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodPut:
+		b.apiUpdatePost(ctx, w, r, slug)
+	case http.MethodDelete:
+		b.apiDeletePost(ctx, w, r, slug)
+	default:
+		p, found, err := b.store.Get(ctx, slug)
+		if err != nil {
+			logf(ctx, "Failed to load post %q: %v", slug, err)
+			writeJSONError(w, r, dbErrorStatus(err), "Failed to load the post.")
+			return
+		}
+		if !found || !p.Live() {
+			writeJSONError(w, r, http.StatusNotFound, "Post not found.")
+			return
+		}
+		p.prepareForDisplay(b.config.HTMLPolicy)
+		writeJSON(w, r, http.StatusOK, p)
+	}
+}
+
+// Handles /api/stats, counting posts and views in the DB rather than loading them all
+func (b *Blog) apiStatsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	stats, err := b.store.Stats(ctx)
+	if err != nil {
+		logf(ctx, "Failed to count posts: %v", err)
+		writeJSONError(w, r, dbErrorStatus(err), "Failed to load the stats.")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, stats)
+}
+
+// What /api/slug-available answers with, the slug as it would be saved and whether a post already has it
+type slugAvailability struct {
+	Slug      string `json:"slug"`
+	Available bool   `json:"available"`
+}
+
+// Handles /api/slug-available?slug=..., for the post form to say whether a slug is free while the author types.
+// exclude names the post being edited, so its own slug counts as free
+func (b *Blog) apiSlugAvailableHandler(w http.ResponseWriter, r *http.Request) {
+	if !b.apiCheckAdmin(w, r) {
+		return
+	}
+
+	slug, err := normalizeSlug(r.URL.Query().Get("slug"))
+	if err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "The slug isn't valid, "+err.Error()+".")
+		return
+	}
+	if exclude, err := normalizeSlug(r.URL.Query().Get("exclude")); err == nil && exclude == slug {
+		writeJSON(w, r, http.StatusOK, slugAvailability{Slug: slug, Available: true})
+		return
+	}
+
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	writeJSON(w, r, http.StatusOK, slugAvailability{Slug: slug, Available: !b.store.SlugExists(ctx, slug)})
+}
+
+func (b *Blog) apiCreatePost(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	var post Post
+	if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "Request body must be a JSON post.")
+		return
+	}
+	if post.Header == "" || post.Content == "" || post.Slug == "" {
+		writeJSONError(w, r, http.StatusBadRequest, "A post needs a header, content and slug.")
+		return
+	}
+	slug, err := normalizeSlug(post.Slug)
+	if err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "The slug isn't valid, "+err.Error()+".")
+		return
+	}
+	post.Slug = slug
+	post.Author = postAuthor(r, post.Author)
+	post.Tags = normalizeTags(post.Tags)
+	if err := validatePost(post); err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "The post is too long, "+err.Error()+".")
+		return
+	}
+	canonicalURL, err := parseCanonicalURL(post.CanonicalURL)
+	if err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "The post isn't valid, "+err.Error()+".")
+		return
+	}
+	post.CanonicalURL = canonicalURL
+
+	err = b.store.Create(ctx, post)
+	if errors.Is(err, errSlugTaken) {
+		writeJSONError(w, r, http.StatusConflict, "A post with that slug already exists.")
+		return
+	}
+	if err != nil {
+		logf(ctx, "Failed to create post %q: %v", post.Slug, err)
+		writeJSONError(w, r, dbErrorStatus(err), "Failed to save the post.")
+		return
+	}
+	b.cache.invalidate()
+	if err := b.announceLive(ctx); err != nil {
+		logf(ctx, "Failed to check for posts going live: %v", err)
+	}
+
+	b.apiWriteStoredPost(ctx, w, r, post.Slug, http.StatusCreated)
+}
+
+func (b *Blog) apiUpdatePost(ctx context.Context, w http.ResponseWriter, r *http.Request, slug string) {
+	var post Post
+	if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "Request body must be a JSON post.")
+		return
+	}
+	if post.Header == "" || post.Content == "" {
+		writeJSONError(w, r, http.StatusBadRequest, "A post needs a header and content.")
+		return
+	}
+	// The slug in the url always wins, slugs can't be changed
+	post.Slug = slug
+	post.Author = postAuthor(r, post.Author)
+	post.Tags = normalizeTags(post.Tags)
+	if err := validatePost(post); err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "The post is too long, "+err.Error()+".")
+		return
+	}
+	canonicalURL, err := parseCanonicalURL(post.CanonicalURL)
+	if err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "The post isn't valid, "+err.Error()+".")
+		return
+	}
+	post.CanonicalURL = canonicalURL
+
+	err = b.store.Update(ctx, post)
+	if errors.Is(err, errPostNotFound) {
+		writeJSONError(w, r, http.StatusNotFound, "Post not found.")
+		return
+	}
+	if errors.Is(err, errPostConflict) {
+		writeJSONError(w, r, http.StatusConflict, "The post has changed since its updated_at, fetch it again and reapply your changes.")
+		return
+	}
+	if err != nil {
+		logf(ctx, "Failed to update post %q: %v", slug, err)
+		writeJSONError(w, r, dbErrorStatus(err), "Failed to save the post.")
+		return
+	}
+	b.cache.invalidate()
+
+	b.apiWriteStoredPost(ctx, w, r, slug, http.StatusOK)
+}
+
+func (b *Blog) apiDeletePost(ctx context.Context, w http.ResponseWriter, r *http.Request, slug string) {
+	err := b.store.Delete(ctx, slug)
+	if errors.Is(err, errPostNotFound) {
+		writeJSONError(w, r, http.StatusNotFound, "Post not found.")
+		return
+	}
+	if err != nil {
+		logf(ctx, "Failed to delete post %q: %v", slug, err)
+		writeJSONError(w, r, dbErrorStatus(err), "Failed to delete the post.")
+		return
+	}
+	b.cache.invalidate()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Responds with the post as it is now stored, so clients see the timestamps the DB assigned
+func (b *Blog) apiWriteStoredPost(ctx context.Context, w http.ResponseWriter, r *http.Request, slug string, status int) {
+	p, found, err := b.store.Get(ctx, slug)
+	if err != nil || !found {
+		logf(ctx, "Failed to reload post %q after saving: %v", slug, err)
+		writeJSONError(w, r, http.StatusInternalServerError, "The post was saved but could not be reloaded.")
+		return
+	}
+	p.prepareForDisplay(b.config.HTMLPolicy)
+	writeJSON(w, r, status, p)
+}
+
+// The body of every error the API responds with, so clients can always parse it as JSON
+type apiError struct {
+	Error  string `json:"error"`
+	Status int    `json:"status"`
+}
+
+func writeJSONError(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	writeJSON(w, r, status, apiError{Error: msg, Status: status})
+}
+
+// Like checkAdmin, but answers the 401 in JSON
+func (b *Blog) apiCheckAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if b.isAdmin(r) {
+		return true
+	}
+	w.Header().Set("WWW-Authenticate", AUTH_CHALLENGE)
+	writeJSONError(w, r, http.StatusUnauthorized, "You need to log in to do that.")
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logf(r.Context(), "Failed to encode JSON response: %v", err)
+	}
+}
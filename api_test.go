@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A request to the JSON API with body as its JSON, logged in as the admin
+func apiRequest(method, target, body string) *http.Request {
+	req := adminRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+// Decodes the JSON w responded with into v, failing the test if it isn't JSON
+func decodeJSON(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if got := w.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+	if err := json.NewDecoder(w.Body).Decode(v); err != nil {
+		t.Fatalf("the response isn't JSON: %v", err)
+	}
+}
+
+func TestAPIPosts(t *testing.T) {
+	b := newTestBlog(t, testConfig(t))
+	router := newRouter(b)
+
+	var p Post
+	w := do(router, apiRequest(http.MethodPost, API_POSTS, `{"header": "Created", "content": "Over the **API**", "slug": "created", "published": true}`))
+	decodeJSON(t, w, &p)
+	if w.Code != http.StatusCreated || p.Slug != "created" || p.CreatedAt.IsZero() {
+		t.Errorf("POST %s responded %d %+v, want the created post as saved", API_POSTS, w.Code, p)
+	}
+	if w := do(router, apiRequest(http.MethodPost, API_POSTS, `{"header": "Again", "content": "Words", "slug": "created"}`)); w.Code != http.StatusConflict {
+		t.Errorf("POST %s with a taken slug responded %d, want %d", API_POSTS, w.Code, http.StatusConflict)
+	}
+
+	var list apiPostList
+	decodeJSON(t, do(router, httptest.NewRequest(http.MethodGet, API_POSTS, nil)), &list)
+	if len(list.Data) != 1 || list.Data[0].Slug != "created" {
+		t.Errorf("GET %s listed %+v, want just the created post", API_POSTS, list.Data)
+	}
+
+	w = do(router, httptest.NewRequest(http.MethodGet, API_POSTS+"/created", nil))
+	decodeJSON(t, w, &p)
+	if w.Code != http.StatusOK || !strings.Contains(string(p.Body), "<strong>API</strong>") {
+		t.Errorf("GET %s/created responded %d %+v, want the post with its HTML", API_POSTS, w.Code, p)
+	}
+
+	w = do(router, apiRequest(http.MethodPut, API_POSTS+"/created", `{"header": "Updated", "content": "Changed"}`))
+	decodeJSON(t, w, &p)
+	if w.Code != http.StatusOK || p.Header != "Updated" || p.Content != "Changed" {
+		t.Errorf("PUT %s/created responded %d %+v, want the updated post", API_POSTS, w.Code, p)
+	}
+
+	if w := do(router, apiRequest(http.MethodDelete, API_POSTS+"/created", "")); w.Code != http.StatusNoContent {
+		t.Errorf("DELETE %s/created responded %d, want %d", API_POSTS, w.Code, http.StatusNoContent)
+	}
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, API_POSTS+"/created", nil),
+		apiRequest(http.MethodPut, API_POSTS+"/created", `{"header": "Updated", "content": "Changed"}`),
+		apiRequest(http.MethodDelete, API_POSTS+"/created", ""),
+	} {
+		if w := do(router, req); w.Code != http.StatusNotFound {
+			t.Errorf("%s %s responded %d after it was deleted, want %d", req.Method, req.URL.Path, w.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestAPIRejectsBadPosts(t *testing.T) {
+	// Nothing that's rejected should get as far as the DB
+	b := newClosedDBBlog(t)
+	router := newRouter(b)
+
+	for _, req := range []*http.Request{
+		apiRequest(http.MethodPost, API_POSTS, `not json`),
+		apiRequest(http.MethodPost, API_POSTS, `{"header": "No content or slug"}`),
+		apiRequest(http.MethodPut, API_POSTS+"/a-post", `{"header": "No content"}`),
+	} {
+		if w := do(router, req); w.Code != http.StatusBadRequest {
+			t.Errorf("%s %s responded %d, want %d", req.Method, req.URL.Path, w.Code, http.StatusBadRequest)
+		}
+	}
+	if w := do(router, httptest.NewRequest(http.MethodGet, API_POSTS+"/a/b", nil)); w.Code != http.StatusNotFound {
+		t.Errorf("GET %s/a/b responded %d, want %d", API_POSTS, w.Code, http.StatusNotFound)
+	}
+}
+
+func TestAPIErrors(t *testing.T) {
+	b := newFakeBlog(t, testConfig(t))
+	createLivePost(t, b.store, "live")
+	router := newRouter(b)
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{name: "missing post", req: httptest.NewRequest(http.MethodGet, API_POSTS+"/never-written", nil), want: http.StatusNotFound},
+		{name: "nested path", req: httptest.NewRequest(http.MethodGet, API_POSTS+"/live/more", nil), want: http.StatusNotFound},
+		{name: "not JSON", req: apiRequest(http.MethodPost, API_POSTS, `header=H`), want: http.StatusBadRequest},
+		{name: "missing fields", req: apiRequest(http.MethodPost, API_POSTS, `{"header": "Only a header"}`), want: http.StatusBadRequest},
+		{name: "bad slug", req: apiRequest(http.MethodPost, API_POSTS, `{"header": "H", "content": "C", "slug": "!!!"}`), want: http.StatusBadRequest},
+		{name: "update missing post", req: apiRequest(http.MethodPut, API_POSTS+"/never-written", `{"header": "H", "content": "C"}`), want: http.StatusNotFound},
+		{name: "not logged in", req: httptest.NewRequest(http.MethodDelete, API_POSTS+"/live", nil), want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := do(router, tt.req)
+			if w.Code != tt.want {
+				t.Errorf("%s %s responded %d, want %d", tt.req.Method, tt.req.URL, w.Code, tt.want)
+			}
+			// Exactly the documented fields, so clients can rely on the shape
+			var got map[string]interface{}
+			decodeJSON(t, w, &got)
+			message, _ := got["error"].(string)
+			if len(got) != 2 || message == "" || got["status"] != float64(tt.want) {
+				t.Errorf("%s %s responded %v, want {\"error\": <message>, \"status\": %d}", tt.req.Method, tt.req.URL, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIDBErrors(t *testing.T) {
+	router := newRouter(newClosedDBBlog(t))
+
+	for _, path := range []string{API_POSTS, API_POSTS + "/live"} {
+		w := do(router, httptest.NewRequest(http.MethodGet, path, nil))
+		var got apiError
+		decodeJSON(t, w, &got)
+		if w.Code != http.StatusInternalServerError || got.Status != http.StatusInternalServerError || got.Error == "" {
+			t.Errorf("GET %s with the DB down responded %d %+v, want %d as a JSON error", path, w.Code, got, http.StatusInternalServerError)
+		}
+		if strings.Contains(got.Error, "sql") {
+			t.Errorf("GET %s with the DB down leaked the DB error %q", path, got.Error)
+		}
+	}
+}
+
+func TestAPIStats(t *testing.T) {
+	b := newTestBlog(t, testConfig(t))
+	ctx := context.Background()
+	router := newRouter(b)
+
+	var empty PostStats
+	decodeJSON(t, do(router, httptest.NewRequest(http.MethodGet, API_STATS, nil)), &empty)
+	if empty != (PostStats{}) {
+		t.Errorf("GET %s with no posts = %+v, want every count 0 and no newest post", API_STATS, empty)
+	}
+
+	newest := time.Now().Add(-time.Hour).Truncate(time.Second)
+	createPost(t, b.store, Post{Header: "Old", Content: "Words", Slug: "old", Author: "Tester", Published: true, PublishedAt: newest.Add(-24 * time.Hour)})
+	createPost(t, b.store, Post{Header: "Newest", Content: "Words", Slug: "newest", Author: "Tester", Published: true, PublishedAt: newest})
+	createPost(t, b.store, Post{Header: "Later", Content: "Words", Slug: "later", Author: "Tester", Published: true, PublishedAt: time.Now().Add(24 * time.Hour)})
+	createPost(t, b.store, Post{Header: "Draft", Content: "Words", Slug: "draft", Author: "Tester"})
+	createLivePost(t, b.store, "deleted")
+	if err := b.store.Delete(ctx, "deleted"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	for _, slug := range []string{"old", "newest", "newest"} {
+		if _, err := b.store.RecordView(ctx, slug); err != nil {
+			t.Fatalf("RecordView(%q): %v", slug, err)
+		}
+	}
+
+	var stats PostStats
+	w := do(router, httptest.NewRequest(http.MethodGet, API_STATS, nil))
+	decodeJSON(t, w, &stats)
+	if w.Code != http.StatusOK {
+		t.Errorf("GET %s responded %d, want %d", API_STATS, w.Code, http.StatusOK)
+	}
+	want := PostStats{Posts: 4, Published: 2, Scheduled: 1, Drafts: 1, Views: 3}
+	newestPost := stats.NewestPost
+	stats.NewestPost = nil
+	if stats != want {
+		t.Errorf("GET %s = %+v, want %+v", API_STATS, stats, want)
+	}
+	if newestPost == nil || !newestPost.Equal(newest) {
+		t.Errorf("GET %s newest post = %v, want %v", API_STATS, newestPost, newest)
+	}
+}
+
+func TestAPISlugAvailable(t *testing.T) {
+	b := newFakeBlog(t, testConfig(t))
+	createLivePost(t, b.store, "taken")
+	createPost(t, b.store, Post{Header: "Draft", Content: "Not yet", Slug: "draft", Author: "Tester"})
+	router := newRouter(b)
+
+	tests := []struct {
+		name  string
+		query string
+		want  slugAvailability
+	}{
+		{name: "available", query: "?slug=free", want: slugAvailability{Slug: "free", Available: true}},
+		{name: "taken", query: "?slug=taken", want: slugAvailability{Slug: "taken"}},
+		{name: "taken by a draft", query: "?slug=draft", want: slugAvailability{Slug: "draft"}},
+		{name: "normalized first", query: "?slug=Taken", want: slugAvailability{Slug: "taken"}},
+		{name: "own slug", query: "?slug=taken&exclude=taken", want: slugAvailability{Slug: "taken", Available: true}},
+		{name: "own slug normalized", query: "?slug=TAKEN&exclude=Taken", want: slugAvailability{Slug: "taken", Available: true}},
+		{name: "someone else's slug", query: "?slug=taken&exclude=draft", want: slugAvailability{Slug: "taken"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := do(router, adminRequest(http.MethodGet, API_SLUG_AVAILABLE+tt.query, nil))
+			var got slugAvailability
+			decodeJSON(t, w, &got)
+			if w.Code != http.StatusOK || got != tt.want {
+				t.Errorf("GET %s%s responded %d %+v, want %d %+v", API_SLUG_AVAILABLE, tt.query, w.Code, got, http.StatusOK, tt.want)
+			}
+		})
+	}
+
+	if w := do(router, adminRequest(http.MethodGet, API_SLUG_AVAILABLE+"?slug=", nil)); w.Code != http.StatusBadRequest {
+		t.Errorf("GET %s?slug= responded %d, want %d", API_SLUG_AVAILABLE, w.Code, http.StatusBadRequest)
+	}
+	if w := do(router, httptest.NewRequest(http.MethodGet, API_SLUG_AVAILABLE+"?slug=taken", nil)); w.Code != http.StatusUnauthorized {
+		t.Errorf("GET %s without logging in responded %d, want %d", API_SLUG_AVAILABLE, w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestAPIPagination(t *testing.T) {
+	b := newFakeBlog(t, testConfig(t))
+	router := newRouter(b)
+
+	w := do(router, httptest.NewRequest(http.MethodGet, API_POSTS, nil))
+	if !strings.Contains(w.Body.String(), `"data":[]`) {
+		t.Errorf("GET %s with no posts = %s, want data to be an empty list", API_POSTS, w.Body.String())
+	}
+
+	published := time.Now().Add(-time.Hour)
+	for i, slug := range []string{"one", "two", "three", "four", "five"} {
+		createPost(t, b.store, Post{Header: slug, Content: "Words", Slug: slug, Author: "Tester", Published: true, PublishedAt: published.Add(time.Duration(i) * time.Minute)})
+	}
+
+	tests := []struct {
+		query string
+		want  apiPostList // Data is compared by slug
+		slugs []string
+	}{
+		{query: "?limit=2", want: apiPostList{Page: 1, Limit: 2, TotalPages: 3, Total: 5}, slugs: []string{"five", "four"}},
+		{query: "?limit=2&page=2", want: apiPostList{Page: 2, Limit: 2, TotalPages: 3, Total: 5}, slugs: []string{"three", "two"}},
+		{query: "?limit=2&page=3", want: apiPostList{Page: 3, Limit: 2, TotalPages: 3, Total: 5}, slugs: []string{"one"}},
+		// Past the end gets the last page, and garbage pages the first, like the homepage
+		{query: "?limit=2&page=9", want: apiPostList{Page: 3, Limit: 2, TotalPages: 3, Total: 5}, slugs: []string{"one"}},
+		{query: "?limit=2&page=nope", want: apiPostList{Page: 1, Limit: 2, TotalPages: 3, Total: 5}, slugs: []string{"five", "four"}},
+		{query: "?limit=100000", want: apiPostList{Page: 1, Limit: MAX_API_PAGE_LIMIT, TotalPages: 1, Total: 5}, slugs: []string{"five", "four", "three", "two", "one"}},
+	}
+	for _, tt := range tests {
+		w := do(router, httptest.NewRequest(http.MethodGet, API_POSTS+tt.query, nil))
+		var got apiPostList
+		decodeJSON(t, w, &got)
+		if gotSlugs := slugs(got.Data); !reflect.DeepEqual(gotSlugs, tt.slugs) {
+			t.Errorf("GET %s%s listed %v, want %v", API_POSTS, tt.query, gotSlugs, tt.slugs)
+		}
+		got.Data = nil
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GET %s%s = %+v, want %+v", API_POSTS, tt.query, got, tt.want)
+		}
+	}
+
+	for _, query := range []string{"?limit=0", "?limit=-1", "?limit=lots"} {
+		if w := do(router, httptest.NewRequest(http.MethodGet, API_POSTS+query, nil)); w.Code != http.StatusBadRequest {
+			t.Errorf("GET %s%s responded %d, want %d", API_POSTS, query, w.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Type used to parse templates on the archive page
+type ArchivePage struct {
+	Years []ArchiveYear // Newest first
+	Total int           // Posts across every year
+}
+
+// A year of the archive, with the months it had posts in
+type ArchiveYear struct {
+	Year   int
+	Count  int            // Posts across the whole year
+	Months []ArchiveMonth // Newest first
+}
+
+// A month of the archive, with the posts published in it
+type ArchiveMonth struct {
+	Month time.Time // The first of the month, in the DISPLAY_TIMEZONE
+	Posts []Post    // Newest first
+}
+
+// Lists every published post at /archive/, grouped by the year and month it was published
+func (b *Blog) archiveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != ARCHIVE {
+		b.notFoundHandler(w, r)
+		return
+	}
+
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	posts, err := b.store.Archive(ctx)
+	if err != nil {
+		b.renderError(w, r, dbErrorStatus(err), fmt.Errorf("failed to load the archive: %w", err))
+		return
+	}
+	b.renderTemplate(w, r, "archive.html", ArchivePage{Years: groupArchive(posts, b.config.DisplayTimezone), Total: len(posts)})
+}
+
+// Groups posts, which must be sorted newest first, by the year and month they were published in zone, the
+// DISPLAY_TIMEZONE, so a post goes under the month its date is shown in
+func groupArchive(posts []Post, zone *time.Location) []ArchiveYear {
+	var years []ArchiveYear
+	for _, p := range posts {
+		published := p.PublishedAt.In(zone)
+		month := time.Date(published.Year(), published.Month(), 1, 0, 0, 0, 0, zone)
+
+		if len(years) == 0 || years[len(years)-1].Year != month.Year() {
+			years = append(years, ArchiveYear{Year: month.Year()})
+		}
+		year := &years[len(years)-1]
+		if len(year.Months) == 0 || !year.Months[len(year.Months)-1].Month.Equal(month) {
+			year.Months = append(year.Months, ArchiveMonth{Month: month})
+		}
+		year.Months[len(year.Months)-1].Posts = append(year.Months[len(year.Months)-1].Posts, p)
+		year.Count++
+	}
+	return years
+}
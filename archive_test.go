@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The archive laid out as "year (count)" and "month: slugs" lines, so whole groupings can be compared at once
+func archiveOutline(years []ArchiveYear) []string {
+	var outline []string
+	for _, year := range years {
+		outline = append(outline, fmt.Sprintf("%d (%d)", year.Year, year.Count))
+		for _, month := range year.Months {
+			outline = append(outline, month.Month.Format("January")+": "+strings.Join(slugs(month.Posts), " "))
+		}
+	}
+	return outline
+}
+
+func TestGroupArchive(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no timezone database: %v", err)
+	}
+	published := func(slug, at string) Post {
+		when, err := time.Parse(time.RFC3339, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return Post{Slug: slug, PublishedAt: when}
+	}
+	// Newest first, as the store loads them
+	posts := []Post{
+		published("new-year", "2022-01-01T03:00:00Z"),
+		published("december", "2021-12-31T12:00:00Z"),
+		published("march-late", "2021-03-31T23:00:00Z"),
+		published("march-early", "2021-03-01T02:00:00Z"),
+		published("january", "2021-01-15T12:00:00Z"),
+		published("old", "2019-06-01T12:00:00Z"),
+	}
+
+	tests := []struct {
+		name string
+		zone *time.Location
+		want []string
+	}{
+		{
+			name: "UTC",
+			zone: time.UTC,
+			want: []string{
+				"2022 (1)", "January: new-year",
+				"2021 (4)", "December: december", "March: march-late march-early", "January: january",
+				"2019 (1)", "June: old",
+			},
+		},
+		{
+			// Grouped by the date readers see, so posts near midnight UTC move back a month, or a year
+			name: "New York",
+			zone: newYork,
+			want: []string{
+				"2021 (5)", "December: new-year december", "March: march-late", "February: march-early", "January: january",
+				"2019 (1)", "June: old",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			years := groupArchive(posts, tt.zone)
+			if got := archiveOutline(years); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("groupArchive =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+			for _, year := range years {
+				for _, month := range year.Months {
+					if month.Month.Day() != 1 || month.Month.Location() != tt.zone {
+						t.Errorf("month %s isn't the first of the month in %s", month.Month, tt.zone)
+					}
+				}
+			}
+		})
+	}
+
+	if years := groupArchive(nil, time.UTC); len(years) != 0 {
+		t.Errorf("groupArchive with no posts = %v, want no years", years)
+	}
+}
+
+func TestArchivePage(t *testing.T) {
+	b := newTestBlog(t, testConfig(t))
+	for _, p := range []struct {
+		slug string
+		date time.Time
+	}{
+		{slug: "march-one", date: time.Date(2021, 3, 5, 12, 0, 0, 0, time.UTC)},
+		{slug: "march-two", date: time.Date(2021, 3, 20, 12, 0, 0, 0, time.UTC)},
+		{slug: "may", date: time.Date(2021, 5, 1, 12, 0, 0, 0, time.UTC)},
+		{slug: "last-year", date: time.Date(2020, 11, 1, 12, 0, 0, 0, time.UTC)},
+	} {
+		createPost(t, b.store, Post{Header: "Post " + p.slug, Content: "Words", Slug: p.slug, Author: "Tester", Published: true, PublishedAt: p.date})
+	}
+	createPost(t, b.store, Post{Header: "Draft", Content: "Words", Slug: "draft", Author: "Tester", PublishedAt: time.Date(2021, 3, 10, 12, 0, 0, 0, time.UTC)})
+	createPost(t, b.store, Post{Header: "Deleted", Content: "Words", Slug: "deleted", Author: "Tester", Published: true, PublishedAt: time.Date(2021, 3, 11, 12, 0, 0, 0, time.UTC)})
+	if err := b.store.Delete(context.Background(), "deleted"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	router := newRouter(b)
+
+	w := do(router, httptest.NewRequest(http.MethodGet, ARCHIVE, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s responded %d, want %d", ARCHIVE, w.Code, http.StatusOK)
+	}
+	body := w.Body.String()
+	// In order, so each heading is followed by what's grouped under it
+	want := []string{
+		"<h2>2021 (3)</h2>",
+		"<h3>May (1)</h3>", `<a href="/post/may">Post may</a>`,
+		"<h3>March (2)</h3>", `<a href="/post/march-two">Post march-two</a>`, `<a href="/post/march-one">Post march-one</a>`,
+		"<h2>2020 (1)</h2>",
+		"<h3>November (1)</h3>", `<a href="/post/last-year">Post last-year</a>`,
+	}
+	last := -1
+	for _, s := range want {
+		i := strings.Index(body, s)
+		if i < 0 {
+			t.Errorf("GET %s is missing %s:\n%s", ARCHIVE, s, body)
+			continue
+		}
+		if i < last {
+			t.Errorf("GET %s has %s out of order:\n%s", ARCHIVE, s, body)
+		}
+		last = i
+	}
+	for _, hidden := range []string{"/post/draft", "/post/deleted"} {
+		if strings.Contains(body, hidden) {
+			t.Errorf("GET %s links to %s, which isn't live", ARCHIVE, hidden)
+		}
+	}
+
+	if w := do(router, httptest.NewRequest(http.MethodGet, ARCHIVE+"2021", nil)); w.Code != http.StatusNotFound {
+		t.Errorf("GET %s2021 responded %d, want %d", ARCHIVE, w.Code, http.StatusNotFound)
+	}
+}
+
+func TestEmptyArchive(t *testing.T) {
+	b := newTestBlog(t, testConfig(t))
+	body := do(newRouter(b), httptest.NewRequest(http.MethodGet, ARCHIVE, nil)).Body.String()
+	if !strings.Contains(body, "Nothing has been published yet") {
+		t.Errorf("GET %s with no posts should say so:\n%s", ARCHIVE, body)
+	}
+}
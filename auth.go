@@ -0,0 +1,48 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+const AUTH_CHALLENGE = `Basic realm="go-blog", charset="UTF-8"` // Sent with every 401 so browsers prompt for the login
+
+// Only lets requests with the admin's basic auth credentials through to handlerFn
+func (b *Blog) requireAdmin(handlerFn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !b.checkAdmin(w, r) {
+			return
+		}
+		handlerFn(w, r)
+	}
+}
+
+// The byline to save a post with, whoever's named on it or else the author who's logged in
+func postAuthor(r *http.Request, named string) string {
+	if named = strings.TrimSpace(named); named != "" {
+		return named
+	}
+	user, _, _ := r.BasicAuth()
+	return user
+}
+
+// Reports whether r carries the admin's credentials, responding with a 401 challenge when it doesn't
+func (b *Blog) checkAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if b.isAdmin(r) {
+		return true
+	}
+	w.Header().Set("WWW-Authenticate", AUTH_CHALLENGE)
+	http.Error(w, "You need to log in to do that.", http.StatusUnauthorized)
+	return false
+}
+
+// Reports whether r carries the admin's credentials
+func (b *Blog) isAdmin(r *http.Request) bool {
+	adminUser, adminPassword := b.config.AdminUser, b.config.AdminPassword
+	user, password, ok := r.BasicAuth()
+	return ok && adminUser != "" && adminPassword != "" &&
+		// Constant time so the credentials can't be guessed a character at a time from response timings
+		subtle.ConstantTimeCompare([]byte(user), []byte(adminUser)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(password), []byte(adminPassword)) == 1
+}
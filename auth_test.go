@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProtectedRoutesNeedAdmin(t *testing.T) {
+	router := newRouter(newFakeBlog(t, testConfig(t)))
+
+	requests := []func() *http.Request{
+		func() *http.Request { return httptest.NewRequest(http.MethodGet, NEW, nil) },
+		func() *http.Request { return httptest.NewRequest(http.MethodGet, EDIT+"live", nil) },
+		func() *http.Request { return httptest.NewRequest(http.MethodGet, DELETE+"live", nil) },
+		func() *http.Request { return httptest.NewRequest(http.MethodPost, SAVE+SAVE_ADD, nil) },
+		func() *http.Request { return httptest.NewRequest(http.MethodPost, API_POSTS, nil) },
+		func() *http.Request { return httptest.NewRequest(http.MethodPut, API_POSTS+"/live", nil) },
+		func() *http.Request { return httptest.NewRequest(http.MethodDelete, API_POSTS+"/live", nil) },
+	}
+	for _, request := range requests {
+		anonymous := request()
+		wrongPassword := request()
+		wrongPassword.SetBasicAuth(TEST_ADMIN_USER, "guess")
+
+		for _, req := range []*http.Request{anonymous, wrongPassword} {
+			w := do(router, req)
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("%s %s without the admin's login responded %d, want %d", req.Method, req.URL.Path, w.Code, http.StatusUnauthorized)
+			}
+			if got := w.Header().Get("WWW-Authenticate"); got == "" {
+				t.Errorf("%s %s didn't challenge for a login", req.Method, req.URL.Path)
+			}
+		}
+	}
+	if w := do(router, adminRequest(http.MethodGet, NEW, nil)); w.Code != http.StatusOK {
+		t.Errorf("GET %s as the admin responded %d, want %d", NEW, w.Code, http.StatusOK)
+	}
+}
+
+func TestNobodyIsAdminWithoutALogin(t *testing.T) {
+	config := testConfig(t)
+	config.AdminUser, config.AdminPassword = "", ""
+
+	req := httptest.NewRequest(http.MethodGet, NEW, nil)
+	req.SetBasicAuth("", "")
+	if w := do(newRouter(newFakeBlog(t, config)), req); w.Code != http.StatusUnauthorized {
+		t.Errorf("GET %s with ADMIN_USER and ADMIN_PASSWORD unset responded %d, want %d", NEW, w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestPostAuthor(t *testing.T) {
+	tests := []struct {
+		name  string
+		named string
+		want  string
+	}{
+		{name: "named", named: "Ada", want: "Ada"},
+		{name: "named with spaces", named: "  Ada ", want: "Ada"},
+		{name: "unnamed", named: "", want: TEST_ADMIN_USER},
+		{name: "blank", named: "   ", want: TEST_ADMIN_USER},
+	}
+	for _, tt := range tests {
+		req := adminRequest(http.MethodPost, SAVE+SAVE_ADD, nil)
+		if got := postAuthor(req, tt.named); got != tt.want {
+			t.Errorf("%s: postAuthor(%q) = %q, want %q", tt.name, tt.named, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Type used to parse templates on the page listing an author's posts
+type AuthorPage struct {
+	HomePage
+	Author string
+}
+
+// Handles /author/<name>/, listing the published posts that author wrote
+func (b *Blog) authorHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, AUTHOR), "/"))
+	if name == "" || strings.Contains(name, "/") {
+		b.notFoundHandler(w, r)
+		return
+	}
+
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	authorPage, err := b.store.ByAuthor(ctx, name, requestedPage(r))
+	if err != nil {
+		b.renderError(w, r, dbErrorStatus(err), fmt.Errorf("failed to load posts by %q: %w", name, err))
+		return
+	}
+	b.prepare(authorPage.Posts)
+
+	b.renderTemplate(w, r, "author.html", authorPage)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAuthorPage(t *testing.T) {
+	b := newFakeBlog(t, testConfig(t))
+	createPost(t, b.store, Post{Header: "Ada on engines", Content: "Words", Slug: "engines", Author: "Ada", Published: true})
+	createPost(t, b.store, Post{Header: "Ada on notes", Content: "Words", Slug: "notes", Author: "Ada", Published: true})
+	createPost(t, b.store, Post{Header: "Ada's draft", Content: "Words", Slug: "ada-draft", Author: "Ada"})
+	createPost(t, b.store, Post{Header: "Grace on compilers", Content: "Words", Slug: "compilers", Author: "Grace", Published: true})
+	router := newRouter(b)
+
+	// The name matches whatever case the url was typed in
+	for _, path := range []string{AUTHOR + "Ada/", AUTHOR + "ada/", AUTHOR + "Ada"} {
+		w := do(router, httptest.NewRequest(http.MethodGet, path, nil))
+		body := w.Body.String()
+		if w.Code != http.StatusOK || !strings.Contains(body, "Ada on engines") || !strings.Contains(body, "Ada on notes") {
+			t.Errorf("GET %s responded %d, want Ada's posts:\n%s", path, w.Code, body)
+		}
+		if strings.Contains(body, "Ada&#39;s draft") || strings.Contains(body, "Grace on compilers") {
+			t.Errorf("GET %s listed drafts or someone else's posts:\n%s", path, body)
+		}
+	}
+
+	w := do(router, httptest.NewRequest(http.MethodGet, AUTHOR+"Nobody/", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Nobody hasn't published any posts yet") {
+		t.Errorf("GET %sNobody/ responded %d, want a page saying they haven't published anything:\n%s", AUTHOR, w.Code, w.Body.String())
+	}
+	for _, path := range []string{AUTHOR, AUTHOR + "Ada/engines"} {
+		if w := do(router, httptest.NewRequest(http.MethodGet, path, nil)); w.Code != http.StatusNotFound {
+			t.Errorf("GET %s responded %d, want %d", path, w.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestPostsShowTheirAuthor(t *testing.T) {
+	b := newFakeBlog(t, testConfig(t))
+	createPost(t, b.store, Post{Header: "Ada on engines", Content: "Words", Slug: "engines", Author: "Ada", Published: true})
+	router := newRouter(b)
+
+	for _, path := range []string{HOME, POST + "engines"} {
+		if w := do(router, httptest.NewRequest(http.MethodGet, path, nil)); !strings.Contains(w.Body.String(), `<a href="/author/Ada/">Ada</a>`) {
+			t.Errorf("GET %s doesn't link to the post's author", path)
+		}
+	}
+}
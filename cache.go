@@ -0,0 +1,46 @@
+package main
+
+import "sync"
+
+// Holds the pages of the homepage we've already loaded, safe for concurrent use by handlers
+type PageCache struct {
+	mu         sync.RWMutex
+	pages      map[pageKey]HomePage
+	generation int // Bumped on every invalidate, so loads that raced a write aren't stored
+}
+
+// Each order of the homepage is paged separately
+type pageKey struct {
+	page int
+	sort PostSort
+}
+
+func NewPageCache() *PageCache {
+	return &PageCache{pages: map[pageKey]HomePage{}}
+}
+
+// Returns the cached page if present, plus the generation to pass to set once a missing page is loaded
+func (c *PageCache) get(page int, sort PostSort) (HomePage, bool, int) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	homePage, ok := c.pages[pageKey{page, sort}]
+	return homePage, ok, c.generation
+}
+
+// Stores a loaded page, unless the cache was invalidated since the caller's get
+func (c *PageCache) set(generation int, homePage HomePage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+	c.pages[pageKey{homePage.CurrentPage, homePage.Sort}] = homePage
+}
+
+// Drops every cached page, called whenever posts are added, updated or deleted
+func (c *PageCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pages = map[pageKey]HomePage{}
+	c.generation++
+}
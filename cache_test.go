@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPageCacheDropsLoadsThatRacedAWrite(t *testing.T) {
+	cache := NewPageCache()
+	_, cached, generation := cache.get(1, SORT_NEWEST)
+	if cached {
+		t.Fatal("an empty cache had page 1")
+	}
+
+	// A post's saved while page 1 is loading, so what was loaded is already out of date
+	cache.invalidate()
+	cache.set(generation, HomePage{CurrentPage: 1, Sort: SORT_NEWEST})
+	if _, cached, _ := cache.get(1, SORT_NEWEST); cached {
+		t.Error("a page loaded before invalidate was cached")
+	}
+
+	_, _, generation = cache.get(1, SORT_NEWEST)
+	cache.set(generation, HomePage{CurrentPage: 1, Sort: SORT_NEWEST})
+	if _, cached, _ := cache.get(1, SORT_NEWEST); !cached {
+		t.Error("a page loaded since the last invalidate wasn't cached")
+	}
+}
+
+// Run with -race to check the cache is safe to share between handlers
+func TestHomePageWhileSaving(t *testing.T) {
+	b := newFakeBlog(t, testConfig(t))
+	router := newRouter(b)
+
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if w := do(router, httptest.NewRequest(http.MethodGet, HOME, nil)); w.Code != http.StatusOK {
+					t.Errorf("GET %s responded %d while posts were being saved", HOME, w.Code)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 20; i++ {
+		slug := fmt.Sprintf("saved-%d", i)
+		form := url.Values{"header": {"Saved " + slug}, "content": {"Words"}, "slug": {slug}}
+		if w := do(router, formRequest(SAVE+SAVE_ADD, form)); w.Code != http.StatusSeeOther {
+			t.Fatalf("adding %s responded %d", slug, w.Code)
+		}
+		if w := do(router, formRequest(SAVE+SAVE_PUBLISH, url.Values{"slug": {slug}})); w.Code != http.StatusSeeOther {
+			t.Fatalf("publishing %s responded %d", slug, w.Code)
+		}
+		// Once publishing's responded, nobody should be served a homepage without the post
+		if w := do(router, httptest.NewRequest(http.MethodGet, HOME, nil)); !strings.Contains(w.Body.String(), "Saved "+slug) {
+			t.Fatalf("the homepage is missing %s after it was published", slug)
+		}
+	}
+	close(stop)
+	readers.Wait()
+}
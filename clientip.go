@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	FORWARDED_FOR_HEADER = "X-Forwarded-For" // The client and each proxy before the last, appended to by each proxy in turn
+	REAL_IP_HEADER       = "X-Real-IP"       // The client, as nginx and some others set it
+
+	FORWARDED_PROTO_HEADER = "X-Forwarded-Proto" // Whether the client used http or https, appended to like X-Forwarded-For
+)
+
+type clientIPKey struct{}
+
+type forwardedProtoKey struct{}
+
+// Works out the IP each request came from and keeps it in the request's context for clientIP. When the blog is behind
+// trustedProxies proxies, the client is read from the headers they add, otherwise they're ignored as anyone could send them.
+// The scheme the client used is read from them the same way, for requestBaseURL
+func clientIPMiddleware(trustedProxies int, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := remoteIP(r)
+		ctx := r.Context()
+		if trustedProxies > 0 {
+			if forwarded := forwardedIP(r, trustedProxies); forwarded != "" {
+				ip = forwarded
+			}
+			if proto := forwardedProto(r, trustedProxies); proto != "" {
+				ctx = context.WithValue(ctx, forwardedProtoKey{}, proto)
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, clientIPKey{}, ip)))
+	})
+}
+
+// The IP the request came from, without its port. Behind trusted proxies that's the client rather than the closest proxy
+func clientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	return remoteIP(r)
+}
+
+// The IP at the other end of the connection
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// The client's IP from the headers set by the trustedProxies proxies in front of the blog, empty if they didn't set one.
+// Each proxy appends who it heard from to X-Forwarded-For, so the client is that many from the end. Anything before it
+// was sent by the client and can't be trusted
+func forwardedIP(r *http.Request, trustedProxies int) string {
+	ip := trustedHop(r, FORWARDED_FOR_HEADER, trustedProxies)
+	if ip == "" {
+		ip = strings.TrimSpace(r.Header.Get(REAL_IP_HEADER))
+	}
+	if net.ParseIP(ip) == nil {
+		return ""
+	}
+	return ip
+}
+
+// The scheme the client used to reach the first of the trustedProxies proxies, http or https, empty if they didn't say
+func forwardedProto(r *http.Request, trustedProxies int) string {
+	proto := strings.ToLower(trustedHop(r, FORWARDED_PROTO_HEADER, trustedProxies))
+	if proto != "http" && proto != "https" {
+		return ""
+	}
+	return proto
+}
+
+// The entry the furthest of the trustedProxies proxies added to a comma separated header that each proxy appends to,
+// empty if the header wasn't set
+func trustedHop(r *http.Request, header string, trustedProxies int) string {
+	var hops []string
+	for _, value := range r.Header.Values(header) {
+		for _, hop := range strings.Split(value, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+
+	switch {
+	case len(hops) >= trustedProxies:
+		return hops[len(hops)-trustedProxies]
+	case len(hops) > 0:
+		// Fewer hops than proxies, so every one of them was added by a proxy
+		return hops[0]
+	}
+	return ""
+}
+
+// Reads TRUST_PROXY, the number of proxies in front of the blog. true is taken to mean one and false none
+func parseTrustProxy(raw string) (int, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	if trust, err := strconv.ParseBool(raw); err == nil {
+		if trust {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("TRUST_PROXY should be true, false or the number of proxies in front of the blog, got %q", raw)
+	}
+	return n, nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIPMiddleware(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies int
+		remoteAddr     string
+		headers        map[string][]string
+		want           string
+	}{
+		{name: "direct", remoteAddr: "192.0.2.1:1234", want: "192.0.2.1"},
+		{name: "untrusted X-Forwarded-For", remoteAddr: "192.0.2.1:1234", headers: map[string][]string{FORWARDED_FOR_HEADER: {"203.0.113.9"}}, want: "192.0.2.1"},
+		{name: "untrusted X-Real-IP", remoteAddr: "192.0.2.1:1234", headers: map[string][]string{REAL_IP_HEADER: {"203.0.113.9"}}, want: "192.0.2.1"},
+		{name: "one proxy", trustedProxies: 1, remoteAddr: "10.0.0.1:1234", headers: map[string][]string{FORWARDED_FOR_HEADER: {"203.0.113.9"}}, want: "203.0.113.9"},
+		{name: "one proxy and a spoofed hop", trustedProxies: 1, remoteAddr: "10.0.0.1:1234", headers: map[string][]string{FORWARDED_FOR_HEADER: {"198.51.100.7, 203.0.113.9"}}, want: "203.0.113.9"},
+		{name: "two proxies", trustedProxies: 2, remoteAddr: "10.0.0.1:1234", headers: map[string][]string{FORWARDED_FOR_HEADER: {"198.51.100.7, 203.0.113.9, 10.0.0.2"}}, want: "203.0.113.9"},
+		{name: "hops over several headers", trustedProxies: 2, remoteAddr: "10.0.0.1:1234", headers: map[string][]string{FORWARDED_FOR_HEADER: {"203.0.113.9", "10.0.0.2"}}, want: "203.0.113.9"},
+		{name: "fewer hops than proxies", trustedProxies: 3, remoteAddr: "10.0.0.1:1234", headers: map[string][]string{FORWARDED_FOR_HEADER: {"203.0.113.9, 10.0.0.2"}}, want: "203.0.113.9"},
+		{name: "X-Real-IP", trustedProxies: 1, remoteAddr: "10.0.0.1:1234", headers: map[string][]string{REAL_IP_HEADER: {" 203.0.113.9 "}}, want: "203.0.113.9"},
+		{name: "X-Forwarded-For over X-Real-IP", trustedProxies: 1, remoteAddr: "10.0.0.1:1234", headers: map[string][]string{FORWARDED_FOR_HEADER: {"203.0.113.9"}, REAL_IP_HEADER: {"198.51.100.7"}}, want: "203.0.113.9"},
+		{name: "IPv6", trustedProxies: 1, remoteAddr: "[::1]:1234", headers: map[string][]string{FORWARDED_FOR_HEADER: {"2001:db8::1"}}, want: "2001:db8::1"},
+		{name: "not an IP", trustedProxies: 1, remoteAddr: "10.0.0.1:1234", headers: map[string][]string{FORWARDED_FOR_HEADER: {"not-an-ip"}}, want: "10.0.0.1"},
+		{name: "no headers", trustedProxies: 1, remoteAddr: "10.0.0.1:1234", want: "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := clientIPMiddleware(tt.trustedProxies, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = clientIP(r)
+			}))
+			req := httptest.NewRequest(http.MethodGet, HOME, nil)
+			req.RemoteAddr = tt.remoteAddr
+			for name, values := range tt.headers {
+				for _, v := range values {
+					req.Header.Add(name, v)
+				}
+			}
+			do(h, req)
+			if got != tt.want {
+				t.Errorf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientIPOutsideTheMiddleware(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, HOME, nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set(FORWARDED_FOR_HEADER, "203.0.113.9")
+	if got := clientIP(req); got != "192.0.2.1" {
+		t.Errorf("clientIP without the middleware = %q, want the connection's IP", got)
+	}
+}
+
+func TestParseTrustProxy(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    int
+		wantErr bool
+	}{
+		{raw: "", want: 0},
+		{raw: "false", want: 0},
+		{raw: "true", want: 1},
+		{raw: "0", want: 0},
+		{raw: "2", want: 2},
+		{raw: "-1", wantErr: true},
+		{raw: "yes", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseTrustProxy(tt.raw)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseTrustProxy(%q) = %d, %v, want %d and an error %v", tt.raw, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// Behind a trusted proxy every client has a bucket of its own, but when the headers aren't trusted changing them doesn't
+// get anyone a new one
+func TestRateLimitingBehindAProxy(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies int
+		wantSecond     int
+	}{
+		{name: "trusted", trustedProxies: 1, wantSecond: http.StatusOK},
+		{name: "untrusted", trustedProxies: 0, wantSecond: http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig(t)
+			config.RateLimit = 0.01
+			config.RateBurst = 1
+			b := newFakeBlog(t, config)
+			createLivePost(t, b.store, "live")
+			h := clientIPMiddleware(tt.trustedProxies, newRouter(b))
+
+			for i, client := range []string{"203.0.113.9", "198.51.100.7"} {
+				req := adminRequest(http.MethodGet, DELETE+"live", nil)
+				req.RemoteAddr = "10.0.0.1:1234"
+				req.Header.Set(FORWARDED_FOR_HEADER, client)
+				want := http.StatusOK
+				if i == 1 {
+					want = tt.wantSecond
+				}
+				if w := do(h, req); w.Code != want {
+					t.Errorf("GET %slive from %s responded %d, want %d", DELETE, client, w.Code, want)
+				}
+			}
+		})
+	}
+}
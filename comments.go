@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	COMMENT = "/comment" // Suffix of the url a post's comment form submits to, /post/<slug>/comment
+
+	MAX_COMMENT_LENGTH = 2000        // Characters allowed in a comment's body
+	ANONYMOUS_AUTHOR   = "Anonymous" // Shown for comments left without a name
+)
+
+// A reader's comment on a post
+type Comment struct {
+	Author    string    `json:"author"`
+	Body      string    `json:"body"` // Plain text, rendered escaped so it can't carry HTML
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Handles the comment form at /post/<slug>/comment, sending the reader back to the post once it's saved
+func (b *Blog) commentHandler(w http.ResponseWriter, r *http.Request) {
+	slug := strings.TrimSuffix(strings.TrimPrefix(strings.ToLower(r.URL.Path), POST), COMMENT)
+	if slug == "" || strings.Contains(slug, "/") {
+		b.notFoundHandler(w, r)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "The form couldn't be read, please go back and try again.", http.StatusBadRequest)
+		return
+	}
+	if !validCSRF(r) {
+		http.Error(w, "This form has expired, please go back, refresh and try again.", http.StatusForbidden)
+		return
+	}
+
+	comment, err := parseComment(r.PostFormValue("author"), r.PostFormValue("body"))
+	if err != nil {
+		b.generateResulTemplate(w, r, http.StatusBadRequest, &CRUDResult{Message: "Sorry! We couldn't save your comment, " + err.Error()})
+		return
+	}
+
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	err = b.store.AddComment(ctx, slug, comment)
+	if errors.Is(err, errPostNotFound) {
+		b.notFoundHandler(w, r)
+		return
+	}
+	if err != nil {
+		logf(ctx, "Failed to save a comment on %q: %v", slug, err)
+		b.generateResulTemplate(w, r, dbErrorStatus(err), &CRUDResult{Message: "Sorry! Something went wrong saving your comment, please try again"})
+		return
+	}
+
+	b.notifyComment(ctx, slug, b.absoluteURL(r, POST+slug), comment)
+
+	// See Other so refreshing the post doesn't resubmit the comment
+	http.Redirect(w, r, POST+slug+"#comments", http.StatusSeeOther)
+}
+
+// Tidies up a submitted comment and checks it's worth saving, naming whatever's wrong with it
+func parseComment(author, body string) (Comment, error) {
+	author = strings.TrimSpace(author)
+	body = strings.TrimSpace(body)
+	if author == "" {
+		author = ANONYMOUS_AUTHOR
+	}
+
+	if body == "" {
+		return Comment{}, errors.New("it was empty")
+	}
+	if n := utf8.RuneCountInString(body); n > MAX_COMMENT_LENGTH {
+		return Comment{}, fmt.Errorf("it is %d characters long, it can be at most %d", n, MAX_COMMENT_LENGTH)
+	}
+	if n := utf8.RuneCountInString(author); n > MAX_AUTHOR_LENGTH {
+		return Comment{}, fmt.Errorf("your name is %d characters long, it can be at most %d", n, MAX_AUTHOR_LENGTH)
+	}
+	return Comment{Author: author, Body: body}, nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParseComment(t *testing.T) {
+	tests := []struct {
+		name    string
+		author  string
+		body    string
+		want    Comment
+		wantErr bool
+	}{
+		{name: "named", author: " Ada ", body: " Nice post \n", want: Comment{Author: "Ada", Body: "Nice post"}},
+		{name: "anonymous", author: "  ", body: "Nice post", want: Comment{Author: ANONYMOUS_AUTHOR, Body: "Nice post"}},
+		{name: "empty", author: "Ada", body: " \n\t", wantErr: true},
+		{name: "at the limit", author: "Ada", body: strings.Repeat("é", MAX_COMMENT_LENGTH), want: Comment{Author: "Ada", Body: strings.Repeat("é", MAX_COMMENT_LENGTH)}},
+		{name: "too long", author: "Ada", body: strings.Repeat("a", MAX_COMMENT_LENGTH+1), wantErr: true},
+		{name: "name too long", author: strings.Repeat("a", MAX_AUTHOR_LENGTH+1), body: "Nice post", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseComment(tt.author, tt.body)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: parseComment error = %v, want an error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: parseComment = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// A reader submitting the comment form on the post at slug, they aren't logged in
+func commentRequest(slug string, form url.Values) *http.Request {
+	form.Set(CSRF_FIELD, TEST_CSRF_TOKEN)
+	req := httptest.NewRequest(http.MethodPost, POST+slug+COMMENT, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: CSRF_COOKIE, Value: TEST_CSRF_TOKEN})
+	return req
+}
+
+func TestComments(t *testing.T) {
+	b := newTestBlog(t, testConfig(t))
+	createLivePost(t, b.store, "live")
+	router := newRouter(b)
+
+	for _, form := range []url.Values{
+		{"author": {"Ada"}, "body": {"First!"}},
+		{"author": {"Grace"}, "body": {"<script>alert(1)</script>"}},
+	} {
+		w := do(router, commentRequest("live", form))
+		if w.Code != http.StatusSeeOther || w.Header().Get("Location") != POST+"live#comments" {
+			t.Fatalf("commenting responded %d to %q, want %d back to the comments", w.Code, w.Header().Get("Location"), http.StatusSeeOther)
+		}
+	}
+
+	body := do(router, httptest.NewRequest(http.MethodGet, POST+"live", nil)).Body.String()
+	first, second := strings.Index(body, "First!"), strings.Index(body, "&lt;script&gt;alert(1)&lt;/script&gt;")
+	if first < 0 || second < 0 || first > second {
+		t.Errorf("GET %slive doesn't show both comments oldest first, escaped:\n%s", POST, body)
+	}
+	if strings.Contains(body, "<script>alert(1)") {
+		t.Errorf("GET %slive shows a comment's HTML unescaped", POST)
+	}
+}
+
+func TestCommentsAreRateLimited(t *testing.T) {
+	config := testConfig(t)
+	config.RateLimit = 0.01 // A token every 100s, so none come back during the test
+	config.RateBurst = 2
+	b := newFakeBlog(t, config)
+	createLivePost(t, b.store, "live")
+	router := newRouter(b)
+
+	var last int
+	for i := 0; i < config.RateBurst+1; i++ {
+		last = do(router, commentRequest("live", url.Values{"author": {"Spammer"}, "body": {"Buy now"}})).Code
+	}
+	if last != http.StatusTooManyRequests {
+		t.Errorf("commenting %d times responded %d, want %d", config.RateBurst+1, last, http.StatusTooManyRequests)
+	}
+	if comments, _ := b.store.Comments(context.Background(), "live"); len(comments) != config.RateBurst {
+		t.Errorf("commenting %d times saved %d comments, want %d", config.RateBurst+1, len(comments), config.RateBurst)
+	}
+
+	// Readers aren't held up by the commenting
+	if w := do(router, httptest.NewRequest(http.MethodGet, POST+"live", nil)); w.Code != http.StatusOK {
+		t.Errorf("GET %slive after being limited responded %d, want %d", POST, w.Code, http.StatusOK)
+	}
+}
+
+func TestCommentsThatArentSaved(t *testing.T) {
+	tests := []struct {
+		name       string
+		slug       string
+		form       url.Values
+		wantStatus int
+	}{
+		{name: "empty", slug: "live", form: url.Values{"author": {"Ada"}, "body": {"  "}}, wantStatus: http.StatusBadRequest},
+		{name: "too long", slug: "live", form: url.Values{"body": {strings.Repeat("a", MAX_COMMENT_LENGTH+1)}}, wantStatus: http.StatusBadRequest},
+		{name: "on a draft", slug: "draft", form: url.Values{"body": {"Early"}}, wantStatus: http.StatusNotFound},
+		{name: "on a missing post", slug: "missing", form: url.Values{"body": {"Hello?"}}, wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newFakeBlog(t, testConfig(t))
+			createLivePost(t, b.store, "live")
+			createPost(t, b.store, Post{Header: "Draft", Content: "Not yet", Slug: "draft", Author: "Tester"})
+
+			if w := do(newRouter(b), commentRequest(tt.slug, tt.form)); w.Code != tt.wantStatus {
+				t.Errorf("commenting on %s responded %d, want %d", tt.slug, w.Code, tt.wantStatus)
+			}
+			for _, slug := range []string{"live", "draft"} {
+				if comments, _ := b.store.Comments(context.Background(), slug); len(comments) != 0 {
+					t.Errorf("commenting on %s saved %+v", tt.slug, comments)
+				}
+			}
+		})
+	}
+}
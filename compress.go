@@ -0,0 +1,113 @@
+package main
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const DEFAULT_GZIP = true // Whether responses are gzipped for clients that accept it, override with GZIP
+
+// Content types that are already compressed, gzipping them again just costs CPU
+var compressedTypePrefixes = []string{"image/", "video/", "audio/", "font/woff", "application/zip", "application/gzip", "application/pdf"}
+
+// Reuses gzip writers between responses, they're expensive to allocate
+var gzipWriters = sync.Pool{New: func() interface{} { return gzip.NewWriter(nil) }}
+
+// Wraps a ResponseWriter to gzip the body, deciding whether to once the handler's set its headers
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz          *gzip.Writer // Nil until we've decided to compress, and always if we decided not to
+	wroteHeader bool
+}
+
+func (g *gzipResponseWriter) WriteHeader(status int) {
+	if g.wroteHeader {
+		g.ResponseWriter.WriteHeader(status)
+		return
+	}
+	g.wroteHeader = true
+
+	h := g.Header()
+	// Ranges are of the uncompressed body and 204s and 304s have none, so only whole bodies are compressed
+	hasWholeBody := status >= http.StatusOK && status != http.StatusNoContent && status != http.StatusPartialContent && status != http.StatusNotModified
+	if hasWholeBody && h.Get("Content-Encoding") == "" && compressible(h.Get("Content-Type")) {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length") // It's the length before compressing
+		g.gz = gzipWriters.Get().(*gzip.Writer)
+		g.gz.Reset(g.ResponseWriter)
+	}
+	g.ResponseWriter.WriteHeader(status)
+}
+
+func (g *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !g.wroteHeader {
+		if g.Header().Get("Content-Type") == "" {
+			// What net/http would have sniffed, we need it now to know whether to compress
+			g.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		g.WriteHeader(http.StatusOK)
+	}
+	if g.gz != nil {
+		return g.gz.Write(b)
+	}
+	return g.ResponseWriter.Write(b)
+}
+
+// Flushes the rest of the compressed body, must be called once the handler's done
+func (g *gzipResponseWriter) close() {
+	if g.gz == nil {
+		return
+	}
+	g.gz.Close()
+	gzipWriters.Put(g.gz)
+}
+
+// Gzips responses for clients whose Accept-Encoding allows it, leaving anything already compressed alone
+func gzipMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Caches need to know the body depends on the header, whichever way we go
+		w.Header().Add("Vary", "Accept-Encoding")
+		if r.Method == http.MethodHead || !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer gw.close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// Reports whether an Accept-Encoding header lists gzip, without it being refused with q=0
+func acceptsGzip(acceptEncoding string) bool {
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params := part, ""
+		if i := strings.Index(part, ";"); i >= 0 {
+			coding, params = part[:i], part[i+1:]
+		}
+		if coding = strings.TrimSpace(coding); coding != "gzip" && coding != "*" {
+			continue
+		}
+		if q := strings.TrimSpace(params); strings.HasPrefix(q, "q=") {
+			if weight, err := strconv.ParseFloat(q[len("q="):], 64); err == nil && weight == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// Reports whether a response of contentType is worth compressing
+func compressible(contentType string) bool {
+	for _, prefix := range compressedTypePrefixes {
+		if strings.HasPrefix(contentType, prefix) {
+			// SVGs are text, so they're the one image type that compresses well
+			return strings.HasPrefix(contentType, "image/svg+xml")
+		}
+	}
+	return true
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		want           bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"deflate, gzip;q=1.0, br", true},
+		{"*", true},
+		{"gzip;q=0", false},
+		{"gzip; q=0.5", true},
+		{"br, deflate", false},
+		{"gzipped", false},
+	}
+	for _, tt := range tests {
+		if got := acceptsGzip(tt.acceptEncoding); got != tt.want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", tt.acceptEncoding, got, tt.want)
+		}
+	}
+}
+
+func TestGzipMiddleware(t *testing.T) {
+	b := newFakeBlog(t, testConfig(t))
+	createLivePost(t, b.store, "compressed")
+	handler := gzipMiddleware(newRouter(b))
+
+	req := httptest.NewRequest(http.MethodGet, POST+"compressed", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	w := do(handler, req)
+	if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("GET %s accepting gzip has Content-Encoding %q, want gzip", req.URL, got)
+	}
+	if got := w.Header().Get("Vary"); !strings.Contains(got, "Accept-Encoding") {
+		t.Errorf("GET %s accepting gzip has Vary %q, want Accept-Encoding", req.URL, got)
+	}
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("GET %s accepting gzip isn't gzipped: %v", req.URL, err)
+	}
+	if body, err := io.ReadAll(gz); err != nil || !strings.Contains(string(body), "All about compressed") {
+		t.Errorf("GET %s accepting gzip doesn't decompress to the post, error %v", req.URL, err)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, POST+"compressed", nil)
+	w = do(handler, req)
+	if got := w.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("GET %s without Accept-Encoding has Content-Encoding %q, want none", req.URL, got)
+	}
+	if got := w.Header().Get("Vary"); !strings.Contains(got, "Accept-Encoding") {
+		t.Errorf("GET %s without Accept-Encoding has Vary %q, want Accept-Encoding", req.URL, got)
+	}
+	if !strings.Contains(w.Body.String(), "All about compressed") {
+		t.Errorf("GET %s without Accept-Encoding doesn't show the post uncompressed", req.URL)
+	}
+}
+
+func TestGzipMiddlewareSkips(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		handler http.HandlerFunc
+	}{
+		{name: "image", method: http.MethodGet, handler: func(w http.ResponseWriter, r *http.Request) {
+			w.Write(testPNG)
+		}},
+		{name: "already encoded", method: http.MethodGet, handler: func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Content-Encoding", "br")
+			w.Write([]byte("already brotli"))
+		}},
+		{name: "not modified", method: http.MethodGet, handler: func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotModified)
+		}},
+		{name: "head", method: http.MethodHead, handler: func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			w := do(gzipMiddleware(tt.handler), req)
+			if got := w.Header().Get("Content-Encoding"); got == "gzip" {
+				t.Errorf("%s / was gzipped", tt.method)
+			}
+			if bytes.HasPrefix(w.Body.Bytes(), []byte{0x1f, 0x8b}) {
+				t.Errorf("%s / has a gzipped body", tt.method)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sets the ETag and Last-Modified headers for a page, and answers with a 304 if the client's copy is still current.
+// Returns true when the 304 was sent, in which case the caller mustn't write a body
+func checkNotModified(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	w.Header().Set("ETag", etag)
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	notModified := false
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		// When both are sent the ETag wins, it's more precise than a timestamp
+		notModified = etagMatches(inm, etag)
+	} else if ims, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !lastModified.IsZero() {
+		// HTTP dates only have second precision
+		notModified = !lastModified.Truncate(time.Second).After(ims)
+	}
+
+	if notModified {
+		w.WriteHeader(http.StatusNotModified)
+	}
+	return notModified
+}
+
+// Reports whether etag is in an If-None-Match list, comparing weakly as RFC 7232 asks of If-None-Match
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Builds a strong ETag from everything that goes into rendering a page
+func makeETag(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0}) // So ("ab", "c") and ("a", "bc") don't hash the same
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// The ETag for a single post, it changes whenever the post is edited.
+// Views are left out, otherwise every view would change it and no one could ever get a 304
+func (p Post) etag() string {
+	parts := []string{p.Slug, p.Header, p.Content, p.UpdatedAt.UTC().Format(time.RFC3339Nano), p.PublishedAt.UTC().Format(time.RFC3339Nano)}
+	for _, tag := range p.Tags {
+		parts = append(parts, tag.Name)
+	}
+	return makeETag(parts...)
+}
+
+// The ETag for a post's own page, which also changes when it's commented on.
+// The CSRF token is included so a browser given a new one doesn't keep a cached comment form with the old
+func (p PostPage) etag() string {
+	parts := []string{p.Post.etag(), p.CSRFToken}
+	for _, c := range p.Comments {
+		parts = append(parts, c.Author, c.Body, c.CreatedAt.UTC().Format(time.RFC3339Nano))
+	}
+	for _, related := range p.Related {
+		parts = append(parts, related.Slug, related.Header)
+	}
+	return makeETag(parts...)
+}
+
+// When the post page last changed, the post's own edits or its newest comment
+func (p PostPage) lastModified() time.Time {
+	modified := p.UpdatedAt
+	for _, c := range p.Comments {
+		if c.CreatedAt.After(modified) {
+			modified = c.CreatedAt
+		}
+	}
+	return modified
+}
+
+// The ETag for a page of the homepage, it changes whenever any post on it does or the pages around it change
+func (h HomePage) etag() string {
+	parts := []string{strconv.Itoa(h.CurrentPage), strconv.Itoa(h.TotalPages), string(h.Sort)}
+	for _, p := range h.Posts {
+		parts = append(parts, p.etag())
+	}
+	return makeETag(parts...)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCheckNotModified(t *testing.T) {
+	const etag = `"abc"`
+	modified := time.Date(2021, 7, 1, 12, 0, 0, 500, time.UTC)
+
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		want    bool
+	}{
+		{name: "unconditional", method: http.MethodGet, want: false},
+		{name: "matching ETag", method: http.MethodGet, headers: map[string]string{"If-None-Match": etag}, want: true},
+		{name: "matching weak ETag", method: http.MethodGet, headers: map[string]string{"If-None-Match": `W/"abc"`}, want: true},
+		{name: "ETag in a list", method: http.MethodGet, headers: map[string]string{"If-None-Match": `"xyz", "abc"`}, want: true},
+		{name: "any ETag", method: http.MethodGet, headers: map[string]string{"If-None-Match": "*"}, want: true},
+		{name: "stale ETag", method: http.MethodGet, headers: map[string]string{"If-None-Match": `"xyz"`}, want: false},
+		{name: "HEAD", method: http.MethodHead, headers: map[string]string{"If-None-Match": etag}, want: true},
+		{name: "POST", method: http.MethodPost, headers: map[string]string{"If-None-Match": etag}, want: false},
+		{name: "modified since", method: http.MethodGet, headers: map[string]string{"If-Modified-Since": modified.Add(-time.Second).Format(http.TimeFormat)}, want: false},
+		{name: "not modified since", method: http.MethodGet, headers: map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)}, want: true},
+		{name: "stale ETag beats a current date", method: http.MethodGet, headers: map[string]string{
+			"If-None-Match":     `"xyz"`,
+			"If-Modified-Since": modified.Format(http.TimeFormat),
+		}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			if got := checkNotModified(w, req, etag, modified); got != tt.want {
+				t.Errorf("checkNotModified = %v, want %v", got, tt.want)
+			}
+			if tt.want && w.Code != http.StatusNotModified {
+				t.Errorf("responded %d, want %d", w.Code, http.StatusNotModified)
+			}
+			if got := w.Header().Get("ETag"); got != etag {
+				t.Errorf("ETag = %q, want %q", got, etag)
+			}
+			if got := w.Header().Get("Last-Modified"); got != modified.Format(http.TimeFormat) {
+				t.Errorf("Last-Modified = %q, want %q", got, modified.Format(http.TimeFormat))
+			}
+		})
+	}
+}
+
+// GETs path, then again with the cookies and validators it was given, returning the second response
+func getAgain(h http.Handler, path string, header string) *httptest.ResponseRecorder {
+	first := do(h, httptest.NewRequest(http.MethodGet, path, nil))
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for _, c := range first.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	switch header {
+	case "If-None-Match":
+		req.Header.Set(header, first.Header().Get("ETag"))
+	case "If-Modified-Since":
+		req.Header.Set(header, first.Header().Get("Last-Modified"))
+	}
+	return do(h, req)
+}
+
+func TestPagesAnswerConditionalRequests(t *testing.T) {
+	b := newTestBlog(t, testConfig(t))
+	createLivePost(t, b.store, "live")
+	router := newRouter(b)
+
+	tests := []struct {
+		path   string
+		header string
+	}{
+		{path: HOME, header: "If-None-Match"},
+		{path: POST + "live", header: "If-None-Match"},
+		{path: POST + "live", header: "If-Modified-Since"},
+	}
+	for _, tt := range tests {
+		w := getAgain(router, tt.path, tt.header)
+		if w.Code != http.StatusNotModified {
+			t.Errorf("GET %s with %s responded %d, want %d", tt.path, tt.header, w.Code, http.StatusNotModified)
+		}
+		if w.Body.Len() != 0 {
+			t.Errorf("GET %s with %s sent a body with its 304", tt.path, tt.header)
+		}
+	}
+}
+
+func TestEditedPostsAreModified(t *testing.T) {
+	b := newFakeBlog(t, testConfig(t))
+	createLivePost(t, b.store, "live")
+	router := newRouter(b)
+
+	for _, path := range []string{HOME, POST + "live"} {
+		first := do(router, httptest.NewRequest(http.MethodGet, path, nil))
+		if w := do(router, formRequest(SAVE+SAVE_UPDATE, url.Values{"slug": {"live"}, "header": {"Edited " + path}, "content": {"New words"}})); w.Code != http.StatusSeeOther {
+			t.Fatalf("editing the post responded %d", w.Code)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for _, c := range first.Result().Cookies() {
+			req.AddCookie(c)
+		}
+		req.Header.Set("If-None-Match", first.Header().Get("ETag"))
+		if w := do(router, req); w.Code != http.StatusOK {
+			t.Errorf("GET %s with the ETag from before an edit responded %d, want %d", path, w.Code, http.StatusOK)
+		}
+	}
+}
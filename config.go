@@ -0,0 +1,347 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/microcosm-cc/bluemonday" // HTML sanitizer
+	"golang.org/x/time/rate"             // Token bucket rate limiter
+)
+
+// Everything the blog can be configured with, read once from the environment at startup by LoadConfig
+type Config struct {
+	DBDriver        string        // DB_DRIVER_POSTGRES or DB_DRIVER_SQLITE
+	DatabaseURL     string        // The Postgres connection string, or the path to the SQLite file
+	PoolSize        int32         // Max connections held open to Postgres
+	ConnectAttempts int           // Tries at connecting to Postgres on startup
+	ConnectDelay    time.Duration // Wait before the first retry, doubling each time
+	SlowQuery       time.Duration // Calls to the store taking longer than this are logged
+
+	ListenAddr       string
+	TLSCert          string // Path to the certificate, HTTPS is only served when it and TLSKey are set
+	TLSKey           string
+	HTTPRedirectAddr string   // Where to listen for plain HTTP to redirect to HTTPS, empty for nowhere
+	BaseURL          string   // Scheme and host absolute links are built from, empty to use each request's host
+	RobotsTxt        string   // Served as robots.txt in place of the default, empty for the default
+	Gzip             bool     // Whether responses are gzipped for clients that accept it
+	CSP              string   // The Content-Security-Policy sent with every page
+	About            string   // Markdown shown on the about page in place of the default, empty for the default
+	Site             Site     // The title, tagline and footer every page is branded with
+	HighlightCSS     string   // The stylesheet for the HIGHLIGHT_STYLE theme, empty when highlighting is turned off
+	Maintenance      bool     // Whether readers are shown the maintenance page in place of the blog
+	CORSOrigins      []string // Origins whose pages may call the JSON API from the browser
+
+	DisplayTimezone *time.Location // The zone dates are shown to readers in, and the post forms take publish dates in
+	DateFormat      string         // The layout dates are shown to readers with
+
+	HTMLPolicy *bluemonday.Policy // What posts' HTML is sanitized with, one of the htmlPolicies
+
+	AdminUser     string // Authors log in with these, if either is empty every protected route is refused
+	AdminPassword string
+
+	RateLimit  rate.Limit // Requests a second each IP may make to the write routes
+	RateBurst  int
+	TrustProxy int // How many proxies in front of the blog to trust the X-Forwarded-For of, 0 to only trust the connection
+
+	PostsPerPage int // How many posts are listed on each page of the homepage, search, tag and author pages
+
+	UploadDir     string // Where uploaded images are saved and served from
+	MaxUploadSize int64  // Bytes an uploaded image may be
+	MaxBodySize   int64  // Bytes the body of any other form or API request may be
+
+	SMTPHost     string // The mail server new comments are emailed through, notifications are off when it's empty
+	SMTPPort     int
+	SMTPUsername string // Only logs in to the mail server when this is set
+	SMTPPassword string
+	SMTPFrom     string
+	NotifyEmail  string // Who's emailed about new comments
+
+	WebhookURL    string // Where posts going live are POSTed to, empty for nowhere
+	WebhookSecret string // Signs what's sent to WebhookURL
+}
+
+// Reads the Config from the environment, using the DEFAULT_* value for anything that's unset.
+// Anything set to a value that can't be used is an error naming the variable, rather than quietly ignored
+func LoadConfig() (Config, error) {
+	config := Config{
+		TLSCert: os.Getenv("TLS_CERT"),
+		TLSKey:  os.Getenv("TLS_KEY"),
+		BaseURL: strings.TrimSuffix(os.Getenv("BASE_URL"), "/"),
+		CSP:     os.Getenv("CONTENT_SECURITY_POLICY"),
+		Site: Site{
+			Title:   os.Getenv("SITE_TITLE"),
+			Tagline: os.Getenv("SITE_TAGLINE"),
+			Footer:  os.Getenv("SITE_FOOTER"),
+		},
+		AdminUser:     os.Getenv("ADMIN_USER"),
+		AdminPassword: os.Getenv("ADMIN_PASSWORD"),
+		SMTPHost:      os.Getenv("SMTP_HOST"),
+		SMTPUsername:  os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:  os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:      os.Getenv("SMTP_FROM"),
+		NotifyEmail:   os.Getenv("NOTIFY_EMAIL"),
+		WebhookURL:    os.Getenv("WEBHOOK_URL"),
+		WebhookSecret: os.Getenv("WEBHOOK_SECRET"),
+	}
+
+	var err error
+	if config.DBDriver = os.Getenv("DB_DRIVER"); config.DBDriver == "" {
+		config.DBDriver = DB_DRIVER_POSTGRES
+	}
+	switch config.DBDriver {
+	case DB_DRIVER_POSTGRES:
+		if config.DatabaseURL, err = databaseURL(); err != nil {
+			return Config{}, err
+		}
+	case DB_DRIVER_SQLITE:
+		if config.DatabaseURL = os.Getenv("DATABASE_URL"); config.DatabaseURL == "" {
+			config.DatabaseURL = DEFAULT_SQLITE_PATH
+		}
+	default:
+		return Config{}, fmt.Errorf("DB_DRIVER must be %s or %s, got %q", DB_DRIVER_POSTGRES, DB_DRIVER_SQLITE, config.DBDriver)
+	}
+	poolSize, err := envInt("DB_POOL_SIZE", DEFAULT_POOL_SIZE)
+	if err != nil {
+		return Config{}, err
+	}
+	config.PoolSize = int32(poolSize)
+	if config.ConnectAttempts, err = envInt("DB_CONNECT_ATTEMPTS", DEFAULT_CONNECT_ATTEMPTS); err != nil {
+		return Config{}, err
+	}
+	if config.ConnectDelay, err = envDuration("DB_CONNECT_DELAY", DEFAULT_CONNECT_DELAY); err != nil {
+		return Config{}, err
+	}
+	if config.SlowQuery, err = envDuration("SLOW_QUERY_THRESHOLD", DEFAULT_SLOW_QUERY_THRESHOLD); err != nil {
+		return Config{}, err
+	}
+
+	if config.ListenAddr, err = listenAddr(); err != nil {
+		return Config{}, err
+	}
+	if config.Gzip, err = envBool("GZIP", DEFAULT_GZIP); err != nil {
+		return Config{}, err
+	}
+	if config.Maintenance, err = envBool("MAINTENANCE", DEFAULT_MAINTENANCE); err != nil {
+		return Config{}, err
+	}
+	if config.Site.Title == "" {
+		config.Site.Title = DEFAULT_SITE_TITLE
+	}
+	if config.Site.Tagline == "" {
+		config.Site.Tagline = DEFAULT_SITE_TAGLINE
+	}
+	if config.CSP == "" {
+		config.CSP = DEFAULT_CSP
+	}
+	if (config.TLSCert == "") != (config.TLSKey == "") {
+		return Config{}, fmt.Errorf("TLS_CERT and TLS_KEY must both be set to serve HTTPS")
+	}
+	if config.HTTPRedirectAddr = os.Getenv("HTTP_REDIRECT_ADDR"); config.HTTPRedirectAddr != "" {
+		if err := validateAddr("HTTP_REDIRECT_ADDR", config.HTTPRedirectAddr); err != nil {
+			return Config{}, err
+		}
+	}
+	if config.BaseURL != "" {
+		parsed, err := url.Parse(config.BaseURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || parsed.RawQuery != "" || parsed.Fragment != "" {
+			return Config{}, fmt.Errorf("BASE_URL should be a scheme and host like https://blog.example.com, got %q", config.BaseURL)
+		}
+	}
+
+	if robotsFile := os.Getenv("ROBOTS_FILE"); robotsFile != "" {
+		robots, err := os.ReadFile(robotsFile)
+		if err != nil {
+			return Config{}, fmt.Errorf("ROBOTS_FILE could not be read: %w", err)
+		}
+		config.RobotsTxt = string(robots)
+	}
+	if aboutFile := os.Getenv("ABOUT_FILE"); aboutFile != "" {
+		about, err := os.ReadFile(aboutFile)
+		if err != nil {
+			return Config{}, fmt.Errorf("ABOUT_FILE could not be read: %w", err)
+		}
+		config.About = string(about)
+	}
+
+	highlightStyle := os.Getenv("HIGHLIGHT_STYLE")
+	if highlightStyle == "" {
+		highlightStyle = DEFAULT_HIGHLIGHT_STYLE
+	}
+	if config.HighlightCSS, err = highlightCSS(highlightStyle); err != nil {
+		return Config{}, err
+	}
+	htmlPolicy := os.Getenv("HTML_POLICY")
+	if htmlPolicy == "" {
+		htmlPolicy = DEFAULT_HTML_POLICY
+	}
+	if config.HTMLPolicy, err = namedHTMLPolicy(htmlPolicy); err != nil {
+		return Config{}, err
+	}
+
+	limit, err := envFloat("RATE_LIMIT", DEFAULT_RATE_LIMIT)
+	if err != nil {
+		return Config{}, err
+	}
+	config.RateLimit = rate.Limit(limit)
+	if config.RateBurst, err = envInt("RATE_BURST", DEFAULT_RATE_BURST); err != nil {
+		return Config{}, err
+	}
+	if config.TrustProxy, err = parseTrustProxy(os.Getenv("TRUST_PROXY")); err != nil {
+		return Config{}, err
+	}
+
+	if config.PostsPerPage, err = envInt("POSTS_PER_PAGE", DEFAULT_POSTS_PER_PAGE); err != nil {
+		return Config{}, err
+	}
+
+	if config.UploadDir = os.Getenv("UPLOAD_DIR"); config.UploadDir == "" {
+		config.UploadDir = DEFAULT_UPLOAD_DIR
+	}
+	maxUploadSize, err := envInt("MAX_UPLOAD_SIZE", DEFAULT_MAX_UPLOAD_SIZE)
+	if err != nil {
+		return Config{}, err
+	}
+	config.MaxUploadSize = int64(maxUploadSize)
+	maxBodySize, err := envInt("MAX_BODY_SIZE", DEFAULT_MAX_BODY_SIZE)
+	if err != nil {
+		return Config{}, err
+	}
+	config.MaxBodySize = int64(maxBodySize)
+
+	if config.CORSOrigins, err = parseCORSOrigins(os.Getenv("CORS_ORIGINS")); err != nil {
+		return Config{}, err
+	}
+
+	if config.SMTPPort, err = envInt("SMTP_PORT", DEFAULT_SMTP_PORT); err != nil {
+		return Config{}, err
+	}
+	if (config.SMTPHost == "") != (config.NotifyEmail == "") {
+		return Config{}, fmt.Errorf("SMTP_HOST and NOTIFY_EMAIL must both be set to be emailed about new comments")
+	}
+	// Otherwise the link in the email would be to whatever host the commenter's request claimed to be for
+	if config.SMTPHost != "" && config.BaseURL == "" {
+		return Config{}, fmt.Errorf("BASE_URL must be set for the comment emails to link to posts")
+	}
+	if config.SMTPFrom == "" {
+		config.SMTPFrom = config.NotifyEmail
+	}
+	if config.WebhookURL != "" {
+		if parsed, err := url.Parse(config.WebhookURL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return Config{}, fmt.Errorf("WEBHOOK_URL should be an http or https URL, got %q", config.WebhookURL)
+		}
+		if config.WebhookSecret == "" {
+			return Config{}, fmt.Errorf("WEBHOOK_SECRET must be set to sign what's sent to WEBHOOK_URL")
+		}
+		// Posts can go live on a schedule with no request around to take the host from
+		if config.BaseURL == "" {
+			return Config{}, fmt.Errorf("BASE_URL must be set for WEBHOOK_URL to be sent links to posts")
+		}
+	}
+
+	config.DisplayTimezone = loadDisplayTimezone(os.Getenv("DISPLAY_TIMEZONE"))
+	if config.DateFormat = os.Getenv("DATE_FORMAT"); config.DateFormat == "" {
+		config.DateFormat = DEFAULT_DATE_FORMAT
+	}
+	return config, nil
+}
+
+// Reads DATABASE_URL from the environment, falling back to DEFAULT_DATABASE_URL if it's unset
+func databaseURL() (string, error) {
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		return DEFAULT_DATABASE_URL, nil
+	}
+
+	parsed, err := url.Parse(dbURL)
+	if err != nil {
+		return "", fmt.Errorf("DATABASE_URL could not be parsed: %w", err)
+	}
+	if parsed.Scheme != "postgres" && parsed.Scheme != "postgresql" {
+		return "", fmt.Errorf("DATABASE_URL must use the postgres:// or postgresql:// scheme, got %q", parsed.Scheme)
+	}
+	if parsed.Host == "" {
+		return "", fmt.Errorf("DATABASE_URL is missing a host")
+	}
+	return dbURL, nil
+}
+
+// Reads the address to listen on from LISTEN_ADDR, or just the port from PORT as most hosting platforms set it,
+// falling back to DEFAULT_LISTEN_ADDR
+func listenAddr() (string, error) {
+	if addr := os.Getenv("LISTEN_ADDR"); addr != "" {
+		return addr, validateAddr("LISTEN_ADDR", addr)
+	}
+	if port := os.Getenv("PORT"); port != "" {
+		return ":" + port, validateAddr("PORT", ":"+port)
+	}
+	return DEFAULT_LISTEN_ADDR, nil
+}
+
+// Checks addr, read from the variable name, is a host:port with a valid port
+func validateAddr(name, addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("%s should look like host:port or :port, got %q: %w", name, addr, err)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("%s needs a port between 1 and 65535, got %q", name, addr)
+	}
+	return nil
+}
+
+// Reads a positive whole number from the variable name, fallback if it's unset
+func envInt(name string, fallback int) (int, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%s must be a whole number of at least 1, got %q", name, raw)
+	}
+	return n, nil
+}
+
+// Reads true or false from the variable name, fallback if it's unset
+func envBool(name string, fallback bool) (bool, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback, nil
+	}
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false, got %q", name, raw)
+	}
+	return b, nil
+}
+
+// Reads a positive number from the variable name, fallback if it's unset
+func envFloat(name string, fallback float64) (float64, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback, nil
+	}
+	n, err := strconv.ParseFloat(raw, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%s must be a number above 0, got %q", name, raw)
+	}
+	return n, nil
+}
+
+// Reads a positive duration like "500ms" from the variable name, fallback if it's unset
+func envDuration(name string, fallback time.Duration) (time.Duration, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s must be a duration like 500ms or 2s, got %q", name, raw)
+	}
+	return d, nil
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/time/rate" // Token bucket rate limiter
+)
+
+// Sets the environment variable key to value until the test's done, as the testing package in go 1.16 can't
+func setenv(t *testing.T, key, value string) {
+	t.Helper()
+	previous, wasSet := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if wasSet {
+			os.Setenv(key, previous)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func TestDatabaseURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		want    string
+		wantErr bool
+	}{
+		{name: "unset", env: "", want: DEFAULT_DATABASE_URL},
+		{name: "postgres", env: "postgres://blog:pw@db.internal:5432/blog", want: "postgres://blog:pw@db.internal:5432/blog"},
+		{name: "postgresql", env: "postgresql://db.internal/blog", want: "postgresql://db.internal/blog"},
+		{name: "wrong scheme", env: "mysql://db.internal/blog", wantErr: true},
+		{name: "no host", env: "postgres:///blog", wantErr: true},
+		{name: "unparseable", env: "postgres://db.internal:port/blog", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setenv(t, "DATABASE_URL", tt.env)
+			got, err := databaseURL()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("databaseURL() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("databaseURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListenAddr(t *testing.T) {
+	tests := []struct {
+		name       string
+		listenAddr string
+		port       string
+		want       string
+		wantErr    bool
+	}{
+		{name: "unset", want: DEFAULT_LISTEN_ADDR},
+		{name: "LISTEN_ADDR", listenAddr: "127.0.0.1:9000", want: "127.0.0.1:9000"},
+		{name: "LISTEN_ADDR without a host", listenAddr: ":9000", want: ":9000"},
+		{name: "PORT", port: "9000", want: ":9000"},
+		{name: "LISTEN_ADDR wins over PORT", listenAddr: "127.0.0.1:9000", port: "9001", want: "127.0.0.1:9000"},
+		{name: "LISTEN_ADDR without a port", listenAddr: "127.0.0.1", wantErr: true},
+		{name: "port out of range", listenAddr: ":70000", wantErr: true},
+		{name: "port zero", port: "0", wantErr: true},
+		{name: "port not a number", port: "http", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setenv(t, "LISTEN_ADDR", tt.listenAddr)
+			setenv(t, "PORT", tt.port)
+			got, err := listenAddr()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("listenAddr() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("listenAddr() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Every variable LoadConfig reads
+var configEnv = []string{
+	"ABOUT_FILE", "ADMIN_PASSWORD", "ADMIN_USER", "BASE_URL", "CONTENT_SECURITY_POLICY", "CORS_ORIGINS", "DATABASE_URL",
+	"DATE_FORMAT", "DB_CONNECT_ATTEMPTS", "DB_CONNECT_DELAY", "DB_DRIVER", "DB_POOL_SIZE", "DISPLAY_TIMEZONE", "GZIP",
+	"HIGHLIGHT_STYLE", "HTML_POLICY", "HTTP_REDIRECT_ADDR", "LISTEN_ADDR", "MAINTENANCE", "MAX_BODY_SIZE",
+	"MAX_UPLOAD_SIZE", "NOTIFY_EMAIL", "PORT", "POSTS_PER_PAGE", "RATE_BURST", "RATE_LIMIT", "ROBOTS_FILE",
+	"SITE_FOOTER", "SITE_TAGLINE", "SITE_TITLE", "SLOW_QUERY_THRESHOLD", "SMTP_FROM", "SMTP_HOST", "SMTP_PASSWORD",
+	"SMTP_PORT", "SMTP_USERNAME", "TLS_CERT", "TLS_KEY", "TRUST_PROXY", "UPLOAD_DIR", "WEBHOOK_SECRET", "WEBHOOK_URL",
+}
+
+// Unsets every variable LoadConfig reads until the test's done, so whatever the machine has set can't leak in
+func clearConfigEnv(t *testing.T) {
+	for _, key := range configEnv {
+		setenv(t, key, "")
+	}
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	clearConfigEnv(t)
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig with nothing set: %v", err)
+	}
+
+	checks := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"DBDriver", config.DBDriver, DB_DRIVER_POSTGRES},
+		{"DatabaseURL", config.DatabaseURL, DEFAULT_DATABASE_URL},
+		{"PoolSize", config.PoolSize, int32(DEFAULT_POOL_SIZE)},
+		{"ConnectAttempts", config.ConnectAttempts, DEFAULT_CONNECT_ATTEMPTS},
+		{"ConnectDelay", config.ConnectDelay, DEFAULT_CONNECT_DELAY},
+		{"ListenAddr", config.ListenAddr, DEFAULT_LISTEN_ADDR},
+		{"PostsPerPage", config.PostsPerPage, DEFAULT_POSTS_PER_PAGE},
+		{"RateLimit", config.RateLimit, rate.Limit(DEFAULT_RATE_LIMIT)},
+		{"RateBurst", config.RateBurst, DEFAULT_RATE_BURST},
+		{"AdminUser", config.AdminUser, ""},
+		{"TLSCert", config.TLSCert, ""},
+		{"BaseURL", config.BaseURL, ""},
+		{"Gzip", config.Gzip, DEFAULT_GZIP},
+		{"CSP", config.CSP, DEFAULT_CSP},
+		{"UploadDir", config.UploadDir, DEFAULT_UPLOAD_DIR},
+		{"MaxUploadSize", config.MaxUploadSize, int64(DEFAULT_MAX_UPLOAD_SIZE)},
+		{"MaxBodySize", config.MaxBodySize, int64(DEFAULT_MAX_BODY_SIZE)},
+		{"DateFormat", config.DateFormat, DEFAULT_DATE_FORMAT},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestLoadConfigOverrides(t *testing.T) {
+	clearConfigEnv(t)
+	env := map[string]string{
+		"DB_DRIVER":               DB_DRIVER_SQLITE,
+		"DATABASE_URL":            "/var/lib/blog.db",
+		"DB_POOL_SIZE":            "20",
+		"PORT":                    "9000",
+		"POSTS_PER_PAGE":          "5",
+		"RATE_LIMIT":              "0.5",
+		"BASE_URL":                "https://blog.example.com/",
+		"ADMIN_USER":              "ada",
+		"GZIP":                    "false",
+		"CONTENT_SECURITY_POLICY": "default-src 'self'",
+	}
+	for key, value := range env {
+		setenv(t, key, value)
+	}
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	checks := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"DBDriver", config.DBDriver, DB_DRIVER_SQLITE},
+		{"DatabaseURL", config.DatabaseURL, "/var/lib/blog.db"},
+		{"PoolSize", config.PoolSize, int32(20)},
+		{"ListenAddr", config.ListenAddr, ":9000"},
+		{"PostsPerPage", config.PostsPerPage, 5},
+		{"RateLimit", config.RateLimit, rate.Limit(0.5)},
+		{"BaseURL", config.BaseURL, "https://blog.example.com"},
+		{"AdminUser", config.AdminUser, "ada"},
+		{"Gzip", config.Gzip, false},
+		{"CSP", config.CSP, "default-src 'self'"},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		env  map[string]string
+		want string // What the error should name
+	}{
+		{env: map[string]string{"DATABASE_URL": "mysql://db.internal/blog"}, want: "DATABASE_URL"},
+		{env: map[string]string{"DB_DRIVER": "mysql"}, want: "DB_DRIVER"},
+		{env: map[string]string{"DB_POOL_SIZE": "0"}, want: "DB_POOL_SIZE"},
+		{env: map[string]string{"DB_CONNECT_DELAY": "soon"}, want: "DB_CONNECT_DELAY"},
+		{env: map[string]string{"POSTS_PER_PAGE": "-1"}, want: "POSTS_PER_PAGE"},
+		{env: map[string]string{"RATE_LIMIT": "fast"}, want: "RATE_LIMIT"},
+		{env: map[string]string{"LISTEN_ADDR": "nowhere"}, want: "LISTEN_ADDR"},
+		{env: map[string]string{"HTTP_REDIRECT_ADDR": ":70000"}, want: "HTTP_REDIRECT_ADDR"},
+		{env: map[string]string{"TLS_CERT": "cert.pem"}, want: "TLS_KEY"},
+		{env: map[string]string{"BASE_URL": "blog.example.com"}, want: "BASE_URL"},
+		{env: map[string]string{"BASE_URL": "ftp://blog.example.com"}, want: "BASE_URL"},
+		{env: map[string]string{"BASE_URL": "https://blog.example.com?lang=en"}, want: "BASE_URL"},
+		{env: map[string]string{"BASE_URL": "https://"}, want: "BASE_URL"},
+		{env: map[string]string{"ROBOTS_FILE": "/nonexistent/robots.txt"}, want: "ROBOTS_FILE"},
+		{env: map[string]string{"ABOUT_FILE": "/nonexistent/about.md"}, want: "ABOUT_FILE"},
+		{env: map[string]string{"MAX_UPLOAD_SIZE": "10MB"}, want: "MAX_UPLOAD_SIZE"},
+		{env: map[string]string{"GZIP": "sometimes"}, want: "GZIP"},
+		{env: map[string]string{"HIGHLIGHT_STYLE": "no-such-theme"}, want: "HIGHLIGHT_STYLE"},
+		{env: map[string]string{"HTML_POLICY": "anything-goes"}, want: "HTML_POLICY"},
+		{env: map[string]string{"MAINTENANCE": "maybe"}, want: "MAINTENANCE"},
+		{env: map[string]string{"SMTP_HOST": "mail.example.com"}, want: "NOTIFY_EMAIL"},
+		{env: map[string]string{"SMTP_HOST": "mail.example.com", "NOTIFY_EMAIL": "me@example.com"}, want: "BASE_URL"},
+		{env: map[string]string{"WEBHOOK_URL": "https://hooks.example.com", "BASE_URL": "https://blog.example.com"}, want: "WEBHOOK_SECRET"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			clearConfigEnv(t)
+			for key, value := range tt.env {
+				setenv(t, key, value)
+			}
+			if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadConfig with %v = %v, want an error naming %s", tt.env, err, tt.want)
+			}
+		})
+	}
+}
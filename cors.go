@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	CORS_ANY_ORIGIN    = "*"                           // Put in CORS_ORIGINS to let every site call the API
+	CORS_ALLOW_HEADERS = "Authorization, Content-Type" // What a frontend needs to log in and send JSON
+	CORS_EXPOSE        = "Location, X-Request-ID"      // Response headers the API's clients may want to read
+	CORS_MAX_AGE       = 600                           // Seconds browsers may cache a preflight answer for
+)
+
+// Lets browser frontends on the origins in CORS_ORIGINS call the API routes. Requests from anywhere else that say
+// where they're from are refused with a 403, bar the blog's own pages. methods are the ones the route accepts,
+// an OPTIONS preflight is answered here and never reaches the handler
+func (b *Blog) corsMiddleware(handlerFn http.HandlerFunc, methods ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || sameHost(origin, r) {
+			// Not a cross-origin request, so there's nothing for CORS to say
+			handlerFn(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		if !b.allowedOrigin(origin) {
+			writeJSONError(w, r, http.StatusForbidden, "Requests from "+origin+" aren't allowed.")
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Expose-Headers", CORS_EXPOSE)
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+			w.Header().Set("Access-Control-Allow-Headers", CORS_ALLOW_HEADERS)
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(CORS_MAX_AGE))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		handlerFn(w, r)
+	}
+}
+
+// Whether origin is the host the request was made to, the scheme isn't compared as TLS may end at a proxy in front of us
+func sameHost(origin string, r *http.Request) bool {
+	parsed, err := url.Parse(origin)
+	return err == nil && parsed.Host == r.Host
+}
+
+func (b *Blog) allowedOrigin(origin string) bool {
+	for _, allowed := range b.config.CORSOrigins {
+		if allowed == CORS_ANY_ORIGIN || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// Splits the comma separated CORS_ORIGINS into origins like https://app.example.com, which is all browsers send
+func parseCORSOrigins(raw string) ([]string, error) {
+	var origins []string
+	for _, origin := range strings.Split(raw, ",") {
+		origin = strings.TrimSuffix(strings.TrimSpace(origin), "/")
+		if origin == "" {
+			continue
+		}
+		if origin != CORS_ANY_ORIGIN {
+			parsed, err := url.Parse(origin)
+			if err != nil || parsed.Scheme == "" || parsed.Host == "" || parsed.Path != "" {
+				return nil, fmt.Errorf("CORS_ORIGINS should be origins like https://app.example.com separated by commas, got %q", origin)
+			}
+		}
+		origins = append(origins, origin)
+	}
+	return origins, nil
+}
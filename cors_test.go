@@ -0,0 +1,121 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// A request to target from a page on origin, the way a browser sends it
+func crossOriginRequest(method, target, origin string) *http.Request {
+	req := httptest.NewRequest(method, target, nil)
+	req.Header.Set("Origin", origin)
+	return req
+}
+
+func TestCORS(t *testing.T) {
+	config := testConfig(t)
+	config.CORSOrigins = []string{"https://app.example.com"}
+	b := newFakeBlog(t, config)
+	createLivePost(t, b.store, "live")
+	router := newRouter(b)
+
+	w := do(router, crossOriginRequest(http.MethodGet, API_POSTS, "https://app.example.com"))
+	if w.Code != http.StatusOK {
+		t.Errorf("GET %s from an allowed origin responded %d, want %d", API_POSTS, w.Code, http.StatusOK)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("GET %s from an allowed origin has Access-Control-Allow-Origin %q, want the origin", API_POSTS, got)
+	}
+	if got := w.Header().Get("Vary"); !strings.Contains(got, "Origin") {
+		t.Errorf("GET %s from an allowed origin has Vary %q, want Origin", API_POSTS, got)
+	}
+
+	w = do(router, crossOriginRequest(http.MethodGet, API_POSTS, "https://evil.example.com"))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("GET %s from another origin responded %d, want %d", API_POSTS, w.Code, http.StatusForbidden)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("GET %s from another origin has Access-Control-Allow-Origin %q, want none", API_POSTS, got)
+	}
+	var apiErr apiError
+	decodeJSON(t, w, &apiErr)
+
+	// The blog's own pages, and anything that isn't a browser, don't need allowing
+	for _, req := range []*http.Request{
+		crossOriginRequest(http.MethodGet, API_POSTS, "http://example.com"),
+		httptest.NewRequest(http.MethodGet, API_POSTS, nil),
+	} {
+		if w := do(router, req); w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("GET %s with Origin %q responded %d, want %d without CORS headers", API_POSTS, req.Header.Get("Origin"), w.Code, http.StatusOK)
+		}
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	config := testConfig(t)
+	config.CORSOrigins = []string{"https://app.example.com"}
+	b := newFakeBlog(t, config)
+	createLivePost(t, b.store, "live")
+	router := newRouter(b)
+
+	req := crossOriginRequest(http.MethodOptions, API_POSTS+"/live", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPut)
+	req.Header.Set("Access-Control-Request-Headers", "authorization, content-type")
+	w := do(router, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("OPTIONS %s/live responded %d, want %d", API_POSTS, w.Code, http.StatusNoContent)
+	}
+	for header, want := range map[string]string{
+		"Access-Control-Allow-Origin":  "https://app.example.com",
+		"Access-Control-Allow-Headers": CORS_ALLOW_HEADERS,
+		"Access-Control-Max-Age":       "600",
+	} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("OPTIONS %s/live %s = %q, want %q", API_POSTS, header, got, want)
+		}
+	}
+	if got := w.Header().Get("Access-Control-Allow-Methods"); !strings.Contains(got, http.MethodPut) || !strings.Contains(got, http.MethodDelete) {
+		t.Errorf("OPTIONS %s/live Access-Control-Allow-Methods = %q, want the methods the route takes", API_POSTS, got)
+	}
+	// Answering the preflight mustn't have run the handler
+	if p := getPost(t, b.store, "live"); p.Header != "Post live" {
+		t.Errorf("the preflight changed the post to %+v", p)
+	}
+
+	req.Header.Set("Origin", "https://evil.example.com")
+	if w := do(router, req); w.Code != http.StatusForbidden || w.Header().Get("Access-Control-Allow-Methods") != "" {
+		t.Errorf("OPTIONS %s/live from another origin responded %d, want %d without allowing anything", API_POSTS, w.Code, http.StatusForbidden)
+	}
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	config := testConfig(t)
+	config.CORSOrigins = []string{CORS_ANY_ORIGIN}
+	w := do(newRouter(newFakeBlog(t, config)), crossOriginRequest(http.MethodGet, API_POSTS, "https://anywhere.example.com"))
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "https://anywhere.example.com" {
+		t.Errorf("GET %s with CORS_ORIGINS=* responded %d, want %d allowing the origin", API_POSTS, w.Code, http.StatusOK)
+	}
+}
+
+func TestParseCORSOrigins(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    []string
+		wantErr bool
+	}{
+		{raw: ""},
+		{raw: "*", want: []string{"*"}},
+		{raw: "https://a.example.com/, http://localhost:3000", want: []string{"https://a.example.com", "http://localhost:3000"}},
+		{raw: "a.example.com", wantErr: true},
+		{raw: "https://a.example.com/path", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseCORSOrigins(tt.raw)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseCORSOrigins(%q) = %v, %v, want %v, error %v", tt.raw, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+)
+
+const (
+	CSRF_COOKIE = "csrf_token" // Cookie holding the browser's token
+	CSRF_FIELD  = "csrf_token" // Hidden form field the token is echoed back in
+)
+
+// Type used for templating the forms that write posts
+type FormPage struct {
+	CSRFToken string
+	Post      Post   // The post being edited, empty when writing a new one
+	Flash     string // A one-off message from the page before
+}
+
+// Renders one of the post forms with the browser's CSRF token embedded in it, filled in from post
+func (b *Blog) renderForm(w http.ResponseWriter, r *http.Request, name string, post Post) {
+	token, err := csrfToken(w, r)
+	if err != nil {
+		b.renderError(w, r, http.StatusInternalServerError, fmt.Errorf("failed to generate a CSRF token: %w", err))
+		return
+	}
+
+	b.renderTemplate(w, r, name, FormPage{CSRFToken: token, Post: post, Flash: takeFlash(w, r)})
+}
+
+// Returns the CSRF token for this browser, issuing a cookie with a fresh one if it doesn't have one yet
+func csrfToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	if cookie, err := r.Cookie(CSRF_COOKIE); err == nil && cookie.Value != "" {
+		return cookie.Value, nil
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     CSRF_COOKIE,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return token, nil
+}
+
+// Reports whether a submitted form echoed back the token in the browser's cookie, which another site can't read to forge
+func validCSRF(r *http.Request) bool {
+	cookie, err := r.Cookie(CSRF_COOKIE)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+	submitted := r.PostFormValue(CSRF_FIELD)
+	return subtle.ConstantTimeCompare([]byte(submitted), []byte(cookie.Value)) == 1
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestFormsIssueACSRFToken(t *testing.T) {
+	router := newRouter(newFakeBlog(t, testConfig(t)))
+
+	w := do(router, adminRequest(http.MethodGet, NEW, nil))
+	var cookie *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == CSRF_COOKIE {
+			cookie = c
+		}
+	}
+	if cookie == nil || cookie.Value == "" {
+		t.Fatalf("GET %s set no %s cookie", NEW, CSRF_COOKIE)
+	}
+	if !cookie.HttpOnly || cookie.SameSite != http.SameSiteStrictMode {
+		t.Errorf("the %s cookie is %+v, want it HttpOnly and SameSite=Strict", CSRF_COOKIE, cookie)
+	}
+	if field := `value="` + cookie.Value + `"`; !strings.Contains(w.Body.String(), field) {
+		t.Errorf("GET %s doesn't echo the cookie's token back in the form", NEW)
+	}
+
+	// A browser that already has a token keeps it
+	req := adminRequest(http.MethodGet, NEW, nil)
+	req.AddCookie(cookie)
+	w = do(router, req)
+	if len(w.Result().Cookies()) != 0 {
+		t.Errorf("GET %s with a token set another cookie", NEW)
+	}
+	if !strings.Contains(w.Body.String(), `value="`+cookie.Value+`"`) {
+		t.Errorf("GET %s with a token didn't put it in the form", NEW)
+	}
+}
+
+func TestSavingNeedsTheCSRFToken(t *testing.T) {
+	// The token's checked before Postgres is touched, so nothing can be saved
+	b := newClosedDBBlog(t)
+
+	tests := []struct {
+		name   string
+		cookie string // Empty for no cookie
+		field  string // Empty for no field
+	}{
+		{name: "no token", cookie: "", field: ""},
+		{name: "no cookie", cookie: "", field: TEST_CSRF_TOKEN},
+		{name: "no field", cookie: TEST_CSRF_TOKEN, field: ""},
+		{name: "mismatched", cookie: TEST_CSRF_TOKEN, field: "forged"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{"header": {"Forged"}, "content": {"Words"}, "slug": {"forged"}}
+			if tt.field != "" {
+				form.Set(CSRF_FIELD, tt.field)
+			}
+			req := adminRequest(http.MethodPost, SAVE+SAVE_ADD, strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: CSRF_COOKIE, Value: tt.cookie})
+			}
+
+			if w := do(newRouter(b), req); w.Code != http.StatusForbidden {
+				t.Errorf("POST %s responded %d, want %d", SAVE+SAVE_ADD, w.Code, http.StatusForbidden)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	DEFAULT_DISPLAY_TIMEZONE = "UTC"            // The zone dates are shown to readers in, override with DISPLAY_TIMEZONE e.g. Europe/London
+	DEFAULT_DATE_FORMAT      = "2 January 2006" // How dates are shown to readers, override with DATE_FORMAT as a Go time layout
+	DISPLAY_TIME_FORMAT      = "15:04 MST"      // Follows the date where the time matters too, with the zone so authors know which it's in
+)
+
+// Timestamps are stored in UTC, this is t as readers see it in the DISPLAY_TIMEZONE and DATE_FORMAT
+func (b *Blog) formatDate(t time.Time) string {
+	return t.In(b.config.DisplayTimezone).Format(b.config.DateFormat)
+}
+
+// Like formatDate with the time of day added, for when a post is scheduled or was last edited
+func (b *Blog) formatDateTime(t time.Time) string {
+	return b.formatDate(t) + " at " + t.In(b.config.DisplayTimezone).Format(DISPLAY_TIME_FORMAT)
+}
+
+// How long ago t was, like "3 days ago", or for times still to come like a scheduled post's, "in 3 days"
+func humanizeTime(t time.Time) string {
+	return humanizeTimeFrom(t, time.Now())
+}
+
+// humanizeTime as it would be at now. Each unit is counted in whole amounts, rounding down, so 47 hours is still "1 day ago"
+func humanizeTimeFrom(t, now time.Time) string {
+	d := now.Sub(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+	if d < time.Second {
+		return "just now"
+	}
+
+	const day = 24 * time.Hour
+	var amount int
+	var unit string
+	switch {
+	case d < time.Minute:
+		amount, unit = int(d/time.Second), "second"
+	case d < time.Hour:
+		amount, unit = int(d/time.Minute), "minute"
+	case d < day:
+		amount, unit = int(d/time.Hour), "hour"
+	case d < 7*day:
+		amount, unit = int(d/day), "day"
+	case d < 30*day:
+		amount, unit = int(d/(7*day)), "week"
+	case d < 12*30*day:
+		amount, unit = int(d/(30*day)), "month"
+	default:
+		// Twelve 30 day months fall short of a year, so the few days between are rounded up to one rather than read "12 months"
+		if amount, unit = int(d/(365*day)), "year"; amount == 0 {
+			amount = 1
+		}
+	}
+	if amount != 1 {
+		unit += "s"
+	}
+
+	if future {
+		return fmt.Sprintf("in %d %s", amount, unit)
+	}
+	return fmt.Sprintf("%d %s ago", amount, unit)
+}
+
+// Loads the zone named by DISPLAY_TIMEZONE. Unlike the rest of the config a bad one doesn't stop the blog starting,
+// dates in UTC are still right, just less handy, so it's logged and UTC used instead
+func loadDisplayTimezone(name string) *time.Location {
+	if name == "" {
+		name = DEFAULT_DISPLAY_TIMEZONE
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		log.Printf("DISPLAY_TIMEZONE %q isn't a timezone we know, showing dates in UTC instead: %v", name, err)
+		return time.UTC
+	}
+	return location
+}
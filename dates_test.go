@@ -0,0 +1,147 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A Blog showing dates in the zone called name, with layout
+func datesBlog(t *testing.T, name, layout string) *Blog {
+	t.Helper()
+	config := testConfig(t)
+	config.DisplayTimezone = loadDisplayTimezone(name)
+	config.DateFormat = layout
+	return newFakeBlog(t, config)
+}
+
+func TestFormatDate(t *testing.T) {
+	// Late on New Year's Eve in UTC, which is already New Year's Day further east
+	at := time.Date(2020, 12, 31, 23, 30, 0, 0, time.UTC)
+	tests := []struct {
+		zone, layout       string
+		wantDate, wantTime string
+	}{
+		{zone: "UTC", layout: DEFAULT_DATE_FORMAT, wantDate: "31 December 2020", wantTime: "31 December 2020 at 23:30 UTC"},
+		{zone: "Europe/Berlin", layout: DEFAULT_DATE_FORMAT, wantDate: "1 January 2021", wantTime: "1 January 2021 at 00:30 CET"},
+		{zone: "America/New_York", layout: DEFAULT_DATE_FORMAT, wantDate: "31 December 2020", wantTime: "31 December 2020 at 18:30 EST"},
+		{zone: "Asia/Kolkata", layout: "2006-01-02", wantDate: "2021-01-01", wantTime: "2021-01-01 at 05:00 IST"},
+	}
+	for _, tt := range tests {
+		b := datesBlog(t, tt.zone, tt.layout)
+		if got := b.formatDate(at); got != tt.wantDate {
+			t.Errorf("formatDate in %s = %q, want %q", tt.zone, got, tt.wantDate)
+		}
+		if got := b.formatDateTime(at); got != tt.wantTime {
+			t.Errorf("formatDateTime in %s = %q, want %q", tt.zone, got, tt.wantTime)
+		}
+	}
+}
+
+func TestLoadDisplayTimezone(t *testing.T) {
+	logs := captureLog(t)
+	if got := loadDisplayTimezone(""); got != time.UTC {
+		t.Errorf("loadDisplayTimezone(\"\") = %v, want UTC", got)
+	}
+	if got := loadDisplayTimezone("Europe/London"); got.String() != "Europe/London" {
+		t.Errorf("loadDisplayTimezone(Europe/London) = %v", got)
+	}
+	if logs.String() != "" {
+		t.Errorf("loading good zones logged %q", logs.String())
+	}
+
+	if got := loadDisplayTimezone("Mars/Olympus_Mons"); got != time.UTC {
+		t.Errorf("loadDisplayTimezone(Mars/Olympus_Mons) = %v, want UTC", got)
+	}
+	if !strings.Contains(logs.String(), `DISPLAY_TIMEZONE "Mars/Olympus_Mons"`) {
+		t.Errorf("falling back to UTC wasn't logged:\n%s", logs.String())
+	}
+}
+
+func TestPagesShowDatesInTheDisplayTimezone(t *testing.T) {
+	b := datesBlog(t, "Pacific/Auckland", "Mon 2 Jan 2006")
+	createPost(t, b.store, Post{Header: "Dated", Content: "Words", Slug: "dated", Author: "Tester", Published: true, PublishedAt: time.Date(2021, 3, 14, 20, 0, 0, 0, time.UTC)})
+
+	body := do(newRouter(b), httptest.NewRequest(http.MethodGet, POST+"dated", nil)).Body.String()
+	if !strings.Contains(body, "Published Mon 15 Mar 2021") {
+		t.Errorf("GET %sdated doesn't show the publish date in Auckland's time:\n%s", POST, body)
+	}
+}
+
+func TestFormsTakeDatesInTheDisplayTimezone(t *testing.T) {
+	b := datesBlog(t, "Pacific/Auckland", DEFAULT_DATE_FORMAT)
+	router := newRouter(b)
+
+	form := url.Values{"header": {"Dated"}, "content": {"Words"}, "slug": {"dated"}, "published_at": {"2021-03-15T09:00"}}
+	if w := do(router, formRequest(SAVE+SAVE_ADD, form)); w.Code != http.StatusSeeOther {
+		t.Fatalf("POST %s responded %d, want %d", SAVE+SAVE_ADD, w.Code, http.StatusSeeOther)
+	}
+	if p, want := getPost(t, b.store, "dated"), time.Date(2021, 3, 14, 20, 0, 0, 0, time.UTC); !p.PublishedAt.Equal(want) {
+		t.Errorf("publish date 2021-03-15T09:00 in Auckland saved as %v, want %v", p.PublishedAt, want)
+	}
+
+	body := do(router, adminRequest(http.MethodGet, EDIT+"dated", nil)).Body.String()
+	for _, want := range []string{`name="published_at" value="2021-03-15T09:00"`, "Publish date (Pacific/Auckland)"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("GET %sdated doesn't contain %s:\n%s", EDIT, want, body)
+		}
+	}
+	if body := do(router, adminRequest(http.MethodGet, NEW, nil)).Body.String(); !strings.Contains(body, "Publish date (Pacific/Auckland)") {
+		t.Errorf("GET %s doesn't label the publish date with Auckland's zone:\n%s", NEW, body)
+	}
+}
+
+func TestHumanizeTime(t *testing.T) {
+	now := time.Date(2021, 6, 15, 12, 0, 0, 0, time.UTC)
+	const day = 24 * time.Hour
+	tests := []struct {
+		ago  time.Duration // How long before now, negative for times still to come
+		want string
+	}{
+		{0, "just now"},
+		{999 * time.Millisecond, "just now"},
+		{-999 * time.Millisecond, "just now"},
+		{time.Second, "1 second ago"},
+		{59 * time.Second, "59 seconds ago"},
+		{time.Minute, "1 minute ago"},
+		{119 * time.Second, "1 minute ago"},
+		{59*time.Minute + 59*time.Second, "59 minutes ago"},
+		{time.Hour, "1 hour ago"},
+		{23*time.Hour + 59*time.Minute, "23 hours ago"},
+		{day, "1 day ago"},
+		{47 * time.Hour, "1 day ago"},
+		{6*day + 23*time.Hour, "6 days ago"},
+		{7 * day, "1 week ago"},
+		{29 * day, "4 weeks ago"},
+		{30 * day, "1 month ago"},
+		{359 * day, "11 months ago"},
+		{360 * day, "1 year ago"},
+		{364 * day, "1 year ago"},
+		{365 * day, "1 year ago"},
+		{3 * 365 * day, "3 years ago"},
+		{-time.Second, "in 1 second"},
+		{-90 * time.Minute, "in 1 hour"},
+		{-3 * day, "in 3 days"},
+		{-400 * day, "in 1 year"},
+	}
+	for _, tt := range tests {
+		if got := humanizeTimeFrom(now.Add(-tt.ago), now); got != tt.want {
+			t.Errorf("humanizeTimeFrom(now - %v) = %q, want %q", tt.ago, got, tt.want)
+		}
+	}
+}
+
+func TestPagesShowRelativeTimes(t *testing.T) {
+	b := newFakeBlog(t, testConfig(t))
+	createPost(t, b.store, Post{Header: "Later", Content: "Words", Slug: "later", Author: "Tester", Published: true, PublishedAt: time.Now().Add(3*24*time.Hour + time.Hour)})
+
+	body := do(newRouter(b), adminRequest(http.MethodGet, ADMIN, nil)).Body.String()
+	for _, want := range []string{"Scheduled to go live <span", ">in 3 days</span>", "just now"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("GET %s is missing %q", ADMIN, want)
+		}
+	}
+}
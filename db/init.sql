@@ -1,8 +1,51 @@
+-- The app creates and migrates its own schema from migrations/ on startup, this just gives the seed data somewhere to go
+-- on a fresh docker volume, so keep it in step with the migrations
+DROP TABLE IF EXISTS slug_redirects;
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS post_tags;
+DROP TABLE IF EXISTS tags;
 DROP TABLE IF EXISTS posts;
 
 CREATE TABLE posts (
 	id      SERIAL PRIMARY KEY, 
 	header  VARCHAR NOT NULL,  -- The title of the Post
 	content TEXT NOT NULL,     -- The content of the blog post
-	slug    VARCHAR UNIQUE NOT NULL   -- The url we access this post on
+	slug    VARCHAR UNIQUE NOT NULL,  -- The url we access this post on
+	author  VARCHAR NOT NULL DEFAULT 'Kealan Parr', -- Who wrote the post
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(), -- When the post was first saved
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now(), -- When the post was last edited
+	published  BOOLEAN NOT NULL DEFAULT false,     -- Drafts are hidden from the public pages
+	views      INTEGER NOT NULL DEFAULT 0,         -- How many times readers have opened the post
+	published_at TIMESTAMPTZ NOT NULL DEFAULT now(), -- The date the post is shown and ordered by, can be backdated unlike created_at
+	deleted_at   TIMESTAMPTZ, -- Set when the post is deleted, it's kept so it can be restored until it's purged
+	canonical_url VARCHAR NOT NULL DEFAULT '', -- Where the post was first published if it's cross-posted, empty if it's ours
+	no_index     BOOLEAN NOT NULL DEFAULT false, -- Asks search engines not to index the post, and leaves it out of the sitemap
+	preview_token VARCHAR NOT NULL DEFAULT '', -- Lets anyone with it read the post before it's live, empty until the author first shares it
+	announced    BOOLEAN NOT NULL DEFAULT false -- Set once the post's gone live and the webhook's been told, so it's only ever told once
+);
+
+CREATE TABLE tags (
+	id   SERIAL PRIMARY KEY,
+	name VARCHAR UNIQUE NOT NULL -- Normalized like a slug, so it can be used in the /tag/ url
+);
+
+CREATE TABLE post_tags (
+	post_id INTEGER NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+	tag_id  INTEGER NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+	PRIMARY KEY (post_id, tag_id)
+);
+
+CREATE TABLE comments (
+	id         SERIAL PRIMARY KEY,
+	post_id    INTEGER NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+	author     VARCHAR NOT NULL,
+	body       TEXT NOT NULL,  -- Stored as written, it's escaped when rendered
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX comments_post_id ON comments (post_id);
+
+CREATE TABLE slug_redirects (
+	old_slug VARCHAR PRIMARY KEY, -- A slug the post had before it was renamed
+	post_id  INTEGER NOT NULL REFERENCES posts (id) ON DELETE CASCADE
 );
\ No newline at end of file
@@ -1,6 +1,6 @@
 INSERT INTO posts
-(header, content, slug)
+(header, content, slug, published)
 VALUES
-('Why I think go will take over the world', 'Everyone has heard of go nowadays.....', 'go-take-over-the-world'),
-('go has one huge issue still facing it', 'go has taken the world by storm. But there''s still a hurdle they need to overcome...', 'go-major-issue'),
-('My Node website rewritten in go performance comparison', 'Everyone has heard of golang at this point.....', 'golang-vs-node')
\ No newline at end of file
+('Why I think go will take over the world', 'Everyone has heard of go nowadays.....', 'go-take-over-the-world', true),
+('go has one huge issue still facing it', 'go has taken the world by storm. But there''s still a hurdle they need to overcome...', 'go-major-issue', true),
+('My Node website rewritten in go performance comparison', 'Everyone has heard of golang at this point.....', 'golang-vs-node', true)
\ No newline at end of file
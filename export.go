@@ -0,0 +1,110 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	EXPORT_JSON     = "json"     // The default, every post in one JSON array
+	EXPORT_MARKDOWN = "markdown" // A zip with a Markdown file for each post, its details in front matter
+
+	EXPORT_DATE_FORMAT = "2006-01-02" // Goes in the export's filename
+)
+
+// Serves every post as a download for backups, in the format ?format= picks. Either can be fed back in with -import.
+// Posts are written out as they're read, so exporting a large blog doesn't need it all in memory
+func (b *Blog) adminExportHandler(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = EXPORT_JSON
+	}
+	if format != EXPORT_JSON && format != EXPORT_MARKDOWN {
+		http.Error(w, "Posts can be exported as json or markdown.", http.StatusBadRequest)
+		return
+	}
+
+	// No QUERY_TIMEOUT, as a big blog can take a while to send. It still stops if the client goes away
+	ctx := r.Context()
+	filename := "blog-" + time.Now().UTC().Format(EXPORT_DATE_FORMAT)
+	w.Header().Set("Cache-Control", "no-store")
+
+	var err error
+	if format == EXPORT_MARKDOWN {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.zip"`)
+		archive := zip.NewWriter(w)
+		err = b.store.Export(ctx, func(p Post) error {
+			file, err := archive.Create(p.Slug + IMPORT_MARKDOWN_EXT)
+			if err != nil {
+				return err
+			}
+			_, err = file.Write([]byte(markdownPost(p)))
+			return err
+		})
+		if err == nil {
+			err = archive.Close()
+		}
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.json"`)
+		separator := "[\n"
+		err = b.store.Export(ctx, func(p Post) error {
+			post, err := json.Marshal(p)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write([]byte(separator + string(post)))
+			separator = ",\n"
+			return err
+		})
+		if err == nil {
+			if separator == "[\n" {
+				// There were no posts
+				_, err = w.Write([]byte("[]\n"))
+			} else {
+				_, err = w.Write([]byte("\n]\n"))
+			}
+		}
+	}
+
+	if err != nil {
+		// It's too late to send an error page, but leaving the archive or array unfinished means it won't be mistaken for a whole backup
+		logf(ctx, "Failed to export the posts: %v", err)
+	}
+}
+
+// The post as a Markdown file with front matter that parseMarkdownPost reads back in
+func markdownPost(p Post) string {
+	var sb strings.Builder
+	sb.WriteString(FRONT_MATTER_FENCE + "\n")
+	fmt.Fprintf(&sb, "header: %s\n", frontMatterString(p.Header))
+	fmt.Fprintf(&sb, "slug: %s\n", p.Slug)
+	if p.Author != "" {
+		fmt.Fprintf(&sb, "author: %s\n", frontMatterString(p.Author))
+	}
+	fmt.Fprintf(&sb, "tags: [%s]\n", p.TagList())
+	if p.CanonicalURL != "" {
+		fmt.Fprintf(&sb, "canonical_url: %s\n", frontMatterString(p.CanonicalURL))
+	}
+	if p.NoIndex {
+		sb.WriteString("no_index: true\n")
+	}
+	fmt.Fprintf(&sb, "published: %s\n", strconv.FormatBool(p.Published))
+	fmt.Fprintf(&sb, "published_at: %s\n", p.PublishedAt.UTC().Format(time.RFC3339Nano))
+	fmt.Fprintf(&sb, "created_at: %s\n", p.CreatedAt.UTC().Format(time.RFC3339Nano))
+	fmt.Fprintf(&sb, "updated_at: %s\n", p.UpdatedAt.UTC().Format(time.RFC3339Nano))
+	sb.WriteString(FRONT_MATTER_FENCE + "\n")
+	sb.WriteString(p.Content + "\n")
+	return sb.String()
+}
+
+// Quotes s so any quotes of its own at either end survive being read back, and keeps it on the one line
+func frontMatterString(s string) string {
+	return `"` + strings.NewReplacer("\r", " ", "\n", " ").Replace(s) + `"`
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Posts with everything an export has to carry, live, draft, tagged, backdated and cross-posted
+func createExportPosts(t *testing.T, store PostStore) {
+	t.Helper()
+	createPost(t, store, Post{Header: `Quoting "things"`, Content: "Some **Markdown**\n\n---\n\nwith a rule", Slug: "quoting", Author: "Tester", Published: true,
+		PublishedAt: time.Date(2018, 2, 3, 4, 5, 6, 0, time.UTC), Tags: []Tag{{Name: "go"}, {Name: "web"}}})
+	createPost(t, store, Post{Header: "A draft", Content: "Not yet", Slug: "a-draft", Author: "Tester"})
+	createPost(t, store, Post{Header: "Elsewhere", Content: "Words", Slug: "elsewhere", Author: "Tester", Published: true,
+		CanonicalURL: "https://elsewhere.example.com/post", NoIndex: true})
+}
+
+// Downloads the export in format, saving it to a file in a temp dir
+func downloadExport(t *testing.T, b *Blog, format, ext string) string {
+	t.Helper()
+	w := do(newRouter(b), adminRequest(http.MethodGet, ADMIN_EXPORT+"?format="+format, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s?format=%s responded %d, want %d", ADMIN_EXPORT, format, w.Code, http.StatusOK)
+	}
+	if got := w.Header().Get("Content-Disposition"); !strings.HasPrefix(got, `attachment; filename="blog-`) || !strings.HasSuffix(got, ext+`"`) {
+		t.Errorf("GET %s?format=%s has Content-Disposition %q, want a %s attachment", ADMIN_EXPORT, format, got, ext)
+	}
+	file := filepath.Join(t.TempDir(), "export"+ext)
+	if err := os.WriteFile(file, w.Body.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestExportRoundTrips(t *testing.T) {
+	for _, format := range []struct{ name, ext string }{{EXPORT_JSON, IMPORT_JSON_EXT}, {EXPORT_MARKDOWN, IMPORT_ZIP_EXT}} {
+		t.Run(format.name, func(t *testing.T) {
+			from := newTestBlog(t, testConfig(t))
+			createExportPosts(t, from.store)
+			file := downloadExport(t, from, format.name, format.ext)
+
+			to := NewSQLitePostStore(openTestSQLite(t, testConfig(t).DatabaseURL), DEFAULT_POSTS_PER_PAGE)
+			var out strings.Builder
+			result, err := importPosts(context.Background(), to, file, &out)
+			if err != nil || result != (ImportResult{Imported: 3}) {
+				t.Fatalf("importing the export = %+v, %v, want all 3 posts imported\n%s", result, err, out.String())
+			}
+
+			for _, slug := range []string{"quoting", "a-draft", "elsewhere"} {
+				want, got := getPost(t, from.store, slug), getPost(t, to, slug)
+				if got.Header != want.Header || got.Content != want.Content || got.Author != want.Author || got.Published != want.Published ||
+					!got.PublishedAt.Equal(want.PublishedAt) || got.TagList() != want.TagList() || got.CanonicalURL != want.CanonicalURL || got.NoIndex != want.NoIndex {
+					t.Errorf("%s came back from the export as\n%+v\nwant\n%+v", slug, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestExport(t *testing.T) {
+	b := newTestBlog(t, testConfig(t))
+	router := newRouter(b)
+
+	w := do(router, adminRequest(http.MethodGet, ADMIN_EXPORT, nil))
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Errorf("GET %s with no posts responded %d %q, want an empty JSON array", ADMIN_EXPORT, w.Code, w.Body.String())
+	}
+
+	createExportPosts(t, b.store)
+	var posts []Post
+	decodeJSON(t, do(router, adminRequest(http.MethodGet, ADMIN_EXPORT, nil)), &posts)
+	if got := slugs(posts); len(got) != 3 || !hasSlug(got, "a-draft") {
+		t.Errorf("GET %s exported %v, want every post, drafts too", ADMIN_EXPORT, got)
+	}
+	for _, p := range posts {
+		if p.CreatedAt.IsZero() || p.UpdatedAt.IsZero() {
+			t.Errorf("GET %s exported %s without its timestamps", ADMIN_EXPORT, p.Slug)
+		}
+	}
+
+	if w := do(router, adminRequest(http.MethodGet, ADMIN_EXPORT+"?format=xml", nil)); w.Code != http.StatusBadRequest {
+		t.Errorf("GET %s?format=xml responded %d, want %d", ADMIN_EXPORT, w.Code, http.StatusBadRequest)
+	}
+	if w := do(router, httptest.NewRequest(http.MethodGet, ADMIN_EXPORT, nil)); w.Code != http.StatusUnauthorized {
+		t.Errorf("GET %s without logging in responded %d, want %d", ADMIN_EXPORT, w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestMarkdownPostFrontMatter(t *testing.T) {
+	p := Post{Header: "Line one\nline two", Slug: "multi", Content: "Body", Published: true}
+	got, err := parseMarkdownPost("ignored", markdownPost(p))
+	if err != nil {
+		t.Fatalf("parseMarkdownPost(markdownPost) = %v", err)
+	}
+	if got.Header != "Line one line two" || got.Slug != "multi" {
+		t.Errorf("a header with a newline came back as %q slugged %q, want it kept on one line", got.Header, got.Slug)
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"time"
+)
+
+const (
+	FEED_SIZE           = 20  // How many of the most recent posts each feed carries
+	FEED_EXCERPT_LENGTH = 200 // Characters of content shown as each item's description
+
+	JSON_FEED_VERSION = "https://jsonfeed.org/version/1.1"
+)
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	GUID        string `xml:"guid"`
+	PubDate     string `xml:"pubDate"`
+}
+
+// Serves an RSS 2.0 feed of the most recently published posts
+func (b *Blog) rssHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	posts, err := b.store.Recent(ctx, FEED_SIZE)
+	if err != nil {
+		logf(ctx, "Failed to load posts for the RSS feed: %v", err)
+		http.Error(w, "Failed to load the feed.", dbErrorStatus(err))
+		return
+	}
+
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       b.config.Site.Title,
+			Link:        b.absoluteURL(r, HOME),
+			Description: b.config.Site.Tagline,
+			Items:       []rssItem{},
+		},
+	}
+	for _, p := range posts {
+		link := b.absoluteURL(r, POST+p.Slug)
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       p.Header,
+			Link:        link,
+			Description: p.Excerpt(FEED_EXCERPT_LENGTH),
+			GUID:        link,
+			PubDate:     p.PublishedAt.UTC().Format(time.RFC1123Z),
+		})
+	}
+
+	writeXML(w, r, "application/rss+xml", feed)
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID        string   `xml:"id"`
+	Title     string   `xml:"title"`
+	Updated   string   `xml:"updated"`
+	Published string   `xml:"published"`
+	Link      atomLink `xml:"link"`
+	Summary   string   `xml:"summary"`
+}
+
+// Serves an Atom 1.0 feed of the most recently published posts
+func (b *Blog) atomHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	posts, err := b.store.Recent(ctx, FEED_SIZE)
+	if err != nil {
+		logf(ctx, "Failed to load posts for the Atom feed: %v", err)
+		http.Error(w, "Failed to load the feed.", dbErrorStatus(err))
+		return
+	}
+
+	feed := atomFeed{
+		ID:    b.absoluteURL(r, HOME),
+		Title: b.config.Site.Title,
+		Links: []atomLink{
+			{Href: b.absoluteURL(r, ATOM), Rel: "self"},
+			{Href: b.absoluteURL(r, HOME)},
+		},
+		Author:  atomAuthor{Name: b.config.Site.Title},
+		Entries: []atomEntry{},
+	}
+
+	// The feed was last updated whenever its newest change was, or the epoch if there's nothing in it
+	var updated time.Time
+	for _, p := range posts {
+		link := b.absoluteURL(r, POST+p.Slug)
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:        link,
+			Title:     p.Header,
+			Updated:   p.UpdatedAt.UTC().Format(time.RFC3339),
+			Published: p.PublishedAt.UTC().Format(time.RFC3339),
+			Link:      atomLink{Href: link},
+			Summary:   p.Excerpt(FEED_EXCERPT_LENGTH),
+		})
+		if p.UpdatedAt.After(updated) {
+			updated = p.UpdatedAt
+		}
+	}
+	if updated.IsZero() {
+		updated = time.Unix(0, 0)
+	}
+	feed.Updated = updated.UTC().Format(time.RFC3339)
+
+	writeXML(w, r, "application/atom+xml", feed)
+}
+
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Description string         `json:"description,omitempty"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url"`
+	Title         string           `json:"title"`
+	ContentHTML   string           `json:"content_html"`
+	Summary       string           `json:"summary"`
+	DatePublished string           `json:"date_published"`
+	DateModified  string           `json:"date_modified"`
+	Authors       []jsonFeedAuthor `json:"authors,omitempty"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+// Serves a JSON Feed 1.1 of the most recently published posts, with their whole content unlike the other feeds
+func (b *Blog) jsonFeedHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	posts, err := b.store.Recent(ctx, FEED_SIZE)
+	if err != nil {
+		logf(ctx, "Failed to load posts for the JSON feed: %v", err)
+		http.Error(w, "Failed to load the feed.", dbErrorStatus(err))
+		return
+	}
+	b.prepare(posts)
+
+	feed := jsonFeed{
+		Version:     JSON_FEED_VERSION,
+		Title:       b.config.Site.Title,
+		HomePageURL: b.absoluteURL(r, HOME),
+		FeedURL:     b.absoluteURL(r, JSON_FEED),
+		Description: b.config.Site.Tagline,
+		Items:       []jsonFeedItem{},
+	}
+	for _, p := range posts {
+		link := b.absoluteURL(r, POST+p.Slug)
+		item := jsonFeedItem{
+			ID:            link,
+			URL:           link,
+			Title:         p.Header,
+			ContentHTML:   string(p.Body),
+			Summary:       p.Excerpt(FEED_EXCERPT_LENGTH),
+			DatePublished: p.PublishedAt.UTC().Format(time.RFC3339),
+			DateModified:  p.UpdatedAt.UTC().Format(time.RFC3339),
+		}
+		if p.Author != "" {
+			item.Authors = []jsonFeedAuthor{{Name: p.Author}}
+		}
+		feed.Items = append(feed.Items, item)
+	}
+
+	w.Header().Set("Content-Type", "application/feed+json")
+	if err := json.NewEncoder(w).Encode(feed); err != nil {
+		logf(ctx, "Failed to encode the JSON feed: %v", err)
+	}
+}
+
+// The absolute link to path on the blog, e.g. /post/hello-world. It's on BASE_URL if that's set, or else whatever host
+// r was made to. r can be nil where there's no request, like when a scheduled post goes live, in which case path is
+// returned as it is without BASE_URL
+func (b *Blog) absoluteURL(r *http.Request, path string) string {
+	if b.config.BaseURL != "" {
+		return b.config.BaseURL + path
+	}
+	if r == nil {
+		return path
+	}
+	return requestBaseURL(r) + path
+}
+
+// Works out the scheme and host the request was made to. Behind trusted proxies the scheme is the one the client used,
+// as the proxy will usually have reached the blog over plain http
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if proto, ok := r.Context().Value(forwardedProtoKey{}).(string); ok {
+		scheme = proto
+	}
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+func writeXML(w http.ResponseWriter, r *http.Request, contentType string, v interface{}) {
+	w.Header().Set("Content-Type", contentType+"; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		logf(r.Context(), "Failed to encode XML response: %v", err)
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A Blog with two live posts and a draft, for the feeds to list
+func newFeedBlog(t *testing.T) *Blog {
+	t.Helper()
+	b := newTestBlog(t, testConfig(t))
+	createLivePost(t, b.store, "older")
+	createLivePost(t, b.store, "newer")
+	createPost(t, b.store, Post{Header: "Draft", Content: "Not yet", Slug: "draft", Author: "Tester"})
+	return b
+}
+
+// GETs path, checking it's served as contentType, and decodes its XML into v
+func getXML(t *testing.T, b *Blog, path, contentType string, v interface{}) {
+	t.Helper()
+	w := do(newRouter(b), httptest.NewRequest(http.MethodGet, path, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s responded %d, want %d", path, w.Code, http.StatusOK)
+	}
+	if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, contentType) {
+		t.Errorf("GET %s has Content-Type %q, want %s", path, got, contentType)
+	}
+	if err := xml.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("GET %s isn't XML: %v", path, err)
+	}
+}
+
+func TestRSSFeed(t *testing.T) {
+	var feed rssFeed
+	getXML(t, newFeedBlog(t), RSS, "application/rss+xml", &feed)
+
+	if feed.Version != "2.0" {
+		t.Errorf("version = %q, want 2.0", feed.Version)
+	}
+	if len(feed.Channel.Items) != 2 {
+		t.Fatalf("the feed has %d items, want the 2 live posts", len(feed.Channel.Items))
+	}
+	item := feed.Channel.Items[0]
+	if item.Title != "Post newer" || item.Link != "http://example.com/post/newer" || item.GUID != item.Link {
+		t.Errorf("the first item is %+v, want the newest post linked absolutely", item)
+	}
+	if item.Description != "All about newer" || item.PubDate == "" {
+		t.Errorf("the first item is %+v, want its excerpt and publish date", item)
+	}
+}
+
+func TestAtomFeed(t *testing.T) {
+	var feed atomFeed
+	getXML(t, newFeedBlog(t), ATOM, "application/atom+xml", &feed)
+
+	if len(feed.Entries) != 2 {
+		t.Fatalf("the feed has %d entries, want the 2 live posts", len(feed.Entries))
+	}
+	entry := feed.Entries[0]
+	if entry.Title != "Post newer" || entry.ID != "http://example.com/post/newer" || entry.Link.Href != entry.ID {
+		t.Errorf("the first entry is %+v, want the newest post linked absolutely", entry)
+	}
+	if feed.Updated != entry.Updated {
+		t.Errorf("the feed was updated %s, want when its newest entry was, %s", feed.Updated, entry.Updated)
+	}
+	if feed.Links[0].Rel != "self" || feed.Links[0].Href != "http://example.com"+ATOM {
+		t.Errorf("the feed's first link is %+v, want it to link to itself", feed.Links[0])
+	}
+}
+
+func TestEmptyAtomFeed(t *testing.T) {
+	var feed atomFeed
+	getXML(t, newTestBlog(t, testConfig(t)), ATOM, "application/atom+xml", &feed)
+	if len(feed.Entries) != 0 || feed.Updated != "1970-01-01T00:00:00Z" {
+		t.Errorf("the empty feed has %d entries and was updated %s, want none and the epoch", len(feed.Entries), feed.Updated)
+	}
+}
+
+// GETs the JSON feed, checking it's served as JSON Feed, and decodes it as the loosely typed JSON a reader would see
+func getJSONFeed(t *testing.T, b *Blog) map[string]interface{} {
+	t.Helper()
+	w := do(newRouter(b), httptest.NewRequest(http.MethodGet, JSON_FEED, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s responded %d, want %d", JSON_FEED, w.Code, http.StatusOK)
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/feed+json" {
+		t.Errorf("GET %s has Content-Type %q, want application/feed+json", JSON_FEED, got)
+	}
+	var feed map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("GET %s isn't JSON: %v", JSON_FEED, err)
+	}
+	return feed
+}
+
+func TestJSONFeed(t *testing.T) {
+	b := newFeedBlog(t)
+	createPost(t, b.store, Post{Header: "Markdown", Content: "Some **bold** words", Slug: "markdown", Author: "Tester", Published: true})
+	feed := getJSONFeed(t, b)
+
+	for key, want := range map[string]string{
+		"version":       "https://jsonfeed.org/version/1.1",
+		"title":         b.config.Site.Title,
+		"home_page_url": "http://example.com" + HOME,
+		"feed_url":      "http://example.com" + JSON_FEED,
+	} {
+		if got, _ := feed[key].(string); got != want {
+			t.Errorf("the feed's %s is %q, want %q", key, got, want)
+		}
+	}
+
+	items, _ := feed["items"].([]interface{})
+	if len(items) != 3 {
+		t.Fatalf("the feed has %d items, want the 3 live posts", len(items))
+	}
+	item, _ := items[0].(map[string]interface{})
+	for _, key := range []string{"id", "url", "title", "content_html", "date_published"} {
+		if got, _ := item[key].(string); got == "" {
+			t.Errorf("the first item has no %s: %v", key, item)
+		}
+	}
+	if item["id"] != "http://example.com/post/markdown" || item["url"] != item["id"] || item["title"] != "Markdown" {
+		t.Errorf("the first item is %v, want the newest post linked absolutely", item)
+	}
+	if html, _ := item["content_html"].(string); !strings.Contains(html, "<strong>bold</strong>") {
+		t.Errorf("the first item's content_html is %q, want the post rendered", html)
+	}
+	if published, _ := item["date_published"].(string); published != "" {
+		if _, err := time.Parse(time.RFC3339, published); err != nil {
+			t.Errorf("the first item's date_published %q isn't RFC 3339: %v", published, err)
+		}
+	}
+	for _, i := range items {
+		if i.(map[string]interface{})["url"] == "http://example.com/post/draft" {
+			t.Error("the feed lists a draft")
+		}
+	}
+}
+
+func TestEmptyJSONFeed(t *testing.T) {
+	feed := getJSONFeed(t, newTestBlog(t, testConfig(t)))
+	if items, ok := feed["items"].([]interface{}); !ok || len(items) != 0 {
+		t.Errorf("the empty feed's items are %v, want an empty list", feed["items"])
+	}
+}
+
+func TestAbsoluteURL(t *testing.T) {
+	tests := []struct {
+		name           string
+		baseURL        string
+		trustedProxies int
+		req            func() *http.Request // nil for building a url outside a request
+		want           string
+	}{
+		{
+			name:    "configured",
+			baseURL: "https://blog.example.com",
+			req:     func() *http.Request { return httptest.NewRequest(http.MethodGet, "http://internal:8080/", nil) },
+			want:    "https://blog.example.com/post/hello",
+		},
+		{
+			name:    "configured outside a request",
+			baseURL: "https://blog.example.com",
+			want:    "https://blog.example.com/post/hello",
+		},
+		{
+			name: "from the request",
+			req:  func() *http.Request { return httptest.NewRequest(http.MethodGet, "http://blog.example.com:8080/", nil) },
+			want: "http://blog.example.com:8080/post/hello",
+		},
+		{
+			name: "from a request over TLS",
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "https://blog.example.com/", nil)
+			},
+			want: "https://blog.example.com/post/hello",
+		},
+		{
+			name:           "behind a trusted proxy",
+			trustedProxies: 1,
+			req: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "http://blog.example.com/", nil)
+				req.Header.Set(FORWARDED_PROTO_HEADER, "https")
+				return req
+			},
+			want: "https://blog.example.com/post/hello",
+		},
+		{
+			name: "X-Forwarded-Proto without a trusted proxy",
+			req: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "http://blog.example.com/", nil)
+				req.Header.Set(FORWARDED_PROTO_HEADER, "https")
+				return req
+			},
+			want: "http://blog.example.com/post/hello",
+		},
+		{
+			name:           "a made up X-Forwarded-Proto",
+			trustedProxies: 1,
+			req: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "http://blog.example.com/", nil)
+				req.Header.Set(FORWARDED_PROTO_HEADER, "javascript")
+				return req
+			},
+			want: "http://blog.example.com/post/hello",
+		},
+		{
+			name: "nothing to go on",
+			want: "/post/hello",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig(t)
+			config.BaseURL = tt.baseURL
+			b := newFakeBlog(t, config)
+			if tt.req == nil {
+				if got := b.absoluteURL(nil, POST+"hello"); got != tt.want {
+					t.Errorf("absoluteURL = %q, want %q", got, tt.want)
+				}
+				return
+			}
+			var got string
+			h := clientIPMiddleware(tt.trustedProxies, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = b.absoluteURL(r, POST+"hello")
+			}))
+			do(h, tt.req())
+			if got != tt.want {
+				t.Errorf("absoluteURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Every absolute link is built from BASE_URL, whatever host the request was made to
+func TestPagesUseTheBaseURL(t *testing.T) {
+	config := testConfig(t)
+	config.BaseURL = "https://blog.example.com"
+	b := newTestBlog(t, config)
+	createLivePost(t, b.store, "hello")
+	router := newRouter(b)
+
+	for _, path := range []string{RSS, ATOM, JSON_FEED, SITEMAP, POST + "hello"} {
+		body := do(router, httptest.NewRequest(http.MethodGet, "http://internal:8080"+path, nil)).Body.String()
+		if !strings.Contains(body, "https://blog.example.com/post/hello") {
+			t.Errorf("GET %s doesn't link to the post at BASE_URL:\n%s", path, body)
+		}
+		if strings.Contains(body, "internal:8080") {
+			t.Errorf("GET %s links to the host it was requested from rather than BASE_URL", path)
+		}
+	}
+}
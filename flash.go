@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+)
+
+const (
+	FLASH_COOKIE  = "flash"
+	FLASH_MAX_AGE = 60 // Seconds a flash survives if the page it's for never loads
+)
+
+// Leaves a one-off message for the next page this browser loads, e.g. to confirm a save after redirecting away from the form
+func setFlash(w http.ResponseWriter, message string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     FLASH_COOKIE,
+		Value:    url.QueryEscape(message), // Cookie values can't hold spaces or commas
+		Path:     "/",
+		MaxAge:   FLASH_MAX_AGE,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// Reads the flash left for this browser and clears it so it's only shown once, empty if there isn't one
+func takeFlash(w http.ResponseWriter, r *http.Request) string {
+	cookie, err := r.Cookie(FLASH_COOKIE)
+	if err != nil || cookie.Value == "" {
+		return ""
+	}
+	http.SetCookie(w, &http.Cookie{Name: FLASH_COOKIE, Path: "/", MaxAge: -1})
+
+	message, err := url.QueryUnescape(cookie.Value)
+	if err != nil {
+		return ""
+	}
+	return message
+}
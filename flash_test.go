@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// The cookie called name that w set, nil if it didn't
+func responseCookie(w *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, c := range w.Result().Cookies() {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestFlashFollowsTheRedirect(t *testing.T) {
+	tests := []struct {
+		action string
+		form   url.Values
+		want   string
+	}{
+		{action: SAVE_ADD, form: url.Values{"header": {"New"}, "content": {"Words"}, "slug": {"new"}}, want: "Your post is saved as a draft"},
+		{action: SAVE_UPDATE, form: url.Values{"header": {"Edited"}, "content": {"Words"}, "slug": {"live"}}, want: "your changes are saved"},
+		{action: SAVE_DELETE, form: url.Values{"slug": {"live"}}, want: "deleted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			b := newFakeBlog(t, testConfig(t))
+			createLivePost(t, b.store, "live")
+			router := newRouter(b)
+
+			saved := do(router, formRequest(SAVE+tt.action, tt.form))
+			location := saved.Header().Get("Location")
+			flash := responseCookie(saved, FLASH_COOKIE)
+			if saved.Code != http.StatusSeeOther || location == "" || flash == nil {
+				t.Fatalf("POST %s%s responded %d to %q with flash %v, want a redirect leaving a flash", SAVE, tt.action, saved.Code, location, flash)
+			}
+
+			// The page redirected to shows it once
+			req := adminRequest(http.MethodGet, location, nil)
+			req.AddCookie(flash)
+			w := do(router, req)
+			if !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("GET %s after saving doesn't show the flash %q:\n%s", location, tt.want, w.Body.String())
+			}
+			if cleared := responseCookie(w, FLASH_COOKIE); cleared == nil || cleared.MaxAge >= 0 {
+				t.Errorf("GET %s didn't clear the flash once it was shown", location)
+			}
+		})
+	}
+}
@@ -3,7 +3,14 @@ module goblog.com/m
 go 1.16
 
 require (
+	github.com/alecthomas/chroma v0.10.0
 	github.com/jackc/pgconn v1.8.1
 	github.com/jackc/pgx/v4 v4.11.0
 	github.com/lib/pq v1.10.2 // indirect
+	github.com/microcosm-cc/bluemonday v1.0.27
+	github.com/prometheus/client_golang v1.11.1
+	github.com/yuin/goldmark v1.4.13
+	github.com/yuin/goldmark-highlighting v0.0.0-20220208100518-594be1970594
+	golang.org/x/time v0.3.0
+	modernc.org/sqlite v1.14.8
 )
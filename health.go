@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const HEALTH_TIMEOUT = 2 * time.Second // Load balancers probe often, so a slow DB should fail the check quickly
+
+// Type used to report health as JSON
+type HealthStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// The liveness check, 200 for as long as the process can answer at all. It doesn't touch the DB, so an outage there
+// gets the blog taken out of rotation by readyHandler rather than restarted
+func (b *Blog) healthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, r, http.StatusOK, HealthStatus{Status: "ok"})
+}
+
+// The readiness check, 200 only once startup's finished, the blog isn't in maintenance mode and Postgres can be reached.
+// Otherwise a 503, so load balancers only send readers to a blog that can serve them
+func (b *Blog) readyHandler(w http.ResponseWriter, r *http.Request) {
+	// Every probe should see the DB as it is right now, never a cached answer
+	w.Header().Set("Cache-Control", "no-store")
+
+	if atomic.LoadInt32(&b.ready) == 0 {
+		writeJSON(w, r, http.StatusServiceUnavailable, HealthStatus{Status: "starting"})
+		return
+	}
+	if b.config.Maintenance {
+		writeJSON(w, r, http.StatusServiceUnavailable, HealthStatus{Status: "maintenance"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), HEALTH_TIMEOUT)
+	defer cancel()
+
+	if err := b.store.Ping(ctx); err != nil {
+		logf(ctx, "Readiness check failed: %v", err)
+		writeJSON(w, r, http.StatusServiceUnavailable, HealthStatus{Status: "unavailable", Error: err.Error()})
+		return
+	}
+	writeJSON(w, r, http.StatusOK, HealthStatus{Status: "ok"})
+}
+
+// Marks the blog as ready to serve readers, once the DB is connected and migrated and everything's started
+func (b *Blog) markReady() {
+	atomic.StoreInt32(&b.ready, 1)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// A Blog that hasn't finished starting up yet, its DB connected but markReady not called
+func newStartingBlog(t *testing.T) *Blog {
+	config := testConfig(t)
+	b := NewBlog(config, NewSQLitePostStore(openTestSQLite(t, config.DatabaseURL), config.PostsPerPage))
+	t.Cleanup(b.Close)
+	return b
+}
+
+// A ready Blog in maintenance mode
+func newMaintenanceBlog(t *testing.T) *Blog {
+	config := testConfig(t)
+	config.Maintenance = true
+	return newTestBlog(t, config)
+}
+
+// A ready Blog whose DB has gone away
+func newClosedDBBlog(t *testing.T) *Blog {
+	config := testConfig(t)
+	db := openTestSQLite(t, config.DatabaseURL)
+	b := NewBlog(config, NewSQLitePostStore(db, config.PostsPerPage))
+	t.Cleanup(b.Close)
+	b.markReady()
+	db.Close()
+	return b
+}
+
+func TestHealthChecks(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		setup      func(t *testing.T) *Blog
+		wantStatus int
+		want       string
+	}{
+		{
+			name:       "live",
+			path:       HEALTH,
+			setup:      func(t *testing.T) *Blog { return newTestBlog(t, testConfig(t)) },
+			wantStatus: http.StatusOK,
+			want:       "ok",
+		},
+		{
+			name:       "live without a DB",
+			path:       HEALTH,
+			setup:      newClosedDBBlog,
+			wantStatus: http.StatusOK,
+			want:       "ok",
+		},
+		{
+			name:       "live while starting",
+			path:       HEALTH,
+			setup:      newStartingBlog,
+			wantStatus: http.StatusOK,
+			want:       "ok",
+		},
+		{
+			name:       "live in maintenance",
+			path:       HEALTH,
+			setup:      newMaintenanceBlog,
+			wantStatus: http.StatusOK,
+			want:       "ok",
+		},
+		{
+			name:       "ready",
+			path:       READY,
+			setup:      func(t *testing.T) *Blog { return newTestBlog(t, testConfig(t)) },
+			wantStatus: http.StatusOK,
+			want:       "ok",
+		},
+		{
+			name:       "still starting",
+			path:       READY,
+			setup:      newStartingBlog,
+			wantStatus: http.StatusServiceUnavailable,
+			want:       "starting",
+		},
+		{
+			name:       "maintenance",
+			path:       READY,
+			setup:      newMaintenanceBlog,
+			wantStatus: http.StatusServiceUnavailable,
+			want:       "maintenance",
+		},
+		{
+			name:       "DB unreachable",
+			path:       READY,
+			setup:      newClosedDBBlog,
+			wantStatus: http.StatusServiceUnavailable,
+			want:       "unavailable",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captureLog(t)
+			w := do(newRouter(tt.setup(t)), httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.wantStatus {
+				t.Errorf("GET %s responded %d, want %d", tt.path, w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Cache-Control"); got != "no-store" {
+				t.Errorf("GET %s has Cache-Control %q, want no-store", tt.path, got)
+			}
+			var health HealthStatus
+			decodeJSON(t, w, &health)
+			if health.Status != tt.want {
+				t.Errorf("GET %s reported %+v, want status %q", tt.path, health, tt.want)
+			}
+			if tt.want == "unavailable" && health.Error == "" {
+				t.Errorf("GET %s didn't say what's wrong with the DB", tt.path)
+			}
+		})
+	}
+}
+
+// Load balancers should start sending readers over as soon as startup's done, without the process restarting
+func TestReadyOnceStarted(t *testing.T) {
+	b := newStartingBlog(t)
+	router := newRouter(b)
+	if w := do(router, httptest.NewRequest(http.MethodGet, READY, nil)); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("GET %s before markReady responded %d, want %d", READY, w.Code, http.StatusServiceUnavailable)
+	}
+	b.markReady()
+	if w := do(router, httptest.NewRequest(http.MethodGet, READY, nil)); w.Code != http.StatusOK {
+		t.Errorf("GET %s after markReady responded %d, want %d", READY, w.Code, http.StatusOK)
+	}
+}
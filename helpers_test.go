@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v4/pgxpool" // SQL connection pool
+	"golang.org/x/time/rate"          // Token bucket rate limiter
+)
+
+const (
+	TEST_ADMIN_USER     = "admin"
+	TEST_ADMIN_PASSWORD = "secret"
+	TEST_CSRF_TOKEN     = "test-csrf-token" // Sent as both the cookie and the form field by formRequest
+)
+
+// The Config tests start from, LoadConfig's defaults with SQLite in a temp dir, an admin login and no rate limit
+func testConfig(t *testing.T) Config {
+	t.Helper()
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	config.DBDriver = DB_DRIVER_SQLITE
+	config.DatabaseURL = filepath.Join(t.TempDir(), "blog.db")
+	config.AdminUser = TEST_ADMIN_USER
+	config.AdminPassword = TEST_ADMIN_PASSWORD
+	config.RateLimit = rate.Inf
+	config.UploadDir = t.TempDir()
+	return config
+}
+
+// Opens the SQLite file at path, closing it when the test's done
+func openTestSQLite(t *testing.T, path string) *sql.DB {
+	t.Helper()
+	db, err := openSQLite(context.Background(), path)
+	if err != nil {
+		t.Fatalf("openSQLite: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// A pool on the Postgres at TEST_DATABASE_URL, skipping the test if there isn't one. Its search_path is a schema
+// of its own that's dropped when the test's done, so every test starts from an empty DB and can't see another's tables
+func openTestPostgres(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	dbURL := os.Getenv("TEST_DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("TEST_DATABASE_URL isn't set")
+	}
+	ctx := context.Background()
+
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	schema := "test_" + hex.EncodeToString(b)
+	admin, err := pgxpool.Connect(ctx, dbURL)
+	if err != nil {
+		t.Fatalf("connecting to TEST_DATABASE_URL: %v", err)
+	}
+	t.Cleanup(admin.Close)
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema+";"); err != nil {
+		t.Fatalf("creating schema %s: %v", schema, err)
+	}
+	t.Cleanup(func() { admin.Exec(ctx, "DROP SCHEMA "+schema+" CASCADE;") })
+
+	poolConfig, err := pgxpool.ParseConfig(dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	poolConfig.ConnConfig.RuntimeParams["search_path"] = schema
+	pool, err := pgxpool.ConnectConfig(ctx, poolConfig)
+	if err != nil {
+		t.Fatalf("connecting to TEST_DATABASE_URL: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+// A Blog for config backed by a fresh SQLite DB, ready to serve
+func newTestBlog(t *testing.T, config Config) *Blog {
+	t.Helper()
+	db := openTestSQLite(t, config.DatabaseURL)
+	b := NewBlog(config, NewSQLitePostStore(db, config.PostsPerPage))
+	t.Cleanup(b.Close)
+	b.markReady()
+	return b
+}
+
+// A Blog for config backed by an empty fakeStore, ready to serve. For testing handlers, what's in the DB is left to the
+// store tests
+func newFakeBlog(t *testing.T, config Config) *Blog {
+	t.Helper()
+	b := NewBlog(config, newFakeStore(config.PostsPerPage))
+	t.Cleanup(b.Close)
+	b.markReady()
+	return b
+}
+
+// Serves req with h, returning what it responded
+func do(h http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+// A request logged in as the admin
+func adminRequest(method, target string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+	req.SetBasicAuth(TEST_ADMIN_USER, TEST_ADMIN_PASSWORD)
+	return req
+}
+
+// form POSTed to target the way the post forms submit it, logged in as the admin and with a valid CSRF token
+func formRequest(target string, form url.Values) *http.Request {
+	form.Set(CSRF_FIELD, TEST_CSRF_TOKEN)
+	req := adminRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: CSRF_COOKIE, Value: TEST_CSRF_TOKEN})
+	return req
+}
+
+// Saves post straight to store
+func createPost(t *testing.T, store PostStore, post Post) {
+	t.Helper()
+	if err := store.Create(context.Background(), post); err != nil {
+		t.Fatalf("Create(%q): %v", post.Slug, err)
+	}
+}
+
+// Saves a published post with the given slug, headed and filled with something made up from it
+func createLivePost(t *testing.T, store PostStore, slug string) {
+	t.Helper()
+	createPost(t, store, Post{Header: "Post " + slug, Content: "All about " + slug, Slug: slug, Author: "Tester", Published: true})
+}
+
+// Everything logged since captureLog, safe to read while background goroutines are still logging
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (l *logBuffer) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf.Write(p)
+}
+
+func (l *logBuffer) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf.String()
+}
+
+// Sends the standard logger's output to a buffer until the test's done
+func captureLog(t *testing.T) *logBuffer {
+	logs := &logBuffer{}
+	log.SetOutput(logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return logs
+}
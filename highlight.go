@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/chroma/formatters/html" // Syntax highlighter's HTML output
+	"github.com/alecthomas/chroma/styles"          // Syntax highlighter's colour themes
+)
+
+const (
+	DEFAULT_HIGHLIGHT_STYLE = "github"               // The chroma theme code blocks are coloured with, override with HIGHLIGHT_STYLE
+	NO_HIGHLIGHT_STYLE      = "none"                 // Set HIGHLIGHT_STYLE to this to leave code blocks uncoloured
+	HIGHLIGHT_CACHE_CONTROL = "public, max-age=3600" // Only changes when HIGHLIGHT_STYLE does, so it's kept short enough a restart shows through
+)
+
+var (
+	// Code is highlighted with class names rather than inline styles, so the sanitizer can keep it without letting style attributes through,
+	// and so the theme is just the stylesheet served at HIGHLIGHT_CSS
+	highlightFormatOptions = []html.Option{html.WithClasses(true)}
+	// The class names chroma gives its spans, e.g. "chroma", "kd", "s1"
+	highlightClasses = regexp.MustCompile(`^[a-z0-9]+( [a-z0-9]+)*$`)
+)
+
+// The stylesheet for the chroma theme called name, or nothing for NO_HIGHLIGHT_STYLE
+func highlightCSS(name string) (string, error) {
+	if name == NO_HIGHLIGHT_STYLE {
+		return "", nil
+	}
+	style, ok := styles.Registry[strings.ToLower(name)]
+	if !ok {
+		names := styles.Names()
+		sort.Strings(names)
+		return "", fmt.Errorf("HIGHLIGHT_STYLE must be %s or one of %s, got %q", NO_HIGHLIGHT_STYLE, strings.Join(names, ", "), name)
+	}
+	var buf bytes.Buffer
+	if err := html.New(highlightFormatOptions...).WriteCSS(&buf, style); err != nil {
+		return "", fmt.Errorf("writing the %s highlight stylesheet: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// Serves the stylesheet for the HIGHLIGHT_STYLE theme, which was worked out at startup
+func (b *Blog) highlightCSSHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/css; charset=utf-8")
+	w.Header().Set("Cache-Control", HIGHLIGHT_CACHE_CONTROL)
+	w.Write([]byte(b.config.HighlightCSS))
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHighlightedCodeBlocks(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		want, skip []string
+	}{
+		{
+			name:    "go",
+			content: "```go\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n```",
+			want:    []string{`<pre class="chroma">`, `<span class="kd">func</span>`, `<span class="s">&#34;hi&#34;</span>`},
+			skip:    []string{"style="},
+		},
+		{
+			name:    "unknown language",
+			content: "```not-a-language\n<b>left alone</b>\n```",
+			want:    []string{"&lt;b&gt;left alone&lt;/b&gt;"},
+			skip:    []string{"<b>"},
+		},
+		{
+			name:    "no language",
+			content: "```\nplain text\n```",
+			want:    []string{"plain text"},
+		},
+		{
+			name:    "classes written by hand",
+			content: `<span class="kd" style="color: red">kept</span> <span class="x&quot;onclick">dropped</span>`,
+			want:    []string{`<span class="kd">kept</span>`},
+			skip:    []string{"style=", "onclick"},
+		},
+	}
+	for _, tt := range tests {
+		for _, policyName := range []string{"strict", "basic"} {
+			t.Run(tt.name+"/"+policyName, func(t *testing.T) {
+				policy, err := namedHTMLPolicy(policyName)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got := string(RenderMarkdown(tt.content, policy))
+				for _, want := range tt.want {
+					if !strings.Contains(got, want) {
+						t.Errorf("RenderMarkdown(%q) = %q, want it to contain %q", tt.content, got, want)
+					}
+				}
+				for _, skip := range tt.skip {
+					if strings.Contains(got, skip) {
+						t.Errorf("RenderMarkdown(%q) = %q, want no %q", tt.content, got, skip)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestHighlightCSS(t *testing.T) {
+	css, err := highlightCSS(DEFAULT_HIGHLIGHT_STYLE)
+	if err != nil || !strings.Contains(css, ".chroma") {
+		t.Errorf("highlightCSS(%q) = %q, %v, want the theme's stylesheet", DEFAULT_HIGHLIGHT_STYLE, css, err)
+	}
+	if css, err := highlightCSS("Monokai"); err != nil || css == "" {
+		t.Errorf("highlightCSS(Monokai) = %q, %v, want theme names to ignore case", css, err)
+	}
+	if css, err := highlightCSS(NO_HIGHLIGHT_STYLE); err != nil || css != "" {
+		t.Errorf("highlightCSS(%q) = %q, %v, want no stylesheet", NO_HIGHLIGHT_STYLE, css, err)
+	}
+	if _, err := highlightCSS("no-such-theme"); err == nil || !strings.Contains(err.Error(), "HIGHLIGHT_STYLE") {
+		t.Errorf("highlightCSS(no-such-theme) = %v, want an error about HIGHLIGHT_STYLE", err)
+	}
+
+	config := testConfig(t)
+	config.HighlightCSS = css
+	w := do(newRouter(newFakeBlog(t, config)), httptest.NewRequest(http.MethodGet, HIGHLIGHT_CSS, nil))
+	if w.Code != http.StatusOK || w.Body.String() != css {
+		t.Errorf("GET %s responded %d, want %d with the stylesheet", HIGHLIGHT_CSS, w.Code, http.StatusOK)
+	}
+	if got := w.Header().Get("Content-Type"); got != "text/css; charset=utf-8" {
+		t.Errorf("GET %s Content-Type = %q, want text/css", HIGHLIGHT_CSS, got)
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	IMPORT_MARKDOWN_EXT = ".md"   // A single post, with its header, slug etc. in front matter
+	IMPORT_JSON_EXT     = ".json" // An array of posts, in the shape the API returns them
+	IMPORT_ZIP_EXT      = ".zip"  // Holding files of either kind
+	FRONT_MATTER_FENCE  = "---"   // The lines above and below a Markdown file's front matter
+)
+
+// What an import did, every post it read is counted in exactly one of these
+type ImportResult struct {
+	Imported  int
+	Conflicts int // Skipped as a post with the slug already exists, so an import can safely be run again
+	Failed    int // Couldn't be read or weren't valid posts
+}
+
+// Creates the posts in source, either a directory of Markdown, JSON and zip files or a single one of them, writing a line
+// to out for each post saying what became of it. Only fails outright if source can't be read at all
+func importPosts(ctx context.Context, store PostStore, source string, out io.Writer) (ImportResult, error) {
+	var result ImportResult
+	files, err := importFiles(source)
+	if err != nil {
+		return result, err
+	}
+
+	for _, file := range files {
+		posts, err := readImportFile(file)
+		if err != nil {
+			fmt.Fprintf(out, "Failed %s: %v\n", file, err)
+			result.Failed++
+			continue
+		}
+		for _, post := range posts {
+			slug, err := importPost(ctx, store, post)
+			switch {
+			case errors.Is(err, errSlugTaken):
+				fmt.Fprintf(out, "Skipped %s from %s, a post with that slug already exists\n", slug, file)
+				result.Conflicts++
+			case err != nil:
+				fmt.Fprintf(out, "Failed %q from %s: %v\n", post.Header, file, err)
+				result.Failed++
+			default:
+				fmt.Fprintf(out, "Imported %s from %s\n", slug, file)
+				result.Imported++
+			}
+		}
+	}
+	fmt.Fprintf(out, "%d imported, %d already existed, %d failed\n", result.Imported, result.Conflicts, result.Failed)
+	return result, nil
+}
+
+// The files to import from source, sorted by name so posts are created in a predictable order
+func importFiles(source string) ([]string, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{source}, nil
+	}
+
+	entries, err := os.ReadDir(source)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if !entry.IsDir() && (ext == IMPORT_MARKDOWN_EXT || ext == IMPORT_JSON_EXT || ext == IMPORT_ZIP_EXT) {
+			files = append(files, filepath.Join(source, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func readImportFile(file string) ([]Post, error) {
+	if strings.ToLower(filepath.Ext(file)) == IMPORT_ZIP_EXT {
+		return readImportZip(file)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return parseImportFile(filepath.Base(file), data)
+}
+
+// Reads every Markdown and JSON file in a zip, like the one the Markdown export downloads
+func readImportZip(file string) ([]Post, error) {
+	archive, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	var posts []Post
+	for _, entry := range archive.File {
+		ext := strings.ToLower(path.Ext(entry.Name))
+		if entry.FileInfo().IsDir() || (ext != IMPORT_MARKDOWN_EXT && ext != IMPORT_JSON_EXT) {
+			continue
+		}
+		content, err := entry.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(content)
+		content.Close()
+		if err != nil {
+			return nil, err
+		}
+		entryPosts, err := parseImportFile(path.Base(entry.Name), data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name, err)
+		}
+		posts = append(posts, entryPosts...)
+	}
+	return posts, nil
+}
+
+// Parses the posts in data, read from the file called name
+func parseImportFile(name string, data []byte) ([]Post, error) {
+	ext := filepath.Ext(name)
+	switch strings.ToLower(ext) {
+	case IMPORT_JSON_EXT:
+		var posts []Post
+		if err := json.Unmarshal(data, &posts); err != nil {
+			return nil, fmt.Errorf("should be a JSON array of posts: %w", err)
+		}
+		return posts, nil
+	case IMPORT_MARKDOWN_EXT:
+		post, err := parseMarkdownPost(strings.TrimSuffix(name, ext), string(data))
+		if err != nil {
+			return nil, err
+		}
+		return []Post{post}, nil
+	}
+	return nil, fmt.Errorf("only %s, %s and %s files can be imported", IMPORT_MARKDOWN_EXT, IMPORT_JSON_EXT, IMPORT_ZIP_EXT)
+}
+
+// Parses a Markdown post with its details in front matter, e.g.
+//
+//	---
+//	header: Hello world
+//	slug: hello-world
+//	date: 2021-03-14
+//	tags: go, web
+//	---
+//	The post's content...
+//
+// title works in place of header, as other blogs tend to call it that. The slug defaults to name, and the post
+// is published unless it has published: false or draft: true
+func parseMarkdownPost(name, data string) (Post, error) {
+	post := Post{Slug: name, Published: true}
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+
+	lines := strings.Split(data, "\n")
+	if strings.TrimSpace(lines[0]) != FRONT_MATTER_FENCE {
+		return post, fmt.Errorf("should start with front matter between %s lines, giving at least the header", FRONT_MATTER_FENCE)
+	}
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == FRONT_MATTER_FENCE {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return post, fmt.Errorf("the front matter is never closed with a %s line", FRONT_MATTER_FENCE)
+	}
+
+	for i, line := range lines[1:end] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		colon := strings.Index(line, ":")
+		if colon < 0 {
+			return post, fmt.Errorf("line %d of the front matter should look like key: value, got %q", i+2, line)
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:colon]))
+		value := unquote(strings.TrimSpace(line[colon+1:]))
+		if err := setFrontMatter(&post, key, value); err != nil {
+			return post, fmt.Errorf("line %d of the front matter: %w", i+2, err)
+		}
+	}
+	post.Content = strings.TrimSpace(strings.Join(lines[end+1:], "\n"))
+	return post, nil
+}
+
+func setFrontMatter(post *Post, key, value string) error {
+	var err error
+	switch key {
+	case "header", "title":
+		post.Header = value
+	case "slug":
+		post.Slug = value
+	case "author":
+		post.Author = value
+	case "tags":
+		post.Tags = parseTags(strings.Trim(value, "[]"))
+	case "date", "published_at":
+		post.PublishedAt, err = parseImportDate(value)
+	case "created_at":
+		// Kept with the post for reference, the store always dates it from when it's imported
+		post.CreatedAt, err = time.Parse(time.RFC3339, value)
+	case "updated_at":
+		post.UpdatedAt, err = time.Parse(time.RFC3339, value)
+	case "canonical_url":
+		post.CanonicalURL = value
+	case "no_index":
+		post.NoIndex, err = strconv.ParseBool(value)
+	case "published":
+		post.Published, err = strconv.ParseBool(value)
+	case "draft":
+		var draft bool
+		draft, err = strconv.ParseBool(value)
+		post.Published = !draft
+	default:
+		return fmt.Errorf("unknown key %q", key)
+	}
+	return err
+}
+
+// Strips a single pair of quotes from around value, so quotes that are part of it are kept
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// Takes the dates the post forms do, as well as full timestamps like other blogs write
+func parseImportDate(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	return parsePublishedAt(raw, time.UTC)
+}
+
+// Validates post the way the API does and creates it, returning the slug it was saved under
+func importPost(ctx context.Context, store PostStore, post Post) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+
+	if post.Header == "" || post.Content == "" {
+		return "", errors.New("a post needs a header and content")
+	}
+	if post.Slug == "" {
+		post.Slug = generateSlug(post.Header, func(candidate string) bool {
+			return store.SlugExists(ctx, candidate)
+		})
+	}
+	slug, err := normalizeSlug(post.Slug)
+	if err != nil {
+		return "", fmt.Errorf("the slug isn't valid, %w", err)
+	}
+	post.Slug = slug
+	post.Author = strings.TrimSpace(post.Author)
+	post.Tags = normalizeTags(post.Tags)
+	if err := validatePost(post); err != nil {
+		return slug, fmt.Errorf("the post is too long, %w", err)
+	}
+	if post.CanonicalURL, err = parseCanonicalURL(post.CanonicalURL); err != nil {
+		return slug, err
+	}
+	return slug, store.Create(ctx, post)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Writes files, keyed by name, into a new temp dir
+func writeFixtures(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestImportPosts(t *testing.T) {
+	store := NewSQLitePostStore(openTestSQLite(t, testConfig(t).DatabaseURL), DEFAULT_POSTS_PER_PAGE)
+	dir := writeFixtures(t, map[string]string{
+		"hello-world.md": "---\ntitle: \"Hello, world\"\nauthor: Tester\ndate: 2019-06-01\ntags: [go, web]\n---\nThe **first** post.\n",
+		"draft.md":       "---\nheader: Not finished\ndraft: true\n---\nStill writing this one\n",
+		"posts.json":     `[{"header": "From JSON", "content": "Words", "slug": "From-JSON", "published": true}, {"header": "Clash", "content": "Words", "slug": "hello-world"}]`,
+		"broken.md":      "No front matter here\n",
+		"notes.txt":      "Not a post, so left alone\n",
+	})
+	var out strings.Builder
+
+	result, err := importPosts(context.Background(), store, dir, &out)
+	if err != nil {
+		t.Fatalf("importPosts: %v", err)
+	}
+	if want := (ImportResult{Imported: 3, Conflicts: 1, Failed: 1}); result != want {
+		t.Errorf("importPosts = %+v, want %+v\n%s", result, want, out.String())
+	}
+	for _, want := range []string{"Imported hello-world from", "Skipped hello-world from", "Failed " + filepath.Join(dir, "broken.md"), "3 imported, 1 already existed, 1 failed"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("importPosts' report is missing %q:\n%s", want, out.String())
+		}
+	}
+
+	hello := getPost(t, store, "hello-world")
+	if hello.Header != "Hello, world" || hello.Author != "Tester" || hello.Content != "The **first** post." || !hello.Live() {
+		t.Errorf("hello-world.md was imported as %+v", hello)
+	}
+	if want := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC); !hello.PublishedAt.Equal(want) {
+		t.Errorf("hello-world.md was dated %v, want %v", hello.PublishedAt, want)
+	}
+	if got := hello.TagList(); got != "go, web" {
+		t.Errorf("hello-world.md was tagged %q, want go, web", got)
+	}
+	if draft := getPost(t, store, "draft"); draft.Header != "Not finished" || draft.Published {
+		t.Errorf("draft.md was imported as %+v, want an unpublished post slugged from its file name", draft)
+	}
+	if p := getPost(t, store, "from-json"); !p.Live() {
+		t.Errorf("posts.json's post was imported as %+v, want it live", p)
+	}
+
+	// Running it again finds everything already there
+	result, err = importPosts(context.Background(), store, dir, &out)
+	if err != nil {
+		t.Fatalf("importPosts again: %v", err)
+	}
+	if want := (ImportResult{Conflicts: 4, Failed: 1}); result != want {
+		t.Errorf("importPosts again = %+v, want %+v", result, want)
+	}
+
+	if _, err := importPosts(context.Background(), store, filepath.Join(dir, "missing"), &out); err == nil {
+		t.Errorf("importPosts from a directory that doesn't exist succeeded")
+	}
+}
+
+func TestParseMarkdownPost(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Post
+		wantErr string
+	}{
+		{name: "minimal", data: "---\nheader: Hi\n---\nBody", want: Post{Header: "Hi", Slug: "minimal", Content: "Body", Published: true}},
+		{name: "windows", data: "---\r\nheader: Hi\r\n---\r\nBody\r\n", want: Post{Header: "Hi", Slug: "windows", Content: "Body", Published: true}},
+		{name: "quotes kept inside", data: "---\nheader: '\"Quoted\" title'\n---\nBody", want: Post{Header: `"Quoted" title`, Slug: "quotes kept inside", Content: "Body", Published: true}},
+		{name: "unpublished", data: "---\nheader: Hi\npublished: false\nno_index: true\n---\nBody", want: Post{Header: "Hi", Slug: "unpublished", Content: "Body", NoIndex: true}},
+		{name: "no front matter", data: "Body", wantErr: "should start with front matter"},
+		{name: "never closed", data: "---\nheader: Hi\nBody", wantErr: "never closed"},
+		{name: "unknown key", data: "---\nlayout: post\n---\nBody", wantErr: `unknown key "layout"`},
+		{name: "not key value", data: "---\nheader\n---\nBody", wantErr: "line 2"},
+		{name: "bad date", data: "---\ndate: yesterday\n---\nBody", wantErr: "publish date"},
+	}
+	for _, tt := range tests {
+		got, err := parseMarkdownPost(tt.name, tt.data)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseMarkdownPost(%q) = %v, want an error about %s", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got.Header != tt.want.Header || got.Slug != tt.want.Slug || got.Content != tt.want.Content || got.Published != tt.want.Published || got.NoIndex != tt.want.NoIndex {
+			t.Errorf("parseMarkdownPost(%q) = %+v, %v, want %+v", tt.name, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+)
+
+const (
+	DEFAULT_MAINTENANCE     = false // Whether the blog starts in maintenance mode, override with MAINTENANCE
+	MAINTENANCE_RETRY_AFTER = 300   // Seconds clients are told to wait before trying again while in maintenance mode
+)
+
+// Routes that keep working in maintenance mode, on top of the protected ones authors use.
+// Health checks and metrics keep monitoring honest, and static files are what the maintenance page is styled with.
+// The readiness check answers for itself, so it can say it's down for maintenance rather than send the page
+var maintenanceExemptRoutes = map[string]bool{
+	HEALTH:  true,
+	READY:   true,
+	METRICS: true,
+	STATIC:  true,
+}
+
+// Answers every public route with the maintenance page and a 503, so readers see we'll be right back rather than errors
+// while the DB is down. Authors can still log in and use the protected routes
+func (b *Blog) maintenanceMiddleware(routes *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, route := routes.Handler(r)
+		if protectedRoutes[route] || maintenanceExemptRoutes[route] || b.isAdmin(r) {
+			routes.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Retry-After", strconv.Itoa(MAINTENANCE_RETRY_AFTER))
+		// So nothing in between holds on to the maintenance page once we're back
+		w.Header().Set("Cache-Control", "no-store")
+		b.renderTemplateStatus(w, r, http.StatusServiceUnavailable, "maintenance.html", nil)
+	})
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMaintenanceMode(t *testing.T) {
+	config := testConfig(t)
+	config.Maintenance = true
+	b := newFakeBlog(t, config)
+	createLivePost(t, b.store, "live")
+	router := newRouter(b)
+	handler := b.maintenanceMiddleware(router)
+
+	for _, path := range []string{HOME, POST + "live", RSS, API_POSTS, "/nothing-here"} {
+		w := do(handler, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("GET %s in maintenance mode responded %d, want %d", path, w.Code, http.StatusServiceUnavailable)
+		}
+		if got := w.Header().Get("Retry-After"); got != strconv.Itoa(MAINTENANCE_RETRY_AFTER) {
+			t.Errorf("GET %s in maintenance mode has Retry-After %q, want %d", path, got, MAINTENANCE_RETRY_AFTER)
+		}
+		if got := w.Header().Get("Cache-Control"); got != "no-store" {
+			t.Errorf("GET %s in maintenance mode has Cache-Control %q, want no-store", path, got)
+		}
+		if !strings.Contains(w.Body.String(), "down for some maintenance") {
+			t.Errorf("GET %s in maintenance mode didn't respond with the maintenance page:\n%s", path, w.Body.String())
+		}
+	}
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{name: "liveness", req: httptest.NewRequest(http.MethodGet, HEALTH, nil), want: http.StatusOK},
+		// Down for maintenance, so load balancers stop sending readers, but answered by the check itself
+		{name: "readiness", req: httptest.NewRequest(http.MethodGet, READY, nil), want: http.StatusServiceUnavailable},
+		{name: "metrics", req: httptest.NewRequest(http.MethodGet, METRICS, nil), want: http.StatusOK},
+		{name: "static files", req: httptest.NewRequest(http.MethodGet, STATIC+"style.css", nil), want: http.StatusOK},
+		{name: "admin on a public page", req: adminRequest(http.MethodGet, HOME, nil), want: http.StatusOK},
+		{name: "admin dashboard", req: adminRequest(http.MethodGet, ADMIN, nil), want: http.StatusOK},
+		{name: "dashboard without logging in", req: httptest.NewRequest(http.MethodGet, ADMIN, nil), want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := do(handler, tt.req)
+			if w.Code != tt.want {
+				t.Errorf("GET %s in maintenance mode responded %d, want %d", tt.req.URL.Path, w.Code, tt.want)
+			}
+			if strings.Contains(w.Body.String(), "down for some maintenance") {
+				t.Errorf("GET %s in maintenance mode responded with the maintenance page", tt.req.URL.Path)
+			}
+		})
+	}
+
+	var health HealthStatus
+	decodeJSON(t, do(handler, httptest.NewRequest(http.MethodGet, READY, nil)), &health)
+	if health.Status != "maintenance" {
+		t.Errorf("GET %s in maintenance mode reported %+v, want status maintenance", READY, health)
+	}
+
+	// Turned off, the same blog serves readers again
+	b.config.Maintenance = false
+	if w := do(router, httptest.NewRequest(http.MethodGet, HOME, nil)); w.Code != http.StatusOK {
+		t.Errorf("GET %s out of maintenance mode responded %d, want %d", HOME, w.Code, http.StatusOK)
+	}
+	if w := do(router, httptest.NewRequest(http.MethodGet, READY, nil)); w.Code != http.StatusOK {
+		t.Errorf("GET %s out of maintenance mode responded %d, want %d", READY, w.Code, http.StatusOK)
+	}
+}
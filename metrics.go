@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"          // Metrics
+	"github.com/prometheus/client_golang/prometheus/promhttp" // Metrics endpoint
+)
+
+const METRICS_NAMESPACE = "goblog" // Prefixed to every metric name
+
+// The metrics served at /metrics, in a registry of their own so nothing else in the process can add to them
+type Metrics struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec   // By route and status
+	latency  *prometheus.HistogramVec // By route
+	queries  *prometheus.HistogramVec // By store operation
+	slow     *prometheus.CounterVec   // By store operation
+}
+
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: METRICS_NAMESPACE,
+			Name:      "http_requests_total",
+			Help:      "Requests served, by the route they matched and the status they got.",
+		}, []string{"route", "status"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: METRICS_NAMESPACE,
+			Name:      "http_request_duration_seconds",
+			Help:      "How long requests took to serve, by the route they matched.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route"}),
+		queries: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: METRICS_NAMESPACE,
+			Name:      "db_query_duration_seconds",
+			Help:      "How long calls to the database took, by the store operation that made them.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation"}),
+		slow: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: METRICS_NAMESPACE,
+			Name:      "db_slow_queries_total",
+			Help:      "Calls to the database that took longer than SLOW_QUERY_THRESHOLD, by the store operation that made them.",
+		}, []string{"operation"}),
+	}
+	m.registry.MustRegister(m.requests, m.latency, m.queries, m.slow, prometheus.NewGoCollector(), prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
+	return m
+}
+
+// Records a served request. route should be the pattern it matched rather than its path, so there's one series per route not per post
+func (m *Metrics) observe(route string, status int, elapsed time.Duration) {
+	m.requests.WithLabelValues(route, strconv.Itoa(status)).Inc()
+	m.latency.WithLabelValues(route).Observe(elapsed.Seconds())
+}
+
+// Records a call to the store, operation being the name of the PostStore method
+func (m *Metrics) observeQuery(operation string, elapsed time.Duration) {
+	m.queries.WithLabelValues(operation).Observe(elapsed.Seconds())
+}
+
+// Counts a call to the store that was slower than SLOW_QUERY_THRESHOLD
+func (m *Metrics) slowQuery(operation string) {
+	m.slow.WithLabelValues(operation).Inc()
+}
+
+// Reports how many DB connections are checked out each time the metrics are scraped, by calling inUse
+func (m *Metrics) watchConnections(inUse func() int) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: METRICS_NAMESPACE,
+		Name:      "db_connections_in_use",
+		Help:      "DB connections currently checked out of the pool.",
+	}, func() float64 {
+		return float64(inUse())
+	}))
+}
+
+// Serves the metrics in the Prometheus text format
+func (m *Metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
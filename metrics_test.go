@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// The metrics b serves at /metrics, in the Prometheus text format
+func scrape(t *testing.T, b *Blog) string {
+	t.Helper()
+	w := do(newRouter(b), httptest.NewRequest(http.MethodGet, METRICS, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s responded %d, want %d", METRICS, w.Code, http.StatusOK)
+	}
+	return w.Body.String()
+}
+
+func TestMetrics(t *testing.T) {
+	captureLog(t)
+	b := newFakeBlog(t, testConfig(t))
+	createLivePost(t, b.store, "live")
+	router := newRouter(b)
+	handler := loggingMiddleware(router, router, b.metrics)
+	b.metrics.watchConnections(func() int { return 3 })
+
+	for _, path := range []string{HOME, HOME, POST + "live", POST + "missing", "/no-such-page"} {
+		do(handler, httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	metrics := scrape(t, b)
+	for _, want := range []string{
+		`goblog_http_requests_total{route="` + HOME + `",status="200"} 2`,
+		// Counted by the route rather than the path, so there's one series however many posts there are
+		`goblog_http_requests_total{route="` + POST + `",status="200"} 1`,
+		`goblog_http_requests_total{route="` + POST + `",status="404"} 1`,
+		`goblog_http_requests_total{route="/",status="404"} 1`,
+		`goblog_http_request_duration_seconds_count{route="` + HOME + `"} 2`,
+		`goblog_db_query_duration_seconds_count{operation="Get"}`,
+		`goblog_db_connections_in_use 3`,
+	} {
+		if !strings.Contains(metrics, want) {
+			t.Errorf("GET %s doesn't report %s", METRICS, want)
+		}
+	}
+	if strings.Contains(metrics, "missing") {
+		t.Errorf("GET %s has a series for a post's slug", METRICS)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+const DEFAULT_MAX_BODY_SIZE = 1 << 20 // Bytes a request to the write routes may send, override with MAX_BODY_SIZE
+
+// Wraps a ResponseWriter to remember the status code a handler responded with
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rw *responseWriter) WriteHeader(status int) {
+	if rw.status == 0 {
+		rw.status = status
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		// Writing without calling WriteHeader first implies a 200
+		rw.status = http.StatusOK
+	}
+	return rw.ResponseWriter.Write(b)
+}
+
+// Logs the client's IP and the method, path, status and duration of every request once it's been served, and records them in metrics
+// labelled by the route in routes the request matched
+func loggingMiddleware(next http.Handler, routes *http.ServeMux, metrics *Metrics) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := &responseWriter{ResponseWriter: w}
+
+		next.ServeHTTP(rw, r)
+
+		status := rw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		elapsed := time.Since(start)
+		logf(r.Context(), "%s %s %s %d %s", clientIP(r), r.Method, r.URL.Path, status, elapsed)
+
+		// The pattern the request matched, e.g. /post/, anything unrouted is counted under /
+		_, route := routes.Handler(r)
+		metrics.observe(route, status, elapsed)
+	})
+}
+
+// Refuses requests with a body over limit bytes with a 413. The body is read up front, so the handler gets the 413
+// however it goes on to read it, rather than each one having to spot the error from a cut off form or JSON
+func maxBodyMiddleware(limit int64, handlerFn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if readBody(w, r, limit) {
+			handlerFn(w, r)
+		}
+	}
+}
+
+// maxBodyMiddleware for handlers that check who's asking before reading the body, so nobody can make us read one
+// without logging in. False once it's responded with a 413, or a 400 if the body couldn't be read at all
+func readBody(w http.ResponseWriter, r *http.Request, limit int64) bool {
+	if r.ContentLength > limit {
+		// Too big by its own account, so there's no need to read any of it
+		http.Error(w, "Request body too large.", http.StatusRequestEntityTooLarge)
+		return false
+	}
+	src := &bodyReader{ReadCloser: r.Body}
+	body, err := io.ReadAll(http.MaxBytesReader(w, src, limit))
+	if err != nil && src.err != nil {
+		// The client went away or sent a broken body, which says nothing about its size
+		http.Error(w, "Failed to read the request body.", http.StatusBadRequest)
+		return false
+	}
+	if err != nil {
+		// Bodies sent without a Content-Length are only caught once they go over
+		http.Error(w, "Request body too large.", http.StatusRequestEntityTooLarge)
+		return false
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return true
+}
+
+// Remembers what went wrong reading a request body, to tell it apart from MaxBytesReader's error for going over the
+// limit. Go 1.16 has no http.MaxBytesError to check for
+type bodyReader struct {
+	io.ReadCloser
+	err error
+}
+
+func (b *bodyReader) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		b.err = err
+	}
+	return n, err
+}
+
+// Turns a panicking handler into a logged stack trace and a 500, rather than a dropped connection
+func (b *Blog) recoverMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &responseWriter{ResponseWriter: w}
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler {
+				// Panicked on purpose to drop the connection, so let net/http do that
+				panic(err)
+			}
+
+			panicErr := fmt.Errorf("panic serving %s %s: %v\n%s", r.Method, r.URL.Path, err, debug.Stack())
+			if rw.status != 0 {
+				// Part of the response has gone already, there's no changing the status now
+				logf(r.Context(), "%v", panicErr)
+				return
+			}
+			b.renderError(rw, r, http.StatusInternalServerError, panicErr)
+		}()
+		next.ServeHTTP(rw, r)
+	})
+}
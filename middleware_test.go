@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestLoggingMiddleware(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    string
+	}{
+		{name: "implied 200", handler: func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("hi")) }, want: "GET /page 200 "},
+		{name: "404", handler: http.NotFound, want: "GET /page 404 "},
+		{name: "first status wins", handler: func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+			w.WriteHeader(http.StatusOK)
+		}, want: "GET /page 418 "},
+		{name: "nothing written", handler: func(w http.ResponseWriter, r *http.Request) {}, want: "GET /page 200 "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := captureLog(t)
+			routes := http.NewServeMux()
+			routes.Handle("/page", tt.handler)
+			metrics := NewMetrics()
+
+			req := httptest.NewRequest(http.MethodGet, "/page", nil)
+			do(loggingMiddleware(routes, routes, metrics), req)
+			if got := logs.String(); !strings.Contains(got, clientIP(req)+" "+tt.want) {
+				t.Errorf("logged %q, want a line containing %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	logs := captureLog(t)
+	b := newFakeBlog(t, testConfig(t))
+	routes := http.NewServeMux()
+	routes.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		var p *Post
+		w.Write([]byte(p.Header)) // A nil pointer, like a fake store that returns nothing
+	})
+	routes.HandleFunc("/panic-late", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("half a page"))
+		panic("too late to change the status")
+	})
+	routes.HandleFunc("/fine", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("still up")) })
+	server := httptest.NewServer(b.recoverMiddleware(routes))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/panic")
+	if err != nil {
+		t.Fatalf("GET /panic: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("GET /panic responded %d, want %d", resp.StatusCode, http.StatusInternalServerError)
+	}
+	if !strings.Contains(string(body), "Something went wrong on our end") {
+		t.Errorf("GET /panic didn't respond with the 500 page:\n%s", body)
+	}
+	if got := logs.String(); !strings.Contains(got, "panic serving GET /panic: runtime error: invalid memory address") || !strings.Contains(got, "goroutine ") {
+		t.Errorf("the panic wasn't logged with its stack trace:\n%s", got)
+	}
+
+	resp, err = http.Get(server.URL + "/panic-late")
+	if err != nil {
+		t.Fatalf("GET /panic-late: %v", err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "half a page" {
+		t.Errorf("GET /panic-late responded %d %q, want the 200 and the half page it had already sent", resp.StatusCode, body)
+	}
+	if !strings.Contains(logs.String(), "too late to change the status") {
+		t.Errorf("the panic after writing wasn't logged:\n%s", logs.String())
+	}
+
+	resp, err = http.Get(server.URL + "/fine")
+	if err != nil {
+		t.Fatalf("GET /fine after the panics: %v", err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "still up" {
+		t.Errorf("GET /fine after the panics responded %d %q, want the server to still be serving", resp.StatusCode, body)
+	}
+}
+
+// A request body that breaks partway through, like a client going away mid-upload
+type brokenBody struct{}
+
+func (brokenBody) Read(p []byte) (int, error) { return 0, errors.New("connection reset") }
+
+func TestMaxBodyMiddleware(t *testing.T) {
+	const limit = 16
+	tests := []struct {
+		name       string
+		body       io.Reader
+		unsized    bool // Sent without a Content-Length, so it's only caught while it's read
+		wantStatus int
+	}{
+		{name: "empty", body: strings.NewReader(""), wantStatus: http.StatusOK},
+		{name: "at the limit", body: strings.NewReader(strings.Repeat("a", limit)), wantStatus: http.StatusOK},
+		{name: "over the limit", body: strings.NewReader(strings.Repeat("a", limit+1)), wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "unsized at the limit", body: strings.NewReader(strings.Repeat("a", limit)), unsized: true, wantStatus: http.StatusOK},
+		{name: "unsized over the limit", body: strings.NewReader(strings.Repeat("a", limit+1)), unsized: true, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "broken", body: brokenBody{}, unsized: true, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := maxBodyMiddleware(limit, func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				got = string(body)
+			})
+			req := httptest.NewRequest(http.MethodPost, SAVE, tt.body)
+			if tt.unsized {
+				req.ContentLength = -1
+			}
+			w := do(h, req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("responded %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK && len(got) != int(req.ContentLength) && !tt.unsized {
+				t.Errorf("the handler read %d bytes of the %d sent", len(got), req.ContentLength)
+			}
+			if tt.wantStatus != http.StatusOK && got != "" {
+				t.Error("the handler ran for a refused body")
+			}
+		})
+	}
+}
+
+func TestOversizedBodiesAreRefused(t *testing.T) {
+	config := testConfig(t)
+	config.MaxBodySize = 1 << 10
+	b := newFakeBlog(t, config)
+	createLivePost(t, b.store, "live")
+	router := newRouter(b)
+	big := strings.Repeat("a", 2<<10)
+
+	comment := httptest.NewRequest(http.MethodPost, POST+"live"+COMMENT, strings.NewReader(url.Values{"author": {"Ada"}, "body": {big}}.Encode()))
+	comment.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// Checked for a login first, so nobody can make us read a body without one
+	anonymous := httptest.NewRequest(http.MethodPost, SAVE+SAVE_ADD, strings.NewReader(big))
+	anonymous.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	tests := []struct {
+		name       string
+		req        *http.Request
+		wantStatus int
+	}{
+		{name: "save", req: formRequest(SAVE+SAVE_ADD, url.Values{"header": {"Big"}, "content": {big}, "slug": {"big"}}), wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "preview", req: formRequest(PREVIEW, url.Values{"header": {"Big"}, "content": {big}}), wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "comment", req: comment, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "API create", req: apiRequest(http.MethodPost, API_POSTS, `{"header":"Big","slug":"big","content":"`+big+`"}`), wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "API update", req: apiRequest(http.MethodPut, API_POSTS+"/live", `{"header":"Big","content":"`+big+`"}`), wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "without logging in", req: anonymous, wantStatus: http.StatusUnauthorized},
+		{name: "under the limit", req: formRequest(SAVE+SAVE_ADD, url.Values{"header": {"Small"}, "content": {"Words"}, "slug": {"small"}}), wantStatus: http.StatusSeeOther},
+	}
+	for _, tt := range tests {
+		if w := do(router, tt.req); w.Code != tt.wantStatus {
+			t.Errorf("%s: %s %s responded %d, want %d", tt.name, tt.req.Method, tt.req.URL.Path, w.Code, tt.wantStatus)
+		}
+	}
+
+	if _, found, _ := b.store.Get(context.Background(), "big"); found {
+		t.Error("an oversized post was saved")
+	}
+	if p := getPost(t, b.store, "live"); p.Content == big {
+		t.Error("an oversized update was saved")
+	}
+}
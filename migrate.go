@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"io/fs"
+	"log"
+	"strings"
+
+	"github.com/jackc/pgx/v4/pgxpool" // SQL connection pool
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS // Numbered .sql files, applied in filename order and each only ever once
+
+// Any number will do, it just has to be the same for every instance of the app so they take turns migrating
+const MIGRATION_LOCK_ID = 7242021
+
+// Brings the schema up to date by applying every migration not yet recorded in schema_migrations
+func runMigrations(ctx context.Context, pool *pgxpool.Pool) error {
+	_, err := pool.Exec(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations (version VARCHAR PRIMARY KEY, applied_at TIMESTAMPTZ NOT NULL DEFAULT now());")
+	if err != nil {
+		return err
+	}
+
+	// ReadDir returns the files sorted by name, which is the order they need applying in
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := applyMigration(ctx, pool, entry.Name()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Applies a single migration and records it in the same transaction, so it's never half applied
+func applyMigration(ctx context.Context, pool *pgxpool.Pool, name string) error {
+	version := strings.TrimSuffix(name, ".sql")
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Held until the transaction ends, so another instance starting at the same time waits rather than applying it twice
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1);", MIGRATION_LOCK_ID); err != nil {
+		return err
+	}
+
+	var applied bool
+	err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1);", version).Scan(&applied)
+	if err != nil || applied {
+		return err
+	}
+
+	sql, err := migrationFiles.ReadFile("migrations/" + name)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, string(sql)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version) VALUES ($1);", version); err != nil {
+		return err
+	}
+
+	log.Printf("Applied migration %s", version)
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"io/fs"
+	"regexp"
+	"testing"
+)
+
+func TestMigrationFilesAreNumbered(t *testing.T) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	named := regexp.MustCompile(`^(\d{4})_[a-z0-9_]+\.sql$`)
+	seen := map[string]string{}
+	for _, entry := range entries {
+		m := named.FindStringSubmatch(entry.Name())
+		if m == nil {
+			t.Errorf("migration %s isn't named NNNN_what_it_does.sql", entry.Name())
+			continue
+		}
+		if other, ok := seen[m[1]]; ok {
+			t.Errorf("migrations %s and %s share a number, so which goes first is down to their names", other, entry.Name())
+		}
+		seen[m[1]] = entry.Name()
+	}
+}
+
+func TestRunMigrations(t *testing.T) {
+	pool := openTestPostgres(t)
+	ctx := context.Background()
+
+	// Twice, as every restart runs them again
+	for i := 0; i < 2; i++ {
+		if err := runMigrations(ctx, pool); err != nil {
+			t.Fatalf("runMigrations, run %d: %v", i+1, err)
+		}
+	}
+
+	for _, table := range []string{"schema_migrations", "posts", "tags", "post_tags", "comments", "slug_redirects"} {
+		var exists bool
+		if err := pool.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL;", table).Scan(&exists); err != nil {
+			t.Fatal(err)
+		}
+		if !exists {
+			t.Errorf("there's no %s table after migrating", table)
+		}
+	}
+
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var applied int
+	if err := pool.QueryRow(ctx, "SELECT count(*) FROM schema_migrations;").Scan(&applied); err != nil {
+		t.Fatal(err)
+	}
+	if applied != len(entries) {
+		t.Errorf("schema_migrations records %d migrations, want each of the %d applied once", applied, len(entries))
+	}
+}
@@ -0,0 +1 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS published BOOLEAN NOT NULL DEFAULT false; -- Drafts are hidden from the public pages
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS tags (
+	id   SERIAL PRIMARY KEY,
+	name VARCHAR UNIQUE NOT NULL -- Normalized like a slug, so it can be used in the /tag/ url
+);
+
+CREATE TABLE IF NOT EXISTS post_tags (
+	post_id INTEGER NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+	tag_id  INTEGER NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+	PRIMARY KEY (post_id, tag_id)
+);
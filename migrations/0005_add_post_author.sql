@@ -0,0 +1 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS author VARCHAR NOT NULL DEFAULT 'Kealan Parr'; -- Every post before bylines was written by the blog's owner
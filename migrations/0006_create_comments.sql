@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS comments (
+	id         SERIAL PRIMARY KEY,
+	post_id    INTEGER NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+	author     VARCHAR NOT NULL,
+	body       TEXT NOT NULL,  -- Stored as written, it's escaped when rendered
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS comments_post_id ON comments (post_id);
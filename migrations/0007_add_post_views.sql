@@ -0,0 +1 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS views INTEGER NOT NULL DEFAULT 0; -- How many times readers have opened the post
@@ -0,0 +1,3 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS published_at TIMESTAMPTZ; -- The date the post is shown and ordered by, can be backdated unlike created_at
+UPDATE posts SET published_at = created_at WHERE published_at IS NULL;
+ALTER TABLE posts ALTER COLUMN published_at SET NOT NULL, ALTER COLUMN published_at SET DEFAULT now();
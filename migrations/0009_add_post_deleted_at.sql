@@ -0,0 +1 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ; -- Set when the post is deleted, it's kept so it can be restored until it's purged
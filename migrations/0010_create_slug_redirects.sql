@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS slug_redirects (
+	old_slug VARCHAR PRIMARY KEY, -- A slug the post had before it was renamed
+	post_id  INTEGER NOT NULL REFERENCES posts (id) ON DELETE CASCADE -- The post rather than its new slug, so renaming it again never leaves a chain of redirects
+);
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS canonical_url VARCHAR NOT NULL DEFAULT ''; -- Where the post was first published if it's cross-posted, empty if it's ours
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS no_index BOOLEAN NOT NULL DEFAULT false; -- Asks search engines not to index the post, and leaves it out of the sitemap
\ No newline at end of file
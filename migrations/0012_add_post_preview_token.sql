@@ -0,0 +1 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS preview_token VARCHAR NOT NULL DEFAULT ''; -- Lets anyone with it read the post before it's live, empty until the author first shares it
\ No newline at end of file
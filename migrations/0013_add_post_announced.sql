@@ -0,0 +1,2 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS announced BOOLEAN NOT NULL DEFAULT false; -- Set once the post's gone live and the webhook's been told, so it's only ever told once
+UPDATE posts SET announced = true WHERE published AND published_at <= now(); -- Everything already live went live before the webhook existed
\ No newline at end of file
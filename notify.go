@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DEFAULT_SMTP_PORT = 587              // The submission port, override with SMTP_PORT
+	NOTIFY_TIMEOUT    = 30 * time.Second // How long sending a notification gets before it's given up on
+)
+
+// Tells the blog's owner about what readers are doing on it, so it can be faked in tests
+type Notifier interface {
+	// comment has just been left on the post at slug, which can be read at postURL
+	NotifyComment(ctx context.Context, slug, postURL string, comment Comment) error
+}
+
+// The Notifier the configuration asks for, nil when notifications are turned off
+func newNotifier(config Config) Notifier {
+	if config.SMTPHost == "" {
+		return nil
+	}
+	return &smtpNotifier{
+		addr:     net.JoinHostPort(config.SMTPHost, strconv.Itoa(config.SMTPPort)),
+		host:     config.SMTPHost,
+		username: config.SMTPUsername,
+		password: config.SMTPPassword,
+		from:     config.SMTPFrom,
+		to:       config.NotifyEmail,
+	}
+}
+
+// Emails notifications to the address in NOTIFY_EMAIL through an SMTP server
+type smtpNotifier struct {
+	addr     string // host:port
+	host     string
+	username string // Only logs in to the server if this is set
+	password string
+	from     string
+	to       string
+}
+
+func (n *smtpNotifier) NotifyComment(ctx context.Context, slug, postURL string, comment Comment) error {
+	subject := "New comment on " + slug
+	body := fmt.Sprintf("%s commented on %s\n\n%s\n", comment.Author, postURL+"#comments", comment.Body)
+	return n.send(ctx, subject, body)
+}
+
+// Sends an email to n.to, upgrading to TLS when the server offers it. net/smtp has no timeouts of its own, so the
+// whole conversation is bounded by ctx's deadline
+func (n *smtpNotifier) send(ctx context.Context, subject, body string) error {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", n.addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: n.host}); err != nil {
+			return err
+		}
+	}
+	if n.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.username, n.password, n.host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(n.from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(n.to); err != nil {
+		return err
+	}
+	data, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := data.Write([]byte(emailMessage(n.from, n.to, subject, body))); err != nil {
+		return err
+	}
+	if err := data.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// The email with its headers. Newlines are stripped from the subject, so nothing in it can add headers of its own
+func emailMessage(from, to, subject, body string) string {
+	subject = strings.NewReplacer("\r", " ", "\n", " ").Replace(subject)
+	return "From: " + from + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"Date: " + time.Now().Format(time.RFC1123Z) + "\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" + body
+}
+
+// Sends the notification about a new comment in the background, so the reader isn't kept waiting on the mail server.
+// ctx is only used for its request ID in the logs, as the request will be long gone by the time it's sent
+func (b *Blog) notifyComment(ctx context.Context, slug, postURL string, comment Comment) {
+	if b.notifier == nil {
+		return
+	}
+	go func() {
+		notifyCtx, cancel := context.WithTimeout(context.Background(), NOTIFY_TIMEOUT)
+		defer cancel()
+		if err := b.notifier.NotifyComment(notifyCtx, slug, postURL, comment); err != nil {
+			logf(ctx, "Failed to send the notification about a comment on %q: %v", slug, err)
+		}
+	}()
+}
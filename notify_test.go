@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A comment the fakeNotifier was told about
+type commentNotification struct {
+	slug    string
+	postURL string
+	comment Comment
+}
+
+// A Notifier that passes on what it's told, so tests can wait for notifications sent in the background
+type fakeNotifier struct {
+	sent    chan commentNotification
+	release chan struct{} // When set, nothing's sent until it's closed
+	err     error
+}
+
+func newFakeNotifier() *fakeNotifier {
+	return &fakeNotifier{sent: make(chan commentNotification, 10)}
+}
+
+func (n *fakeNotifier) NotifyComment(ctx context.Context, slug, postURL string, comment Comment) error {
+	if n.release != nil {
+		<-n.release
+	}
+	n.sent <- commentNotification{slug: slug, postURL: postURL, comment: comment}
+	return n.err
+}
+
+// The next notification sent, failing the test if there isn't one soon
+func (n *fakeNotifier) next(t *testing.T) commentNotification {
+	t.Helper()
+	select {
+	case sent := <-n.sent:
+		return sent
+	case <-time.After(time.Second):
+		t.Fatal("no notification was sent")
+		return commentNotification{}
+	}
+}
+
+func TestCommentsNotify(t *testing.T) {
+	b := newFakeBlog(t, testConfig(t))
+	notifier := newFakeNotifier()
+	b.notifier = notifier
+	createLivePost(t, b.store, "live")
+	router := newRouter(b)
+
+	do(router, commentRequest("live", url.Values{"author": {"Ada"}, "body": {"First!"}}))
+	sent := notifier.next(t)
+	if sent.slug != "live" || sent.postURL != "http://example.com/post/live" {
+		t.Errorf("notified about a comment on %q at %q, want live at http://example.com/post/live", sent.slug, sent.postURL)
+	}
+	if sent.comment.Author != "Ada" || sent.comment.Body != "First!" {
+		t.Errorf("notified about %+v, want Ada's comment", sent.comment)
+	}
+
+	// Comments that aren't saved aren't worth telling anyone about
+	do(router, commentRequest("live", url.Values{"author": {"Ada"}, "body": {"  "}}))
+	do(router, commentRequest("missing", url.Values{"author": {"Ada"}, "body": {"Hello?"}}))
+	select {
+	case sent := <-notifier.sent:
+		t.Errorf("notified about a comment that wasn't saved: %+v", sent)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestCommentsDontWaitForTheNotification(t *testing.T) {
+	logs := captureLog(t)
+	b := newFakeBlog(t, testConfig(t))
+	notifier := newFakeNotifier()
+	notifier.release = make(chan struct{})
+	notifier.err = errors.New("mail server down")
+	b.notifier = notifier
+	createLivePost(t, b.store, "live")
+
+	// The notifier's stuck until it's released, so the response would never come if it waited on it
+	responded := make(chan int)
+	go func() { responded <- do(newRouter(b), commentRequest("live", url.Values{"body": {"Hi"}})).Code }()
+	select {
+	case code := <-responded:
+		if code != http.StatusSeeOther {
+			t.Errorf("commenting responded %d, want %d", code, http.StatusSeeOther)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("commenting waited on the notification")
+	}
+
+	close(notifier.release)
+	notifier.next(t)
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(logs.String(), "mail server down") && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !strings.Contains(logs.String(), "Failed to send the notification") {
+		t.Errorf("the failed notification wasn't logged:\n%s", logs)
+	}
+}
+
+func TestNewNotifier(t *testing.T) {
+	config := testConfig(t)
+	if n := newNotifier(config); n != nil {
+		t.Errorf("newNotifier without SMTP_HOST = %v, want notifications off", n)
+	}
+	config.SMTPHost, config.SMTPPort, config.NotifyEmail = "mail.example.com", 2525, "owner@example.com"
+	n, ok := newNotifier(config).(*smtpNotifier)
+	if !ok || n.addr != "mail.example.com:2525" || n.to != "owner@example.com" {
+		t.Errorf("newNotifier = %+v, want an smtpNotifier for mail.example.com:2525", n)
+	}
+}
+
+func TestEmailMessage(t *testing.T) {
+	msg := emailMessage("blog@example.com", "owner@example.com", "New comment on a\r\nBcc: everyone@example.com", "Hi")
+	parts := strings.SplitN(msg, "\r\n\r\n", 2)
+	headers, body := parts[0], parts[len(parts)-1]
+	if body != "Hi" {
+		t.Errorf("the email's body is %q, want Hi", body)
+	}
+	for _, line := range strings.Split(headers, "\r\n") {
+		if strings.HasPrefix(line, "Bcc:") {
+			t.Errorf("the subject added a header of its own:\n%s", headers)
+		}
+	}
+	for _, want := range []string{"From: blog@example.com", "To: owner@example.com", "Subject: New comment on a  Bcc: everyone@example.com", "Content-Type: text/plain; charset=utf-8"} {
+		if !strings.Contains(headers, want) {
+			t.Errorf("the email's headers are missing %q:\n%s", want, headers)
+		}
+	}
+}
+
+// Plays the part of an SMTP server for a single email, without TLS or logins, sending what it was sent on the channel
+func fakeSMTPServer(t *testing.T) (addr string, received chan string) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	received = make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 localhost")
+		var transcript strings.Builder
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			transcript.WriteString(line)
+			switch command := strings.ToUpper(strings.Fields(line + " x")[0]); command {
+			case "EHLO", "HELO":
+				reply("250 localhost")
+			case "DATA":
+				reply("354 go ahead")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					transcript.WriteString(line)
+				}
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				received <- transcript.String()
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return l.Addr().String(), received
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	addr, received := fakeSMTPServer(t)
+	host, port, _ := net.SplitHostPort(addr)
+	config := testConfig(t)
+	config.SMTPHost, config.SMTPFrom, config.NotifyEmail = host, "blog@example.com", "owner@example.com"
+	config.SMTPPort, _ = strconv.Atoi(port)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := newNotifier(config).NotifyComment(ctx, "live", "http://example.com/post/live", Comment{Author: "Ada", Body: "First!"})
+	if err != nil {
+		t.Fatalf("NotifyComment: %v", err)
+	}
+	transcript := <-received
+	for _, want := range []string{
+		"MAIL FROM:<blog@example.com>",
+		"RCPT TO:<owner@example.com>",
+		"Subject: New comment on live",
+		"Ada commented on http://example.com/post/live#comments",
+		"First!",
+	} {
+		if !strings.Contains(transcript, want) {
+			t.Errorf("the mail server wasn't sent %q:\n%s", want, transcript)
+		}
+	}
+}
+
+func TestSMTPNotifierTimesOut(t *testing.T) {
+	// Accepts the connection but never greets it
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		if conn, err := l.Accept(); err == nil {
+			defer conn.Close()
+			time.Sleep(2 * time.Second)
+		}
+	}()
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	config := testConfig(t)
+	config.SMTPHost = host
+	config.SMTPPort, _ = strconv.Atoi(port)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := newNotifier(config).NotifyComment(ctx, "live", "http://example.com/post/live", Comment{Body: "Hi"}); err == nil {
+		t.Error("NotifyComment to a server that never answers succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("NotifyComment took %s, want it given up on at the context's deadline", elapsed)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+)
+
+const SOCIAL_DESCRIPTION_LENGTH = 200 // Characters of the post shown in link previews on social media
+
+// The src of the first image in a rendered post, the sanitizer always writes attributes with double quotes
+var firstImageSrc = regexp.MustCompile(`<img[^>]*\ssrc="([^"]+)"`)
+
+// Fills in what link previews on social media are built from, the OpenGraph and Twitter Card tags in post.html.
+// The post's first image is used as the preview's image, if it has one
+func (b *Blog) setSocialMeta(r *http.Request, page *PostPage) {
+	postURL, err := url.Parse(b.absoluteURL(r, POST+page.Slug))
+	if err != nil {
+		// The host came from the request, so a broken one just means no preview
+		return
+	}
+	page.URL = postURL.String()
+	if page.CanonicalURL != "" {
+		// Shares of this copy count towards the original
+		page.URL = page.CanonicalURL
+	}
+	page.Description = page.Excerpt(SOCIAL_DESCRIPTION_LENGTH)
+
+	if match := firstImageSrc.FindStringSubmatch(string(page.Body)); match != nil {
+		// Previews need an absolute url, whereas uploads are embedded as /uploads/...
+		if src, err := url.Parse(html.UnescapeString(match[1])); err == nil {
+			page.Image = postURL.ResolveReference(src).String()
+		}
+	}
+}
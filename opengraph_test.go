@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSocialMetaTags(t *testing.T) {
+	config := testConfig(t)
+	config.BaseURL = "https://blog.example.com"
+	b := newFakeBlog(t, config)
+	createPost(t, b.store, Post{Header: `Tom & "Jerry"`, Content: "A chase scene.\n\n" + strings.Repeat("More words. ", 40), Slug: "plain", Author: "Tester", Published: true})
+	createPost(t, b.store, Post{Header: "Pictures", Content: "![A cat](/uploads/cat.png)", Slug: "pictures", Author: "Tester", Published: true})
+	createPost(t, b.store, Post{Header: "Cross-posted", Content: "Words", Slug: "cross-posted", Author: "Tester", Published: true, CanonicalURL: "https://elsewhere.example.com/original"})
+	router := newRouter(b)
+
+	tests := []struct {
+		slug       string
+		want, skip []string
+	}{
+		{
+			slug: "plain",
+			want: []string{
+				`<meta property="og:type" content="article">`,
+				`<meta property="og:title" content="Tom &amp; &#34;Jerry&#34;">`,
+				`<meta property="og:description" content="A chase scene.`,
+				`<meta property="og:url" content="https://blog.example.com/post/plain">`,
+				`<meta name="twitter:card" content="summary">`,
+				`<meta name="twitter:title" content="Tom &amp; &#34;Jerry&#34;">`,
+			},
+			skip: []string{"og:image", "twitter:image"},
+		},
+		{
+			slug: "pictures",
+			want: []string{
+				`<meta property="og:image" content="https://blog.example.com/uploads/cat.png">`,
+				`<meta name="twitter:card" content="summary_large_image">`,
+				`<meta name="twitter:image" content="https://blog.example.com/uploads/cat.png">`,
+			},
+		},
+		{
+			slug: "cross-posted",
+			want: []string{`<meta property="og:url" content="https://elsewhere.example.com/original">`},
+		},
+	}
+	for _, tt := range tests {
+		w := do(router, httptest.NewRequest(http.MethodGet, POST+tt.slug, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s%s responded %d, want %d", POST, tt.slug, w.Code, http.StatusOK)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(w.Body.String(), want) {
+				t.Errorf("GET %s%s is missing %s", POST, tt.slug, want)
+			}
+		}
+		for _, skip := range tt.skip {
+			if strings.Contains(w.Body.String(), skip) {
+				t.Errorf("GET %s%s has %s, want none", POST, tt.slug, skip)
+			}
+		}
+	}
+}
+
+func TestSocialDescriptionIsShort(t *testing.T) {
+	b := newFakeBlog(t, testConfig(t))
+	page := PostPage{Post: Post{Slug: "long", Content: strings.Repeat("word ", 200)}}
+	page.prepareForDisplay(b.config.HTMLPolicy)
+	b.setSocialMeta(httptest.NewRequest(http.MethodGet, POST+"long", nil), &page)
+	if n := len([]rune(page.Description)); n > SOCIAL_DESCRIPTION_LENGTH+len("…") {
+		t.Errorf("Description is %d characters, want at most about %d", n, SOCIAL_DESCRIPTION_LENGTH)
+	}
+	if page.URL != "http://example.com/post/long" {
+		t.Errorf("URL without BASE_URL = %q, want it built from the request's host", page.URL)
+	}
+}
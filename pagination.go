@@ -0,0 +1,99 @@
+package main
+
+import (
+	"net/url"
+	"strconv"
+)
+
+const PAGINATION_WINDOW = 2 // Pages either side of the current one that get a link, besides the first and last
+
+// The page links under a listing, rendered by the "pagination" template in views/_pagination.html
+type PaginationData struct {
+	Current int    // 1-indexed page being shown
+	Total   int    // Pages in the listing, the links are hidden when there's only one
+	BaseURL string // The listing's url with any query it needs, bar the page
+}
+
+// One of the numbered links, or a gap standing in for the pages left out between them
+type PageLink struct {
+	Page    int
+	URL     string
+	Current bool // Shown without a link, as it's the page being read
+	Gap     bool
+}
+
+// The url of page, the first page is left without a ?page= so it has just the one url
+func (p PaginationData) URL(page int) string {
+	u, err := url.Parse(p.BaseURL)
+	if err != nil {
+		// BaseURL is always built by us, so this never happens
+		return p.BaseURL
+	}
+	query := u.Query()
+	if page > 1 {
+		query.Set("page", strconv.Itoa(page))
+	} else {
+		query.Del("page")
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// Empty on the first page
+func (p PaginationData) PrevURL() string {
+	if p.Current <= 1 {
+		return ""
+	}
+	return p.URL(p.Current - 1)
+}
+
+// Empty on the last page
+func (p PaginationData) NextURL() string {
+	if p.Current >= p.Total {
+		return ""
+	}
+	return p.URL(p.Current + 1)
+}
+
+// Links to the first and last pages and the PAGINATION_WINDOW either side of the current one,
+// with a gap wherever pages are skipped, e.g. 1 … 4 5 6 7 8 … 20
+func (p PaginationData) Links() []PageLink {
+	var links []PageLink
+	for page := 1; page <= p.Total; page++ {
+		// A gap would take as much room as a lone page it skips, so that's shown instead
+		if !p.linked(page) && !(p.linked(page-1) && p.linked(page+1)) {
+			if !links[len(links)-1].Gap {
+				links = append(links, PageLink{Gap: true})
+			}
+			continue
+		}
+		links = append(links, PageLink{Page: page, URL: p.URL(page), Current: page == p.Current})
+	}
+	return links
+}
+
+// Whether page is the first, the last or within the PAGINATION_WINDOW of the current page
+func (p PaginationData) linked(page int) bool {
+	return page == 1 || page == p.Total || (page >= p.Current-PAGINATION_WINDOW && page <= p.Current+PAGINATION_WINDOW)
+}
+
+// The homepage's page links, keeping the sort order unless it's the default
+func (h HomePage) Pagination() PaginationData {
+	base := HOME
+	if h.Sort != "" && h.Sort != SORT_NEWEST {
+		base += "?sort=" + url.QueryEscape(string(h.Sort))
+	}
+	return PaginationData{Current: h.CurrentPage, Total: h.TotalPages, BaseURL: base}
+}
+
+func (s SearchPage) Pagination() PaginationData {
+	return PaginationData{Current: s.CurrentPage, Total: s.TotalPages, BaseURL: SEARCH + "?q=" + url.QueryEscape(s.Query)}
+}
+
+func (t TagPage) Pagination() PaginationData {
+	return PaginationData{Current: t.CurrentPage, Total: t.TotalPages, BaseURL: TAG + url.PathEscape(t.Tag) + "/"}
+}
+
+func (a AuthorPage) Pagination() PaginationData {
+	return PaginationData{Current: a.CurrentPage, Total: a.TotalPages, BaseURL: AUTHOR + url.PathEscape(a.Author) + "/"}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// The page numbers Links gives, with 0 for a gap and the current page negated
+func linkPages(links []PageLink) []int {
+	var pages []int
+	for _, link := range links {
+		switch {
+		case link.Gap:
+			pages = append(pages, 0)
+		case link.Current:
+			pages = append(pages, -link.Page)
+		default:
+			pages = append(pages, link.Page)
+		}
+	}
+	return pages
+}
+
+func TestPaginationLinks(t *testing.T) {
+	tests := []struct {
+		current, total int
+		want           []int
+	}{
+		{current: 1, total: 1, want: []int{-1}},
+		{current: 2, total: 3, want: []int{1, -2, 3}},
+		{current: 1, total: 20, want: []int{-1, 2, 3, 0, 20}},
+		{current: 10, total: 20, want: []int{1, 0, 8, 9, -10, 11, 12, 0, 20}},
+		{current: 20, total: 20, want: []int{1, 0, 18, 19, -20}},
+		// 2 is all a gap would skip, so it's linked instead
+		{current: 5, total: 20, want: []int{1, 2, 3, 4, -5, 6, 7, 0, 20}},
+		{current: 4, total: 7, want: []int{1, 2, 3, -4, 5, 6, 7}},
+	}
+	for _, tt := range tests {
+		p := PaginationData{Current: tt.current, Total: tt.total, BaseURL: HOME}
+		if got := linkPages(p.Links()); !equalInts(got, tt.want) {
+			t.Errorf("Links on page %d of %d = %v, want %v", tt.current, tt.total, got, tt.want)
+		}
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPaginationURLs(t *testing.T) {
+	tests := []struct {
+		base       string
+		page       int
+		want       string
+		prev, next string
+	}{
+		{base: HOME, page: 1, want: HOME, next: HOME + "?page=2"},
+		{base: HOME, page: 2, want: HOME + "?page=2", prev: HOME, next: HOME + "?page=3"},
+		{base: HOME + "?sort=oldest", page: 3, want: HOME + "?page=3&sort=oldest", prev: HOME + "?page=2&sort=oldest"},
+		{base: SEARCH + "?q=go+%26+rust", page: 2, want: SEARCH + "?page=2&q=go+%26+rust", prev: SEARCH + "?q=go+%26+rust", next: SEARCH + "?page=3&q=go+%26+rust"},
+		{base: TAG + "go/", page: 1, want: TAG + "go/", next: TAG + "go/?page=2"},
+	}
+	for _, tt := range tests {
+		total := 3
+		p := PaginationData{Current: tt.page, Total: total, BaseURL: tt.base}
+		if got := p.URL(tt.page); got != tt.want {
+			t.Errorf("URL(%d) from %s = %q, want %q", tt.page, tt.base, got, tt.want)
+		}
+		if got := p.PrevURL(); got != tt.prev {
+			t.Errorf("PrevURL on page %d from %s = %q, want %q", tt.page, tt.base, got, tt.prev)
+		}
+		if got := p.NextURL(); got != tt.next {
+			t.Errorf("NextURL on page %d from %s = %q, want %q", tt.page, tt.base, got, tt.next)
+		}
+	}
+}
+
+// Executes the pagination partial on its own with p
+func renderPagination(t *testing.T, b *Blog, p PaginationData) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := b.templates["home.html"].ExecuteTemplate(&buf, "pagination", p); err != nil {
+		t.Fatalf("executing pagination: %v", err)
+	}
+	return buf.String()
+}
+
+func TestPaginationPartial(t *testing.T) {
+	b := newFakeBlog(t, testConfig(t))
+
+	if got := strings.TrimSpace(renderPagination(t, b, PaginationData{Current: 1, Total: 1, BaseURL: HOME})); got != "" {
+		t.Errorf("pagination with a single page rendered %q, want nothing", got)
+	}
+
+	got := renderPagination(t, b, PaginationData{Current: 10, Total: 20, BaseURL: SEARCH + "?q=a+b"})
+	for _, want := range []string{
+		`<a href="/search/?page=9&amp;q=a&#43;b" rel="prev">Previous</a>`,
+		`<a href="/search/?q=a&#43;b">1</a>`,
+		`<span>&hellip;</span>`,
+		`<strong aria-current="page">10</strong>`,
+		`<a href="/search/?page=20&amp;q=a&#43;b">20</a>`,
+		`<a href="/search/?page=11&amp;q=a&#43;b" rel="next">Next</a>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("pagination on page 10 of 20 is missing %s:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, ">5</a>") || strings.Contains(got, ">15</a>") {
+		t.Errorf("pagination on page 10 of 20 links pages outside the window:\n%s", got)
+	}
+
+	first := renderPagination(t, b, PaginationData{Current: 1, Total: 2, BaseURL: HOME})
+	if strings.Contains(first, "Previous") || !strings.Contains(first, "Next") {
+		t.Errorf("pagination on the first page should only link onwards:\n%s", first)
+	}
+}
+
+func TestListingsArePaginated(t *testing.T) {
+	config := testConfig(t)
+	config.PostsPerPage = 1
+	b := newTestBlog(t, config)
+	for i := 0; i < 3; i++ {
+		createPost(t, b.store, Post{Header: "Go post " + strconv.Itoa(i), Content: "Words", Slug: "go-" + strconv.Itoa(i), Author: "Tester", Published: true, Tags: []Tag{{Name: "go"}}})
+	}
+	router := newRouter(b)
+
+	for _, path := range []string{HOME, TAG + "go/", AUTHOR + "Tester/", SEARCH + "?q=post"} {
+		body := do(router, httptest.NewRequest(http.MethodGet, path, nil)).Body.String()
+		if !strings.Contains(body, `<nav class="pagination">`) || !strings.Contains(body, `rel="next"`) {
+			t.Errorf("GET %s with 3 posts a page apart has no pagination", path)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const PREVIEW_TOKEN_BYTES = 32 // Random bytes in a post's preview token, base64 encoded so it fits in a url
+
+// Sends the post forms' Preview button to previewHandler, which only authors can use, and preview links to draftPreviewHandler
+func (b *Blog) previewRoutes(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		b.requireAdmin(maxBodyMiddleware(b.config.MaxBodySize, b.previewHandler))(w, r)
+		return
+	}
+	b.draftPreviewHandler(w, r)
+}
+
+// Renders the header and content from a post form as the post's page would show them, without saving anything,
+// so authors can check their Markdown before they submit it
+func (b *Blog) previewHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "The form couldn't be read, please go back and try again.", http.StatusBadRequest)
+		return
+	}
+	if !validCSRF(r) {
+		http.Error(w, "This form has expired, please go back, refresh and try again.", http.StatusForbidden)
+		return
+	}
+
+	post := Post{
+		Header:  r.PostFormValue("header"),
+		Content: r.PostFormValue("content"),
+		Author:  postAuthor(r, r.PostFormValue("author")),
+		Tags:    parseTags(r.PostFormValue("tags")),
+	}
+	if err := validatePost(post); err != nil {
+		b.generateResulTemplate(w, r, http.StatusBadRequest, &CRUDResult{Message: "Sorry! That post is too long, " + err.Error()})
+		return
+	}
+	publishedAt, err := parsePublishedAt(r.PostFormValue("published_at"), b.config.DisplayTimezone)
+	if err != nil {
+		b.generateResulTemplate(w, r, http.StatusBadRequest, &CRUDResult{Message: "Sorry! " + err.Error()})
+		return
+	}
+	if post.PublishedAt = publishedAt; publishedAt.IsZero() {
+		post.PublishedAt = time.Now()
+	}
+	post.prepareForDisplay(b.config.HTMLPolicy)
+
+	// It's whatever was in the form a moment ago, there's nothing worth keeping a copy of
+	w.Header().Set("Cache-Control", "no-store")
+	b.renderTemplate(w, r, "post.html", PostPage{Post: post, Preview: true})
+}
+
+// Shows a post that isn't live yet at /preview/<slug>?token=... to anyone with the link from its edit page, so authors
+// can share it for review before publishing it. Without the post's token it 404s like any other missing post
+func (b *Blog) draftPreviewHandler(w http.ResponseWriter, r *http.Request) {
+	slug := strings.TrimPrefix(r.URL.Path, PREVIEW)
+	token := r.URL.Query().Get("token")
+	if slug == "" || strings.Contains(slug, "/") || token == "" {
+		b.notFoundHandler(w, r)
+		return
+	}
+
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	p, found, err := b.store.Get(ctx, slug)
+	if err != nil {
+		b.renderError(w, r, dbErrorStatus(err), fmt.Errorf("failed to load post %q: %w", slug, err))
+		return
+	}
+	// Compared in constant time, so the token can't be guessed a character at a time
+	if !found || p.PreviewToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(p.PreviewToken)) != 1 {
+		b.notFoundHandler(w, r)
+		return
+	}
+	if p.Live() {
+		// It's been published since the link was shared
+		http.Redirect(w, r, POST+p.Slug, http.StatusFound)
+		return
+	}
+
+	p.NoIndex = true
+	p.prepareForDisplay(b.config.HTMLPolicy)
+	w.Header().Set("Cache-Control", "no-store")
+	b.renderTemplate(w, r, "post.html", PostPage{Post: p, Preview: true, Draft: true})
+}
+
+// A new token for previewing a post, long enough that it can't be guessed
+func newPreviewToken() (string, error) {
+	b := make([]byte, PREVIEW_TOKEN_BYTES)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
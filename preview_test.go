@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestPreview(t *testing.T) {
+	b := newFakeBlog(t, testConfig(t))
+	router := newRouter(b)
+	form := url.Values{
+		"header":  {"Trying <em>it</em> out"},
+		"content": {"Some **bold** words\n\n- a list\n\n<script>alert(1)</script>"},
+		"author":  {"Tester"},
+	}
+
+	w := do(router, formRequest(PREVIEW, form))
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST %s responded %d, want %d", PREVIEW, w.Code, http.StatusOK)
+	}
+	body := w.Body.String()
+	for _, want := range []string{"<strong>bold</strong>", "<li>a list</li>", "Trying &lt;em&gt;it&lt;/em&gt; out"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("POST %s doesn't show %q:\n%s", PREVIEW, want, body)
+		}
+	}
+	if strings.Contains(body, "<script>alert(1)</script>") {
+		t.Errorf("POST %s shows the script unsanitized", PREVIEW)
+	}
+	if got := w.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("POST %s Cache-Control = %q, want no-store", PREVIEW, got)
+	}
+	if posts, err := b.store.List(context.Background(), true); err != nil || len(posts) != 0 {
+		t.Errorf("POST %s left %d posts in the store, error %v, want it to save nothing", PREVIEW, len(posts), err)
+	}
+}
+
+func TestPreviewIsForAuthors(t *testing.T) {
+	b := newFakeBlog(t, testConfig(t))
+	router := newRouter(b)
+	form := url.Values{"header": {"Preview"}, "content": {"Words"}}
+
+	req := formRequest(PREVIEW, form)
+	req.Header.Del("Authorization")
+	if w := do(router, req); w.Code != http.StatusUnauthorized {
+		t.Errorf("POST %s without logging in responded %d, want %d", PREVIEW, w.Code, http.StatusUnauthorized)
+	}
+
+	req = adminRequest(http.MethodPost, PREVIEW, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if w := do(router, req); w.Code != http.StatusForbidden {
+		t.Errorf("POST %s without a CSRF token responded %d, want %d", PREVIEW, w.Code, http.StatusForbidden)
+	}
+
+	form.Set("published_at", "yesterday")
+	if w := do(router, formRequest(PREVIEW, form)); w.Code != http.StatusBadRequest {
+		t.Errorf("POST %s with publish date yesterday responded %d, want %d", PREVIEW, w.Code, http.StatusBadRequest)
+	}
+}
+
+// Where the edit page links to for sharing the post
+var previewLinkRE = regexp.MustCompile(`href="(/preview/[^"]+)"`)
+
+func TestDraftPreviewLink(t *testing.T) {
+	b := newFakeBlog(t, testConfig(t))
+	createPost(t, b.store, Post{Header: "Not yet", Content: "Secret **words**", Slug: "draft", Author: "Tester"})
+	createPost(t, b.store, Post{Header: "Other", Content: "Words", Slug: "other", Author: "Tester"})
+	router := newRouter(b)
+
+	// The link to share is on the edit page, and the same every time it's shown
+	editPage := do(router, adminRequest(http.MethodGet, EDIT+"draft", nil)).Body.String()
+	match := previewLinkRE.FindStringSubmatch(editPage)
+	if match == nil {
+		t.Fatalf("GET %sdraft has no preview link:\n%s", EDIT, editPage)
+	}
+	link := strings.ReplaceAll(match[1], "&amp;", "&")
+	if again := previewLinkRE.FindStringSubmatch(do(router, adminRequest(http.MethodGet, EDIT+"draft", nil)).Body.String()); again == nil || again[1] != match[1] {
+		t.Errorf("the preview link changed between showing the edit page, want it kept so shared links keep working")
+	}
+	otherPage := do(router, adminRequest(http.MethodGet, EDIT+"other", nil)).Body.String()
+	otherLink := strings.ReplaceAll(previewLinkRE.FindStringSubmatch(otherPage)[1], "&amp;", "&")
+	otherToken := otherLink[strings.Index(otherLink, "token="):]
+
+	// Anyone with the link can read it, without logging in
+	w := do(router, httptest.NewRequest(http.MethodGet, link, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s responded %d, want %d", link, w.Code, http.StatusOK)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "Secret <strong>words</strong>") || !strings.Contains(body, `<meta name="robots" content="noindex">`) {
+		t.Errorf("GET %s should show the draft, hidden from search engines:\n%s", link, body)
+	}
+	if got := w.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("GET %s has Cache-Control %q, want no-store", link, got)
+	}
+
+	for _, path := range []string{
+		PREVIEW + "draft",
+		PREVIEW + "draft?token=",
+		PREVIEW + "draft?token=guessed",
+		PREVIEW + "draft?" + otherToken,
+		PREVIEW + "missing?" + otherToken,
+		PREVIEW + "?" + otherToken,
+	} {
+		if w := do(router, httptest.NewRequest(http.MethodGet, path, nil)); w.Code != http.StatusNotFound {
+			t.Errorf("GET %s responded %d, want %d", path, w.Code, http.StatusNotFound)
+		}
+	}
+
+	// Once it's published the link goes to the post, and the edit page stops offering one
+	if err := b.store.Publish(context.Background(), "draft"); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	w = do(router, httptest.NewRequest(http.MethodGet, link, nil))
+	if w.Code != http.StatusFound || w.Header().Get("Location") != POST+"draft" {
+		t.Errorf("GET %s for a live post responded %d to %q, want %d to %s", link, w.Code, w.Header().Get("Location"), http.StatusFound, POST+"draft")
+	}
+	if previewLinkRE.MatchString(do(router, adminRequest(http.MethodGet, EDIT+"draft", nil)).Body.String()) {
+		t.Errorf("GET %sdraft for a live post still has a preview link", EDIT)
+	}
+}
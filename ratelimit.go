@@ -0,0 +1,103 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate" // Token bucket rate limiter
+)
+
+const (
+	DEFAULT_RATE_LIMIT = 1.0              // Requests a second each IP may make to the write routes, override with RATE_LIMIT
+	DEFAULT_RATE_BURST = 5                // Requests an IP may make in a burst before being limited, override with RATE_BURST
+	VISITOR_EXPIRY     = 10 * time.Minute // How long an IP's bucket is kept after its last request
+)
+
+// Hands out a token bucket per client IP, forgetting IPs that have gone quiet
+type ipRateLimiter struct {
+	mu       sync.Mutex
+	visitors map[string]*visitor
+	limit    rate.Limit
+	burst    int
+}
+
+type visitor struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// Starts a limiter whose cleanup runs until stop is closed
+func newIPRateLimiter(limit rate.Limit, burst int, stop <-chan struct{}) *ipRateLimiter {
+	l := &ipRateLimiter{visitors: map[string]*visitor{}, limit: limit, burst: burst}
+	go l.cleanup(VISITOR_EXPIRY, stop)
+	return l
+}
+
+// Takes a token from ip's bucket, if there isn't one it reports how long until there will be
+func (l *ipRateLimiter) allow(ip string) (bool, time.Duration) {
+	l.mu.Lock()
+	v, ok := l.visitors[ip]
+	if !ok {
+		v = &visitor{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.visitors[ip] = v
+	}
+	v.lastSeen = time.Now()
+	l.mu.Unlock()
+
+	reservation := v.limiter.Reserve()
+	if delay := reservation.Delay(); delay > 0 {
+		// Hand the token back, we're rejecting this request rather than making it wait
+		reservation.Cancel()
+		return false, delay
+	}
+	return true, 0
+}
+
+// Periodically drops the buckets of IPs we haven't heard from in expiry, so the map doesn't grow forever, until stop
+// is closed
+func (l *ipRateLimiter) cleanup(expiry time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(expiry)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			l.mu.Lock()
+			for ip, v := range l.visitors {
+				if time.Since(v.lastSeen) > expiry {
+					delete(l.visitors, ip)
+				}
+			}
+			l.mu.Unlock()
+		}
+	}
+}
+
+// Rejects requests from IPs that have run out of tokens with a 429
+func rateLimitMiddleware(l *ipRateLimiter, handlerFn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if ok, retryAfter := l.allow(clientIP(r)); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			http.Error(w, "Too many requests, please slow down.", http.StatusTooManyRequests)
+			return
+		}
+		handlerFn(w, r)
+	}
+}
+
+// Like rateLimitMiddleware, but only POSTs are limited and anything else goes straight through to handlerFn
+func rateLimitPostsMiddleware(l *ipRateLimiter, handlerFn http.HandlerFunc) http.HandlerFunc {
+	limited := rateLimitMiddleware(l, handlerFn)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			limited(w, r)
+			return
+		}
+		handlerFn(w, r)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestWriteRoutesAreRateLimited(t *testing.T) {
+	const burst = 3
+	config := testConfig(t)
+	config.RateLimit = 0.01 // A token every 100s, so none come back during the test
+	config.RateBurst = burst
+	// Whether the post's there doesn't matter, only whether the request got past the limit
+	router := newRouter(newFakeBlog(t, config))
+
+	path := DELETE + "live"
+	for i := 1; i <= burst+2; i++ {
+		req := adminRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		w := do(router, req)
+
+		if i <= burst {
+			if w.Code == http.StatusTooManyRequests {
+				t.Errorf("GET %s number %d was limited, want it let through", path, i)
+			}
+			continue
+		}
+		if w.Code != http.StatusTooManyRequests {
+			t.Errorf("GET %s number %d responded %d, want %d", path, i, w.Code, http.StatusTooManyRequests)
+		}
+		if retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After")); err != nil || retryAfter < 1 {
+			t.Errorf("GET %s number %d said to retry after %q, want a whole number of seconds", path, i, w.Header().Get("Retry-After"))
+		}
+	}
+
+	// Each IP has a bucket of its own
+	req := adminRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = "192.0.2.2:1234"
+	if w := do(router, req); w.Code == http.StatusTooManyRequests {
+		t.Errorf("GET %s from another IP was limited, want it let through", path)
+	}
+
+	// And the routes that don't write aren't limited at all
+	for i := 0; i < burst+2; i++ {
+		req := adminRequest(http.MethodGet, NEW, nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		if w := do(router, req); w.Code != http.StatusOK {
+			t.Fatalf("GET %s after being limited responded %d, want %d", NEW, w.Code, http.StatusOK)
+		}
+	}
+}
+
+func TestPasswordGuessesAreRateLimited(t *testing.T) {
+	const burst = 2
+	config := testConfig(t)
+	config.RateLimit = 0.01
+	config.RateBurst = burst
+	router := newRouter(newFakeBlog(t, config))
+
+	var last int
+	for i := 0; i < burst+1; i++ {
+		req := httptest.NewRequest(http.MethodPost, SAVE+SAVE_ADD, nil)
+		req.SetBasicAuth(TEST_ADMIN_USER, "guess"+strconv.Itoa(i))
+		last = do(router, req).Code
+	}
+	if last != http.StatusTooManyRequests {
+		t.Errorf("guessing the password %d times responded %d, want %d", burst+1, last, http.StatusTooManyRequests)
+	}
+}
+
+func TestRateLimiterCleanup(t *testing.T) {
+	l := &ipRateLimiter{visitors: map[string]*visitor{}, limit: 1, burst: 1}
+	l.allow("192.0.2.1")
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		l.cleanup(10*time.Millisecond, stop)
+		close(stopped)
+	}()
+
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(5 * time.Millisecond) {
+		l.mu.Lock()
+		left := len(l.visitors)
+		l.mu.Unlock()
+		if left == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("cleanup still has %d buckets long after they expired", left)
+		}
+	}
+
+	close(stop)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Error("cleanup kept running after stop was closed")
+	}
+}
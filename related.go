@@ -0,0 +1,78 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	RELATED_POSTS    = 3 // How many related posts are suggested under a post
+	MIN_MATCH_LENGTH = 4 // Header words shorter than this ("go", "the", "and") are too common to say posts are related
+)
+
+// Picks up to limit posts from all that are most like current, ranked by shared tags then by words their headers share.
+// Posts with nothing in common aren't suggested, and ties keep the order of all
+func relatedPosts(current Post, all []Post, limit int) []Post {
+	currentTags := map[string]bool{}
+	for _, tag := range current.Tags {
+		currentTags[tag.Name] = true
+	}
+	currentWords := headerWords(current.Header)
+
+	type candidate struct {
+		post        Post
+		sharedTags  int
+		sharedWords int
+	}
+	var candidates []candidate
+	for _, p := range all {
+		if p.Slug == current.Slug {
+			continue
+		}
+		c := candidate{post: p}
+		for _, tag := range p.Tags {
+			if currentTags[tag.Name] {
+				c.sharedTags++
+			}
+		}
+		for word := range headerWords(p.Header) {
+			if currentWords[word] {
+				c.sharedWords++
+			}
+		}
+		if c.sharedTags > 0 || c.sharedWords > 0 {
+			candidates = append(candidates, c)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].sharedTags != candidates[j].sharedTags {
+			return candidates[i].sharedTags > candidates[j].sharedTags
+		}
+		return candidates[i].sharedWords > candidates[j].sharedWords
+	})
+
+	related := []Post{}
+	for _, c := range candidates {
+		if len(related) == limit {
+			break
+		}
+		related = append(related, c.post)
+	}
+	return related
+}
+
+// The distinct words of a header worth comparing, lowercased with punctuation dropped
+func headerWords(header string) map[string]bool {
+	words := map[string]bool{}
+	normalized, err := normalizeSlug(header)
+	if err != nil {
+		return words
+	}
+	for _, word := range strings.Split(normalized, "-") {
+		if len(word) >= MIN_MATCH_LENGTH {
+			words[word] = true
+		}
+	}
+	return words
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRelatedPosts(t *testing.T) {
+	current := Post{Header: "Profiling Go services", Slug: "current", Tags: []Tag{{"go"}, {"performance"}}}
+	all := []Post{
+		current,
+		{Header: "Baking bread", Slug: "unrelated", Tags: []Tag{{"food"}}},
+		{Header: "Go generics", Slug: "one-tag", Tags: []Tag{{"go"}}},
+		{Header: "Making Go fast", Slug: "two-tags", Tags: []Tag{{"go"}, {"performance"}}},
+		{Header: "Profiling Rust services", Slug: "two-words"},
+		{Header: "Go and the web", Slug: "short-words-only"},
+		{Header: "Profiling databases", Slug: "one-word"},
+		{Header: "Go channels", Slug: "another-tag", Tags: []Tag{{"go"}}},
+	}
+
+	tests := []struct {
+		limit int
+		want  []string
+	}{
+		// Shared tags count for more than shared words, ties keep the order they came in
+		{limit: RELATED_POSTS, want: []string{"two-tags", "one-tag", "another-tag"}},
+		{limit: 10, want: []string{"two-tags", "one-tag", "another-tag", "two-words", "one-word"}},
+		{limit: 1, want: []string{"two-tags"}},
+	}
+	for _, tt := range tests {
+		if got := slugs(relatedPosts(current, all, tt.limit)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("relatedPosts(limit %d) = %v, want %v", tt.limit, got, tt.want)
+		}
+	}
+
+	if got := relatedPosts(Post{Header: "Nothing alike", Slug: "alone"}, all, RELATED_POSTS); len(got) != 0 {
+		t.Errorf("relatedPosts for a post with nothing in common = %v, want none", slugs(got))
+	}
+}
+
+func TestPostPageSuggestsRelatedPosts(t *testing.T) {
+	b := newFakeBlog(t, testConfig(t))
+	createPost(t, b.store, Post{Header: "Profiling Go", Content: "Words", Slug: "current", Author: "Tester", Published: true, Tags: []Tag{{"go"}}})
+	createPost(t, b.store, Post{Header: "Go generics", Content: "Words", Slug: "related", Author: "Tester", Published: true, Tags: []Tag{{"go"}}})
+	createPost(t, b.store, Post{Header: "Go drafts", Content: "Words", Slug: "draft", Author: "Tester", Tags: []Tag{{"go"}}})
+
+	body := do(newRouter(b), httptest.NewRequest(http.MethodGet, POST+"current", nil)).Body.String()
+	if !strings.Contains(body, `href="/post/related"`) {
+		t.Errorf("GET %scurrent doesn't suggest the related post", POST)
+	}
+	if strings.Contains(body, `href="/post/draft"`) {
+		t.Errorf("GET %scurrent suggests a draft", POST)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/microcosm-cc/bluemonday"                  // HTML sanitizer
+	"github.com/yuin/goldmark"                            // Markdown renderer
+	highlighting "github.com/yuin/goldmark-highlighting"  // Syntax highlighting for fenced code blocks
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html" // Markdown renderer options
+)
+
+const (
+	WORDS_PER_MINUTE = 200 // Roughly how fast people read, used for the reading time estimate
+
+	DEFAULT_HTML_POLICY = "basic" // The policy posts' HTML is sanitized with, override with HTML_POLICY
+)
+
+var (
+	// Posts may mix raw HTML into their Markdown, it's let through here and made safe by sanitizeHTML.
+	// Fenced code blocks are highlighted for the language they name, any language chroma doesn't know is left as a plain block
+	markdown = goldmark.New(
+		goldmark.WithRendererOptions(goldmarkhtml.WithUnsafe()),
+		goldmark.WithExtensions(highlighting.NewHighlighting(highlighting.WithFormatOptions(highlightFormatOptions...))),
+	)
+	// Strips every tag, leaving just the text
+	textPolicy = bluemonday.StrictPolicy()
+	// Authors put this in a post where its excerpt should end, like WordPress' <!--more--> tag
+	moreMarker = regexp.MustCompile(`<!--\s*more\s*-->`)
+)
+
+// The policies HTML_POLICY can pick from, by name. Every one of them strips anything that could run script
+var htmlPolicies = map[string]func() *bluemonday.Policy{
+	// Formatting, lists, tables and code but no links or images, for blogs that would rather posts didn't point anywhere
+	"strict": func() *bluemonday.Policy {
+		policy := bluemonday.NewPolicy()
+		policy.AllowElements("p", "br", "hr", "h1", "h2", "h3", "h4", "h5", "h6", "blockquote", "pre", "code", "span",
+			"em", "strong", "b", "i", "del", "s", "sub", "sup", "ul", "ol", "li", "table", "thead", "tbody", "tr", "th", "td")
+		return policy
+	},
+	// The UGC policy, which keeps links and images as well
+	"basic": bluemonday.UGCPolicy,
+}
+
+// The default policy, which is what posts were always sanitized with
+func newHTMLPolicy() *bluemonday.Policy {
+	policy, _ := namedHTMLPolicy(DEFAULT_HTML_POLICY)
+	return policy
+}
+
+// The policy in htmlPolicies called name, plus the class names the highlighter colours code with
+func namedHTMLPolicy(name string) (*bluemonday.Policy, error) {
+	newPolicy, ok := htmlPolicies[strings.ToLower(name)]
+	if !ok {
+		var names []string
+		for known := range htmlPolicies {
+			names = append(names, known)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("HTML_POLICY must be one of %s, got %q", strings.Join(names, ", "), name)
+	}
+	policy := newPolicy()
+	policy.AllowAttrs("class").Matching(highlightClasses).OnElements("pre", "code", "span")
+	return policy, nil
+}
+
+// Converts the Markdown a post is stored as into HTML sanitized with policy, the blog's HTML_POLICY, that's safe to
+// render without escaping. Policies strip anything that could run script, so posts can't carry stored XSS
+func RenderMarkdown(content string, policy *bluemonday.Policy) template.HTML {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(content), &buf); err != nil {
+		// Fall back to showing the raw Markdown escaped, rather than failing the whole page
+		log.Printf("Failed to render markdown: %v", err)
+		return template.HTML(template.HTMLEscapeString(content))
+	}
+	return sanitizeHTML(buf.String(), policy)
+}
+
+// Posts are always stored as written and only sanitized on the way out, so a tightened policy applies to old posts too
+func sanitizeHTML(unsafe string, policy *bluemonday.Policy) template.HTML {
+	return template.HTML(policy.Sanitize(unsafe))
+}
+
+// Fills in the fields of a Post that are derived from its Content, for showing it. The stores leave them empty
+func (p *Post) prepareForDisplay(policy *bluemonday.Policy) {
+	p.Body = RenderMarkdown(moreMarker.ReplaceAllString(p.Content, ""), policy)
+	p.ReadingTimeMinutes = estimateReadingTime(p.Content)
+	p.WordCount = wordCount(plainText(p.Body))
+}
+
+// prepareForDisplay for each of posts, with the HTML_POLICY
+func (b *Blog) prepare(posts []Post) {
+	for i := range posts {
+		posts[i].prepareForDisplay(b.config.HTMLPolicy)
+	}
+}
+
+// How many minutes content takes to read, rounded up so even the shortest post takes a minute
+func estimateReadingTime(content string) int {
+	words := len(strings.Fields(content))
+	minutes := (words + WORDS_PER_MINUTE - 1) / WORDS_PER_MINUTE
+	if minutes < 1 {
+		return 1
+	}
+	return minutes
+}
+
+// The text of rendered HTML with every tag stripped, Fields collapses the leftover whitespace to single spaces
+func plainText(body template.HTML) string {
+	return strings.Join(strings.Fields(html.UnescapeString(textPolicy.Sanitize(string(body)))), " ")
+}
+
+// How many words are in text, anything with a letter or number in it counts so stray punctuation like "-" doesn't
+func wordCount(text string) int {
+	words := 0
+	for _, field := range strings.Fields(text) {
+		if strings.IndexFunc(field, func(c rune) bool { return unicode.IsLetter(c) || unicode.IsNumber(c) }) >= 0 {
+			words++
+		}
+	}
+	return words
+}
+
+// The start of the post as plain text. Everything before its more marker if it has one, as the author picked where it ends,
+// otherwise the first n or so characters cut at a word boundary with an ellipsis if anything was left off
+func (p Post) Excerpt(n int) string {
+	if marker := moreMarker.FindStringIndex(p.Content); marker != nil {
+		if text := plainText(RenderMarkdown(p.Content[:marker[0]], textPolicy)); text != "" {
+			return text
+		}
+	}
+
+	body := p.Body
+	if body == "" {
+		// Only the text is kept, so the post needn't be prepared with the blog's HTML_POLICY first
+		body = RenderMarkdown(p.Content, textPolicy)
+	}
+	text := plainText(body)
+
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+
+	cut := string(runes[:n])
+	if runes[n] != ' ' {
+		// We're mid word, so back up to the end of the last whole one
+		if lastSpace := strings.LastIndex(cut, " "); lastSpace > 0 {
+			cut = cut[:lastSpace]
+		}
+	}
+	return strings.TrimRight(cut, " .,;:") + "..."
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{content: "# Title", want: "<h1>Title</h1>"},
+		{content: "Some **bold** and _italic_ text", want: "<p>Some <strong>bold</strong> and <em>italic</em> text</p>"},
+		{content: "- one\n- two", want: "<ul>\n<li>one</li>\n<li>two</li>\n</ul>"},
+		{content: "[a link](https://example.com)", want: `<a href="https://example.com" rel="nofollow">a link</a>`},
+	}
+	for _, tt := range tests {
+		if got := string(RenderMarkdown(tt.content, newHTMLPolicy())); !strings.Contains(got, tt.want) {
+			t.Errorf("RenderMarkdown(%q) = %q, want it to contain %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestPostPageRendersMarkdown(t *testing.T) {
+	b := newTestBlog(t, testConfig(t))
+	createPost(t, b.store, Post{Header: "Markdown", Content: "Some **bold** text", Slug: "markdown", Author: "Tester", Published: true})
+
+	w := do(newRouter(b), httptest.NewRequest(http.MethodGet, POST+"markdown", nil))
+	if !strings.Contains(w.Body.String(), "Some <strong>bold</strong> text") {
+		t.Errorf("GET %smarkdown doesn't show the post's Markdown as HTML:\n%s", POST, w.Body.String())
+	}
+}
+
+func TestPostFormsTakeContentInATextarea(t *testing.T) {
+	b := newTestBlog(t, testConfig(t))
+	createLivePost(t, b.store, "editable")
+	router := newRouter(b)
+
+	for _, path := range []string{NEW, EDIT + "editable"} {
+		w := do(router, adminRequest(http.MethodGet, path, nil))
+		if !strings.Contains(w.Body.String(), `<textarea id="content" name="content"`) {
+			t.Errorf("GET %s has no textarea for the post's content", path)
+		}
+	}
+}
+
+func TestPostsCantRunScript(t *testing.T) {
+	b := newTestBlog(t, testConfig(t))
+	createPost(t, b.store, Post{
+		Header:    "<script>alert('header')</script>",
+		Content:   "Hi <script>alert('content')</script><a href=\"javascript:alert(1)\" onclick=\"alert(2)\">link</a> <img src=x onerror=alert(3)>",
+		Slug:      "xss",
+		Author:    "<b>Mallory</b>",
+		Published: true,
+	})
+	router := newRouter(b)
+
+	for _, path := range []string{POST + "xss", HOME} {
+		body := do(router, httptest.NewRequest(http.MethodGet, path, nil)).Body.String()
+		for _, unsafe := range []string{"<script>alert", "javascript:", "onclick", "onerror", "<b>Mallory"} {
+			if strings.Contains(body, unsafe) {
+				t.Errorf("GET %s let %q through", path, unsafe)
+			}
+		}
+	}
+	if body := do(router, httptest.NewRequest(http.MethodGet, POST+"xss", nil)).Body.String(); !strings.Contains(body, "&lt;script&gt;alert(&#39;header&#39;)&lt;/script&gt;") {
+		t.Error("the header wasn't shown escaped")
+	}
+}
+
+func TestEstimateReadingTime(t *testing.T) {
+	tests := []struct {
+		words int
+		want  int
+	}{
+		{words: 0, want: 1},
+		{words: 1, want: 1},
+		{words: WORDS_PER_MINUTE, want: 1},
+		{words: WORDS_PER_MINUTE + 1, want: 2},
+		{words: 10 * WORDS_PER_MINUTE, want: 10},
+	}
+	for _, tt := range tests {
+		content := strings.Repeat("word ", tt.words)
+		if got := estimateReadingTime(content); got != tt.want {
+			t.Errorf("estimateReadingTime(%d words) = %d, want %d", tt.words, got, tt.want)
+		}
+	}
+}
+
+func TestPagesShowTheReadingTime(t *testing.T) {
+	b := newTestBlog(t, testConfig(t))
+	createPost(t, b.store, Post{Header: "Long", Content: strings.Repeat("word ", 3*WORDS_PER_MINUTE), Slug: "long", Author: "Tester", Published: true})
+	router := newRouter(b)
+
+	for _, path := range []string{HOME, POST + "long"} {
+		if w := do(router, httptest.NewRequest(http.MethodGet, path, nil)); !strings.Contains(w.Body.String(), "3 min read") {
+			t.Errorf("GET %s doesn't say the post takes 3 minutes to read", path)
+		}
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		n       int
+		want    string
+	}{
+		{name: "shorter than n", content: "Just a few words", n: 200, want: "Just a few words"},
+		{name: "exactly n", content: "Four", n: 4, want: "Four"},
+		{name: "cut between words", content: "one two three", n: 7, want: "one two..."},
+		{name: "cut mid word", content: "one two three", n: 10, want: "one two..."},
+		{name: "no trailing punctuation", content: "one, two three", n: 5, want: "one..."},
+		{name: "Markdown stripped", content: "# Title\n\nSome **bold** [link](https://example.com)", n: 200, want: "Title Some bold link"},
+		{name: "HTML stripped", content: "<div>Raw <em>HTML</em> &amp; more</div>", n: 200, want: "Raw HTML & more"},
+		{name: "multibyte", content: "héllo wörld", n: 8, want: "héllo..."},
+		{name: "more marker", content: "The intro.\n\n<!--more-->\n\nThe rest.", n: 200, want: "The intro."},
+		{name: "more marker past n", content: "one two three <!-- more --> four", n: 3, want: "one two three"},
+		{name: "more marker first", content: "<!--more-->one two three", n: 7, want: "one two..."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (Post{Content: tt.content}).Excerpt(tt.n); got != tt.want {
+				t.Errorf("Excerpt(%d) of %q = %q, want %q", tt.n, tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoreMarkerIsntShown(t *testing.T) {
+	b := newTestBlog(t, testConfig(t))
+	createPost(t, b.store, Post{Header: "More", Content: "The intro.\n\n<!--more-->\n\nThe rest.", Slug: "more", Author: "Tester", Published: true})
+	router := newRouter(b)
+
+	w := do(router, httptest.NewRequest(http.MethodGet, POST+"more", nil))
+	if body := w.Body.String(); strings.Contains(body, "more-->") || !strings.Contains(body, "The rest.") {
+		t.Errorf("GET %smore should show the whole post without its more marker:\n%s", POST, body)
+	}
+	w = do(router, httptest.NewRequest(http.MethodGet, HOME, nil))
+	if body := w.Body.String(); !strings.Contains(body, "The intro.") || strings.Contains(body, "The rest.") {
+		t.Errorf("GET %s should list the post up to its more marker:\n%s", HOME, body)
+	}
+}
+
+func TestWordCount(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"   \n\t ", 0},
+		{"one", 1},
+		{"one two  three\n\nfour", 4},
+		{"Hello, world! How's it going?", 5},
+		{"well - sort of ...", 3},
+		{"2021 was a year", 4},
+		{"naïve café", 2},
+	}
+	for _, tt := range tests {
+		if got := wordCount(tt.text); got != tt.want {
+			t.Errorf("wordCount(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestWordCountIgnoresMarkup(t *testing.T) {
+	tests := []struct {
+		content string
+		want    int
+	}{
+		{content: "# A heading\n\nSome **bold** words", want: 5},
+		{content: "[a link](https://example.com/a/long/path) here", want: 3},
+		{content: "<p class=\"intro\">Two words</p>", want: 2},
+		{content: "- one\n- two\n\n> quoted", want: 3},
+		{content: "before <!--more--> after", want: 2},
+	}
+	for _, tt := range tests {
+		p := Post{Content: tt.content}
+		p.prepareForDisplay(newHTMLPolicy())
+		if p.WordCount != tt.want {
+			t.Errorf("WordCount of %q = %d, want %d", tt.content, p.WordCount, tt.want)
+		}
+	}
+}
+
+func TestAdminShowsWordCounts(t *testing.T) {
+	b := newFakeBlog(t, testConfig(t))
+	createPost(t, b.store, Post{Header: "Counted", Content: "one **two** three four five six seven", Slug: "counted", Author: "Tester", Published: true})
+
+	w := do(newRouter(b), adminRequest(http.MethodGet, ADMIN, nil))
+	if !strings.Contains(w.Body.String(), "<td>7</td>") {
+		t.Errorf("GET %s doesn't show the post's 7 words:\n%s", ADMIN, w.Body.String())
+	}
+	var p Post
+	decodeJSON(t, do(newRouter(b), httptest.NewRequest(http.MethodGet, API_POSTS+"/counted", nil)), &p)
+	if p.WordCount != 7 {
+		t.Errorf("GET %s/counted word_count = %d, want 7", API_POSTS, p.WordCount)
+	}
+}
+
+func TestHTMLPolicies(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		strict  string // What the strict policy should render it as, or leave in it
+		basic   string
+		neither string // What no policy should let through
+	}{
+		{name: "link", content: "[a link](https://example.com)", strict: "<p>a link</p>", basic: `<a href="https://example.com" rel="nofollow">a link</a>`},
+		{name: "image", content: "![a cat](https://example.com/cat.png)", strict: "<p></p>", basic: `<img src="https://example.com/cat.png" alt="a cat">`},
+		{name: "formatting", content: "Some **bold** and `code`", strict: "<strong>bold</strong> and <code>code</code>", basic: "<strong>bold</strong> and <code>code</code>"},
+		{name: "script", content: "Hi <script>alert(1)</script>", strict: "Hi", basic: "Hi", neither: "<script"},
+		{name: "event handler", content: `<p onclick="alert(1)">Hi</p>`, strict: "<p>Hi</p>", basic: "<p>Hi</p>", neither: "onclick"},
+		{name: "javascript link", content: "[click](javascript:alert(1))", strict: "click", basic: "click", neither: "javascript:"},
+	}
+	for _, tt := range tests {
+		for policyName, want := range map[string]string{"strict": tt.strict, "basic": tt.basic} {
+			policy, err := namedHTMLPolicy(policyName)
+			if err != nil {
+				t.Fatalf("namedHTMLPolicy(%q): %v", policyName, err)
+			}
+			got := string(RenderMarkdown(tt.content, policy))
+			if !strings.Contains(got, want) {
+				t.Errorf("%s with the %s policy = %q, want it to contain %q", tt.name, policyName, got, want)
+			}
+			if tt.neither != "" && strings.Contains(got, tt.neither) {
+				t.Errorf("%s with the %s policy = %q, let %q through", tt.name, policyName, got, tt.neither)
+			}
+		}
+	}
+}
+
+func TestNamedHTMLPolicy(t *testing.T) {
+	for _, name := range []string{"strict", "basic", "Strict", "BASIC"} {
+		if policy, err := namedHTMLPolicy(name); err != nil || policy == nil {
+			t.Errorf("namedHTMLPolicy(%q) = %v, %v, want the policy", name, policy, err)
+		}
+	}
+	_, err := namedHTMLPolicy("ugc")
+	if err == nil || !strings.Contains(err.Error(), "basic, strict") {
+		t.Errorf("namedHTMLPolicy(%q) = %v, want an error listing the policies", "ugc", err)
+	}
+}
+
+func TestHTMLPolicyConfig(t *testing.T) {
+	tests := []struct {
+		env      string
+		wantLink bool
+	}{
+		{env: "", wantLink: true},
+		{env: "basic", wantLink: true},
+		{env: "strict", wantLink: false},
+	}
+	for _, tt := range tests {
+		clearConfigEnv(t)
+		setenv(t, "HTML_POLICY", tt.env)
+		config, err := LoadConfig()
+		if err != nil {
+			t.Fatalf("LoadConfig with HTML_POLICY %q: %v", tt.env, err)
+		}
+		b := newFakeBlog(t, config)
+		createPost(t, b.store, Post{Header: "Linked", Content: "[a link](https://example.com)", Slug: "linked", Author: "Tester", Published: true})
+		body := do(newRouter(b), httptest.NewRequest(http.MethodGet, POST+"linked", nil)).Body.String()
+		if got := strings.Contains(body, `<a href="https://example.com"`); got != tt.wantLink {
+			t.Errorf("with HTML_POLICY %q the post's link is shown %v, want %v", tt.env, got, tt.wantLink)
+		}
+	}
+}
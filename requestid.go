@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+)
+
+const (
+	REQUEST_ID_HEADER     = "X-Request-ID" // Read from the request if a proxy in front already set one, and echoed on the response
+	REQUEST_ID_BYTES      = 8              // Random bytes in the IDs we generate, hex encoded
+	MAX_REQUEST_ID_LENGTH = 128            // Longer IDs sent by clients are replaced with one of ours, so they can't flood the logs
+)
+
+type requestIDKey struct{}
+
+// Tags every request with an ID, so all the log lines for one request can be found together.
+// The ID is kept in the request's context for requestID and logf, and sent back in the X-Request-ID header
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(REQUEST_ID_HEADER)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(REQUEST_ID_HEADER, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// The ID of the request ctx belongs to, empty outside of a request
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Like log.Printf, but starts the line with the ID of the request ctx belongs to
+func logf(ctx context.Context, format string, args ...interface{}) {
+	if id := requestID(ctx); id != "" {
+		format = "[" + id + "] " + format
+	}
+	log.Printf(format, args...)
+}
+
+// Whether an ID sent by the client is safe to put in the logs, printable ASCII with no spaces so it can't forge extra lines
+func validRequestID(id string) bool {
+	if id == "" || len(id) > MAX_REQUEST_ID_LENGTH {
+		return false
+	}
+	for _, c := range id {
+		if c <= ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, REQUEST_ID_BYTES)
+	if _, err := rand.Read(b); err != nil {
+		// Never happens in practice, and a request without an ID is better than no request
+		log.Printf("Failed to generate a request ID: %v", err)
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
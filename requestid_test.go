@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	generated := regexp.MustCompile(`^[0-9a-f]{16}$`)
+	tests := []struct {
+		name string
+		sent string
+		keep bool // Whether the ID sent is the one used, rather than one we generate
+	}{
+		{name: "none sent"},
+		{name: "sent by a proxy", sent: "abc-123", keep: true},
+		{name: "at the limit", sent: strings.Repeat("a", MAX_REQUEST_ID_LENGTH), keep: true},
+		{name: "too long", sent: strings.Repeat("a", MAX_REQUEST_ID_LENGTH+1)},
+		{name: "spaces", sent: "abc 123"},
+		{name: "forged log line", sent: "abc\nGET /admin/ 200"},
+		{name: "not ASCII", sent: "abcé"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := captureLog(t)
+			var seen string
+			routes := http.NewServeMux()
+			routes.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+				seen = requestID(r.Context())
+				logf(r.Context(), "handling it")
+			})
+			req := httptest.NewRequest(http.MethodGet, "/page", nil)
+			if tt.sent != "" {
+				req.Header.Set(REQUEST_ID_HEADER, tt.sent)
+			}
+
+			w := do(requestIDMiddleware(loggingMiddleware(routes, routes, NewMetrics())), req)
+			id := w.Header().Get(REQUEST_ID_HEADER)
+			if tt.keep && id != tt.sent {
+				t.Errorf("%s = %q, want the %q sent", REQUEST_ID_HEADER, id, tt.sent)
+			}
+			if !tt.keep && !generated.MatchString(id) {
+				t.Errorf("%s = %q, want one we generated", REQUEST_ID_HEADER, id)
+			}
+			if seen != id {
+				t.Errorf("requestID in the handler = %q, want the %q responded with", seen, id)
+			}
+			for _, want := range []string{"[" + id + "] handling it", "[" + id + "] 192.0.2.1 GET /page 200 "} {
+				if !strings.Contains(logs.String(), want) {
+					t.Errorf("the logs are missing %q:\n%s", want, logs.String())
+				}
+			}
+		})
+	}
+}
+
+func TestEveryRequestGetsItsOwnID(t *testing.T) {
+	handler := requestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	first := do(handler, httptest.NewRequest(http.MethodGet, "/", nil)).Header().Get(REQUEST_ID_HEADER)
+	second := do(handler, httptest.NewRequest(http.MethodGet, "/", nil)).Header().Get(REQUEST_ID_HEADER)
+	if first == second {
+		t.Errorf("two requests were both given the ID %q", first)
+	}
+}
+
+func TestLogfOutsideARequest(t *testing.T) {
+	logs := captureLog(t)
+	if id := requestID(context.Background()); id != "" {
+		t.Errorf("requestID outside a request = %q, want none", id)
+	}
+	logf(context.Background(), "starting up")
+	if got := logs.String(); !strings.Contains(got, "starting up") || strings.Contains(got, "[") {
+		t.Errorf("logf outside a request logged %q, want the message without an ID", got)
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+const ROBOTS_CACHE_CONTROL = "public, max-age=86400" // Crawlers only check back now and then anyway
+
+// Serves robots.txt, the file ROBOTS_FILE names if it's set, or else one letting crawlers everywhere and pointing them at the sitemap
+func (b *Blog) robotsHandler(w http.ResponseWriter, r *http.Request) {
+	body := b.config.RobotsTxt
+	if body == "" {
+		body = fmt.Sprintf("User-agent: *\nAllow: /\n\nSitemap: %s\n", b.absoluteURL(r, SITEMAP))
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", ROBOTS_CACHE_CONTROL)
+	w.Write([]byte(body))
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRobotsTxt(t *testing.T) {
+	tests := []struct {
+		name      string
+		baseURL   string
+		robotsTxt string
+		want      string
+	}{
+		{name: "from the request", want: "Sitemap: http://example.com" + SITEMAP + "\n"},
+		{name: "from BASE_URL", baseURL: "https://blog.example.com", want: "Sitemap: https://blog.example.com" + SITEMAP + "\n"},
+		{name: "from ROBOTS_FILE", robotsTxt: "User-agent: *\nDisallow: /\n", want: "User-agent: *\nDisallow: /\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig(t)
+			config.BaseURL = tt.baseURL
+			config.RobotsTxt = tt.robotsTxt
+
+			w := do(newRouter(newFakeBlog(t, config)), httptest.NewRequest(http.MethodGet, ROBOTS, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("GET %s responded %d, want %d", ROBOTS, w.Code, http.StatusOK)
+			}
+			if got := w.Header().Get("Content-Type"); got != "text/plain; charset=utf-8" {
+				t.Errorf("GET %s has Content-Type %q, want text/plain", ROBOTS, got)
+			}
+			if got := w.Header().Get("Cache-Control"); got != ROBOTS_CACHE_CONTROL {
+				t.Errorf("GET %s has Cache-Control %q, want %q", ROBOTS, got, ROBOTS_CACHE_CONTROL)
+			}
+			if tt.robotsTxt != "" {
+				if w.Body.String() != tt.want {
+					t.Errorf("GET %s = %q, want ROBOTS_FILE's %q", ROBOTS, w.Body.String(), tt.want)
+				}
+			} else if !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("GET %s = %q, want it to contain %q", ROBOTS, w.Body.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+const SCHEDULE_CHECK_INTERVAL = time.Minute // How often we look for scheduled posts that have gone live, so they're at most this late onto the homepage
+
+// Whether the post is published but with a publish date still to come, it goes live by itself once that date passes
+func (p Post) Scheduled() bool {
+	return p.Published && p.PublishedAt.After(time.Now())
+}
+
+// Whether the post is a draft nobody has picked a publish date for. Those are saved with their published_at the same as
+// their created_at, and dated when they're published
+func (p Post) Undated() bool {
+	return !p.Published && p.PublishedAt.Equal(p.CreatedAt)
+}
+
+// Whether readers can see the post, i.e. it's published and its publish date has arrived
+func (p Post) Live() bool {
+	return p.Published && !p.Scheduled()
+}
+
+// Empties the page cache whenever a scheduled post goes live, and tells the webhook about it. Nothing is written to the
+// DB when the publish date arrives, so without this the cached homepage would go on missing the post until the next
+// edit. Runs until stop is closed
+func (b *Blog) watchSchedule(stop <-chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), QUERY_TIMEOUT)
+			if err := b.announceLive(ctx); err != nil {
+				logf(ctx, "Failed to check for scheduled posts going live: %v", err)
+			}
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPostStates(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name                     string
+		post                     Post
+		live, scheduled, undated bool
+	}{
+		{name: "undated draft", post: Post{CreatedAt: now, PublishedAt: now}, undated: true},
+		{name: "backdated draft", post: Post{CreatedAt: now, PublishedAt: now.Add(-time.Hour)}},
+		{name: "live", post: Post{Published: true, CreatedAt: now, PublishedAt: now.Add(-time.Minute)}, live: true},
+		{name: "scheduled", post: Post{Published: true, CreatedAt: now, PublishedAt: now.Add(time.Hour)}, scheduled: true},
+		{name: "draft dated later", post: Post{CreatedAt: now, PublishedAt: now.Add(time.Hour)}},
+	}
+	for _, tt := range tests {
+		if got := tt.post.Live(); got != tt.live {
+			t.Errorf("%s: Live = %v, want %v", tt.name, got, tt.live)
+		}
+		if got := tt.post.Scheduled(); got != tt.scheduled {
+			t.Errorf("%s: Scheduled = %v, want %v", tt.name, got, tt.scheduled)
+		}
+		if got := tt.post.Undated(); got != tt.undated {
+			t.Errorf("%s: Undated = %v, want %v", tt.name, got, tt.undated)
+		}
+	}
+}
+
+func TestScheduledPostsGoLive(t *testing.T) {
+	b := newTestBlog(t, testConfig(t))
+	goesLive := time.Now().Add(time.Second)
+	createPost(t, b.store, Post{Header: "Coming soon", Content: "Words", Slug: "coming-soon", Author: "Tester", Published: true, PublishedAt: goesLive})
+	router := newRouter(b)
+	visible := func(path string) bool {
+		return strings.Contains(do(router, httptest.NewRequest(http.MethodGet, path, nil)).Body.String(), POST+"coming-soon")
+	}
+
+	// The homepage is cached without it from here on
+	for _, path := range []string{HOME, RSS, SITEMAP, POST + "coming-soon"} {
+		if visible(path) {
+			t.Errorf("GET %s shows the post before its publish date", path)
+		}
+	}
+	if w := do(router, httptest.NewRequest(http.MethodGet, POST+"coming-soon", nil)); w.Code != http.StatusNotFound {
+		t.Errorf("GET %scoming-soon before its publish date responded %d, want %d", POST, w.Code, http.StatusNotFound)
+	}
+	if body := do(router, adminRequest(http.MethodGet, ADMIN, nil)).Body.String(); !strings.Contains(body, "Scheduled to go live") {
+		t.Errorf("GET %s doesn't say the post is scheduled", ADMIN)
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		b.watchSchedule(stop, 20*time.Millisecond)
+		close(done)
+	}()
+	defer func() {
+		close(stop)
+		<-done
+	}()
+
+	time.Sleep(time.Until(goesLive))
+	deadline := time.Now().Add(5 * time.Second)
+	for !visible(HOME) {
+		if time.Now().After(deadline) {
+			t.Fatalf("GET %s still doesn't show the post after its publish date", HOME)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	for _, path := range []string{RSS, SITEMAP, POST + "coming-soon"} {
+		if !visible(path) {
+			t.Errorf("GET %s doesn't show the post after its publish date", path)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Type used to parse templates on the search results page
+type SearchPage struct {
+	HomePage
+	Query string // What the reader searched for, used to build the pagination links
+}
+
+func (b *Blog) searchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != SEARCH {
+		b.notFoundHandler(w, r)
+		return
+	}
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Redirect(w, r, HOME, http.StatusFound)
+		return
+	}
+	if len(query) > MAX_SEARCH_LENGTH {
+		http.Error(w, "That search is too long.", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	results, err := b.store.Search(ctx, query, requestedPage(r))
+	if err != nil {
+		b.renderError(w, r, dbErrorStatus(err), fmt.Errorf("failed to search posts for %q: %w", query, err))
+		return
+	}
+	b.prepare(results.Posts)
+
+	b.renderTemplate(w, r, "search.html", results)
+}
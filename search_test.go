@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	b := newTestBlog(t, testConfig(t))
+	createPost(t, b.store, Post{Header: "Learning Go", Content: "Goroutines are cheap", Slug: "go", Author: "Tester", Published: true})
+	createPost(t, b.store, Post{Header: "Baking bread", Content: "Knead the dough", Slug: "bread", Author: "Tester", Published: true})
+	createPost(t, b.store, Post{Header: "Unfinished goroutines", Content: "Draft", Slug: "draft", Author: "Tester"})
+	router := newRouter(b)
+
+	tests := []struct {
+		query      string
+		want, skip []string
+	}{
+		{query: "learning", want: []string{"Learning Go"}, skip: []string{"Baking bread"}},
+		{query: "dough", want: []string{"Baking bread"}, skip: []string{"Learning Go"}},
+		{query: "goroutine", want: []string{"Learning Go"}, skip: []string{"Baking bread", "Unfinished goroutines"}},
+		{query: "nothing-matches", want: []string{"No posts matched your search"}, skip: []string{"Learning Go", "Baking bread"}},
+	}
+	for _, tt := range tests {
+		w := do(router, httptest.NewRequest(http.MethodGet, SEARCH+"?q="+tt.query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("searching for %q responded %d, want %d", tt.query, w.Code, http.StatusOK)
+		}
+		for _, text := range tt.want {
+			if !strings.Contains(w.Body.String(), text) {
+				t.Errorf("searching for %q didn't find %q", tt.query, text)
+			}
+		}
+		for _, text := range tt.skip {
+			if strings.Contains(w.Body.String(), text) {
+				t.Errorf("searching for %q found %q", tt.query, text)
+			}
+		}
+	}
+}
+
+func TestSearchQueries(t *testing.T) {
+	// Neither gets as far as the DB
+	b := newClosedDBBlog(t)
+	router := newRouter(b)
+
+	if w := do(router, httptest.NewRequest(http.MethodGet, SEARCH+"?q=+", nil)); w.Code != http.StatusFound || w.Header().Get("Location") != HOME {
+		t.Errorf("an empty search responded %d to %q, want a redirect home", w.Code, w.Header().Get("Location"))
+	}
+	long := strings.Repeat("a", MAX_SEARCH_LENGTH+1)
+	if w := do(router, httptest.NewRequest(http.MethodGet, SEARCH+"?q="+long, nil)); w.Code != http.StatusBadRequest {
+		t.Errorf("a search of %d characters responded %d, want %d", len(long), w.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import "net/http"
+
+const (
+	// Only loads scripts, styles and fonts from the blog itself. Images can come from anywhere over https as posts embed them,
+	// and inline styles are allowed as the views use them. Override with CONTENT_SECURITY_POLICY
+	DEFAULT_CSP = "default-src 'self'; img-src 'self' https: data:; style-src 'self' 'unsafe-inline'; script-src 'self'; object-src 'none'; base-uri 'self'; form-action 'self'; frame-ancestors 'none'"
+
+	REFERRER_POLICY = "strict-origin-when-cross-origin" // Other sites see which blog a reader came from, but not which post
+)
+
+// Sets the security headers on every response
+func securityHeadersMiddleware(csp string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("X-Frame-Options", "DENY")
+		h.Set("Referrer-Policy", REFERRER_POLICY)
+		h.Set("Content-Security-Policy", csp)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	b := newFakeBlog(t, testConfig(t))
+	const csp = "default-src 'self'"
+	handler := securityHeadersMiddleware(csp, newRouter(b))
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		csp  string
+	}{
+		{name: "home", req: httptest.NewRequest(http.MethodGet, HOME, nil), csp: csp},
+		{name: "404", req: httptest.NewRequest(http.MethodGet, "/nothing-here", nil), csp: csp},
+		// Its script is a static file, so it keeps to the configured policy like every other page
+		{name: "new post page", req: adminRequest(http.MethodGet, NEW, nil), csp: csp},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := do(handler, tt.req)
+			want := map[string]string{
+				"X-Content-Type-Options":  "nosniff",
+				"X-Frame-Options":         "DENY",
+				"Referrer-Policy":         REFERRER_POLICY,
+				"Content-Security-Policy": tt.csp,
+			}
+			for header, value := range want {
+				if got := w.Header().Get(header); got != value {
+					t.Errorf("GET %s %s = %q, want %q", tt.req.URL.Path, header, got, value)
+				}
+			}
+		})
+	}
+}
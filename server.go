@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+const DEFAULT_LISTEN_ADDR = ":8080" // Where the blog listens, override with LISTEN_ADDR or PORT
+
+// Builds the blog's server around handler, serving HTTPS when the config names a certificate and its key.
+// The certificate is loaded here rather than when serving, so a bad one stops startup with a clear error
+func newServer(config Config, handler http.Handler) (*http.Server, error) {
+	server := &http.Server{Addr: config.ListenAddr, Handler: handler}
+	if config.TLSCert == "" || config.TLSKey == "" {
+		return server, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(config.TLSCert, config.TLSKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the TLS certificate: %w", err)
+	}
+	server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	return server, nil
+}
+
+// Builds a plain HTTP server on the config's HTTPRedirectAddr that sends every request over to httpsServer.
+// Returns nil when httpsServer isn't serving HTTPS or no redirect was asked for
+func newRedirectServer(config Config, httpsServer *http.Server) *http.Server {
+	if config.HTTPRedirectAddr == "" || httpsServer.TLSConfig == nil {
+		return nil
+	}
+	return &http.Server{Addr: config.HTTPRedirectAddr, Handler: redirectToHTTPS(httpsServer.Addr)}
+}
+
+// Redirects to the same url over HTTPS, on the port httpsAddr listens on
+func redirectToHTTPS(httpsAddr string) http.HandlerFunc {
+	_, port, _ := net.SplitHostPort(httpsAddr)
+	return func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	}
+}
+
+// Serves HTTPS if the server was given a certificate, or else plain HTTP
+func serve(server *http.Server) error {
+	if server.TLSConfig != nil {
+		// The certificate is already in TLSConfig, so no files need naming here
+		return server.ListenAndServeTLS("", "")
+	}
+	return server.ListenAndServe()
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Writes a self-signed certificate for localhost and its key to a temp dir, returning their paths
+func writeTestCert(t *testing.T) (certPath, keyPath string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certPath, keyPath = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certPath, keyPath
+}
+
+func TestNewServer(t *testing.T) {
+	certPath, keyPath := writeTestCert(t)
+	tests := []struct {
+		name         string
+		cert, key    string
+		redirectAddr string
+		wantErr      bool
+		wantTLS      bool
+		wantRedirect bool
+	}{
+		{name: "plain HTTP", wantTLS: false},
+		{name: "plain HTTP ignores the redirect", redirectAddr: ":8081", wantTLS: false, wantRedirect: false},
+		{name: "HTTPS", cert: certPath, key: keyPath, wantTLS: true},
+		{name: "HTTPS with a redirect", cert: certPath, key: keyPath, redirectAddr: ":8081", wantTLS: true, wantRedirect: true},
+		{name: "missing certificate", cert: filepath.Join(t.TempDir(), "missing.pem"), key: keyPath, wantErr: true},
+		{name: "key for the certificate swapped", cert: keyPath, key: certPath, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig(t)
+			config.ListenAddr = ":8443"
+			config.TLSCert, config.TLSKey = tt.cert, tt.key
+			config.HTTPRedirectAddr = tt.redirectAddr
+
+			server, err := newServer(config, http.NotFoundHandler())
+			if tt.wantErr {
+				if err == nil {
+					t.Error("newServer with a bad certificate succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newServer: %v", err)
+			}
+			if server.Addr != config.ListenAddr {
+				t.Errorf("the server listens on %q, want %q", server.Addr, config.ListenAddr)
+			}
+			if got := server.TLSConfig != nil; got != tt.wantTLS {
+				t.Errorf("the server serves HTTPS %v, want %v", got, tt.wantTLS)
+			}
+			redirect := newRedirectServer(config, server)
+			if got := redirect != nil; got != tt.wantRedirect {
+				t.Fatalf("newRedirectServer built a server %v, want %v", got, tt.wantRedirect)
+			}
+			if redirect != nil && redirect.Addr != tt.redirectAddr {
+				t.Errorf("the redirect server listens on %q, want %q", redirect.Addr, tt.redirectAddr)
+			}
+		})
+	}
+}
+
+func TestRedirectToHTTPS(t *testing.T) {
+	tests := []struct {
+		httpsAddr string
+		target    string
+		want      string
+	}{
+		{httpsAddr: ":443", target: "http://blog.example.com/post/hello?page=2", want: "https://blog.example.com/post/hello?page=2"},
+		{httpsAddr: ":443", target: "http://blog.example.com:8080/home/", want: "https://blog.example.com/home/"},
+		{httpsAddr: ":8443", target: "http://blog.example.com:8080/home/", want: "https://blog.example.com:8443/home/"},
+		{httpsAddr: "127.0.0.1:8443", target: "http://localhost/", want: "https://localhost:8443/"},
+	}
+	for _, tt := range tests {
+		w := do(redirectToHTTPS(tt.httpsAddr), httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != tt.want {
+			t.Errorf("GET %s to HTTPS on %s redirected %d to %q, want %d to %q", tt.target, tt.httpsAddr, w.Code, w.Header().Get("Location"), http.StatusMovedPermanently, tt.want)
+		}
+	}
+}
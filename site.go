@@ -0,0 +1,13 @@
+package main
+
+const (
+	DEFAULT_SITE_TITLE   = "go-blog"                                                                      // Override with SITE_TITLE
+	DEFAULT_SITE_TAGLINE = "An educative and eloquent technical blog on the prestigious go-blog platform" // Override with SITE_TAGLINE
+)
+
+// The branding every page and feed carries
+type Site struct {
+	Title   string // Shown at the top of every page, in the browser tab and as the feeds' title
+	Tagline string // Shown under the title, and used as the description search engines and feeds show
+	Footer  string // Shown at the bottom of every page, empty for no footer
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSiteBranding(t *testing.T) {
+	config := testConfig(t)
+	config.Site = Site{Title: "Notes & <Things>", Tagline: "Written down so I don't forget", Footer: "© Tester"}
+	b := newFakeBlog(t, config)
+	createLivePost(t, b.store, "branded")
+	router := newRouter(b)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, HOME, nil),
+		httptest.NewRequest(http.MethodGet, POST+"branded", nil),
+		httptest.NewRequest(http.MethodGet, ABOUT, nil),
+		httptest.NewRequest(http.MethodGet, "/nothing-here", nil),
+		adminRequest(http.MethodGet, NEW, nil),
+		adminRequest(http.MethodGet, EDIT+"branded", nil),
+		adminRequest(http.MethodGet, ADMIN, nil),
+	} {
+		body := do(router, req).Body.String()
+		for _, want := range []string{"Notes &amp; &lt;Things&gt;</title>", "<h1>Notes &amp; &lt;Things&gt;</h1>", "<footer>© Tester</footer>"} {
+			if !strings.Contains(body, want) {
+				t.Errorf("GET %s is missing %s", req.URL.Path, want)
+			}
+		}
+		if strings.Contains(body, "<Things>") || strings.Contains(body, DEFAULT_SITE_TITLE+"</") {
+			t.Errorf("GET %s shows the title unescaped or the default title", req.URL.Path)
+		}
+	}
+
+	if body := do(router, httptest.NewRequest(http.MethodGet, HOME, nil)).Body.String(); !strings.Contains(body, `content="Written down so I don&#39;t forget"`) {
+		t.Errorf("GET %s doesn't describe itself with the tagline", HOME)
+	}
+	if body := do(router, httptest.NewRequest(http.MethodGet, RSS, nil)).Body.String(); !strings.Contains(body, "<title>Notes &amp; &lt;Things&gt;</title>") {
+		t.Errorf("GET %s isn't titled with the site's title:\n%s", RSS, body)
+	}
+}
+
+func TestNoFooterByDefault(t *testing.T) {
+	b := newFakeBlog(t, testConfig(t))
+	body := do(newRouter(b), httptest.NewRequest(http.MethodGet, HOME, nil)).Body.String()
+	if !strings.Contains(body, "<title>"+DEFAULT_SITE_TITLE) || strings.Contains(body, "<footer>") {
+		t.Errorf("GET %s without any branding configured should have the default title and no footer", HOME)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/xml"
+	"net/http"
+	"time"
+)
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// Serves a sitemap listing the homepage and every published post
+func (b *Blog) sitemapHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	posts, err := b.store.Sitemap(ctx)
+	if err != nil {
+		logf(ctx, "Failed to load posts for the sitemap: %v", err)
+		http.Error(w, "Failed to load the sitemap.", dbErrorStatus(err))
+		return
+	}
+
+	home := sitemapURL{Loc: b.absoluteURL(r, HOME)}
+	urls := []sitemapURL{}
+	var newest time.Time
+	for _, p := range posts {
+		urls = append(urls, sitemapURL{Loc: b.absoluteURL(r, POST+p.Slug), LastMod: p.UpdatedAt.UTC().Format(time.RFC3339)})
+		if p.UpdatedAt.After(newest) {
+			newest = p.UpdatedAt
+		}
+	}
+	if !newest.IsZero() {
+		// The homepage changes whenever any post does
+		home.LastMod = newest.UTC().Format(time.RFC3339)
+	}
+
+	writeXML(w, r, "application/xml", sitemapURLSet{URLs: append([]sitemapURL{home}, urls...)})
+}
@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestSitemap(t *testing.T) {
+	var sitemap sitemapURLSet
+	getXML(t, newFeedBlog(t), SITEMAP, "application/xml", &sitemap)
+
+	var locs []string
+	for _, u := range sitemap.URLs {
+		locs = append(locs, u.Loc)
+		if u.LastMod == "" {
+			t.Errorf("%s has no lastmod", u.Loc)
+		}
+	}
+	want := []string{"http://example.com/home/", "http://example.com/post/newer", "http://example.com/post/older"}
+	if len(locs) != len(want) {
+		t.Fatalf("the sitemap lists %v, want %v", locs, want)
+	}
+	for i := range want {
+		if locs[i] != want[i] {
+			t.Errorf("the sitemap lists %v, want %v", locs, want)
+			break
+		}
+	}
+}
+
+func TestSitemapLeavesOutNoIndexedPosts(t *testing.T) {
+	b := newFeedBlog(t)
+	createPost(t, b.store, Post{Header: "Thin", Content: "Words", Slug: "thin", Author: "Tester", Published: true, NoIndex: true})
+	var sitemap sitemapURLSet
+	getXML(t, b, SITEMAP, "application/xml", &sitemap)
+
+	if len(sitemap.URLs) != 3 {
+		t.Errorf("the sitemap lists %d urls, want the home page and the 2 indexed posts", len(sitemap.URLs))
+	}
+	for _, u := range sitemap.URLs {
+		if u.Loc == "http://example.com/post/thin" {
+			t.Error("the sitemap lists a noindexed post")
+		}
+	}
+}
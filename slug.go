@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Turns whatever the author typed into a slug that's safe to route on, lowercase kebab case of a-z, 0-9 and hyphens
+func normalizeSlug(slug string) (string, error) {
+	var b strings.Builder
+	lastWasHyphen := true // Treated as true at the start so leading separators are dropped
+	for _, c := range strings.ToLower(strings.TrimSpace(slug)) {
+		switch {
+		case (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9'):
+			b.WriteRune(c)
+			lastWasHyphen = false
+		case c == ' ' || c == '-' || c == '_':
+			if !lastWasHyphen {
+				b.WriteRune('-')
+				lastWasHyphen = true
+			}
+		}
+		// Anything else (slashes, punctuation, unicode) is dropped
+	}
+
+	normalized := strings.TrimSuffix(b.String(), "-")
+	if normalized == "" {
+		return "", errors.New("the slug must contain at least one letter or number")
+	}
+	return normalized, nil
+}
+
+// Derives a slug from a post's header, appending -2, -3 etc. until exists reports it's free
+func generateSlug(header string, exists func(string) bool) string {
+	base, err := normalizeSlug(header)
+	if err != nil {
+		// The header had nothing we can put in a url, e.g. it was all punctuation
+		base = "post"
+	}
+
+	slug := base
+	for n := 2; exists(slug); n++ {
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+	return slug
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestNormalizeSlug(t *testing.T) {
+	tests := []struct {
+		slug    string
+		want    string
+		wantErr bool
+	}{
+		{slug: "hello-world", want: "hello-world"},
+		{slug: "Hello World", want: "hello-world"},
+		{slug: "  --Go_is  fun--  ", want: "go-is-fun"},
+		{slug: "what's/new?", want: "whatsnew"},
+		{slug: "café 2021", want: "caf-2021"},
+		{slug: "", wantErr: true},
+		{slug: "?!/", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := normalizeSlug(tt.slug)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("normalizeSlug(%q) error = %v, want error %v", tt.slug, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("normalizeSlug(%q) = %q, want %q", tt.slug, got, tt.want)
+		}
+	}
+}
+
+func TestInvalidSlugsAreRejected(t *testing.T) {
+	// Rejected before they get as far as the DB
+	b := newClosedDBBlog(t)
+	router := newRouter(b)
+
+	for _, req := range []*http.Request{
+		formRequest(SAVE+SAVE_ADD, url.Values{"header": {"Bad"}, "content": {"Words"}, "slug": {"!!!"}}),
+		apiRequest(http.MethodPost, API_POSTS, `{"header": "Bad", "content": "Words", "slug": "!!!"}`),
+	} {
+		if w := do(router, req); w.Code != http.StatusBadRequest {
+			t.Errorf("%s %s with an invalid slug responded %d, want %d", req.Method, req.URL.Path, w.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestGenerateSlug(t *testing.T) {
+	taken := map[string]bool{"my-post": true, "my-post-2": true}
+	exists := func(slug string) bool { return taken[slug] }
+
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "A Fresh Post!", want: "a-fresh-post"},
+		{header: "My Post", want: "my-post-3"},
+		{header: "???", want: "post"},
+	}
+	for _, tt := range tests {
+		if got := generateSlug(tt.header, exists); got != tt.want {
+			t.Errorf("generateSlug(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestBlankSlugsAreGenerated(t *testing.T) {
+	b := newFakeBlog(t, testConfig(t))
+	router := newRouter(b)
+
+	for _, want := range []string{"made-up-slug", "made-up-slug-2"} {
+		if w := do(router, formRequest(SAVE+SAVE_ADD, url.Values{"header": {"Made Up Slug!"}, "content": {"Words"}})); w.Code != http.StatusSeeOther {
+			t.Fatalf("adding a post without a slug responded %d", w.Code)
+		}
+		if !b.store.SlugExists(context.Background(), want) {
+			t.Errorf("no post was saved at %s", want)
+		}
+	}
+}
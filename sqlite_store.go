@@ -0,0 +1,821 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite" // SQLite driver, pure Go so it builds without cgo
+)
+
+// The SQLite versions of the statements in store.go, for running locally without Postgres. SQLite numbers its
+// placeholders ?1 rather than $1, has no full text search built in and stores timestamps as text, in SQLITE_TIME_FORMAT
+const (
+	// Fixed width and always UTC, so comparing the text orders them the same as the times they are
+	SQLITE_TIME_FORMAT = "2006-01-02 15:04:05.000000000"
+
+	// The whole schema, created when the DB is opened. Keep it in step with migrations/ as db/init.sql is
+	SQLITE_SCHEMA = `
+CREATE TABLE IF NOT EXISTS posts (
+	id           INTEGER PRIMARY KEY,
+	header       TEXT NOT NULL,
+	content      TEXT NOT NULL,
+	slug         TEXT UNIQUE NOT NULL,
+	author       TEXT NOT NULL DEFAULT 'Kealan Parr',
+	created_at   TEXT NOT NULL,
+	updated_at   TEXT NOT NULL,
+	published    INTEGER NOT NULL DEFAULT 0,
+	views        INTEGER NOT NULL DEFAULT 0,
+	published_at  TEXT NOT NULL,
+	deleted_at    TEXT,
+	canonical_url TEXT NOT NULL DEFAULT '',
+	no_index      INTEGER NOT NULL DEFAULT 0,
+	preview_token TEXT NOT NULL DEFAULT '',
+	announced     INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS tags (
+	id   INTEGER PRIMARY KEY,
+	name TEXT UNIQUE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS post_tags (
+	post_id INTEGER NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+	tag_id  INTEGER NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+	PRIMARY KEY (post_id, tag_id)
+);
+
+CREATE TABLE IF NOT EXISTS comments (
+	id         INTEGER PRIMARY KEY,
+	post_id    INTEGER NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+	author     TEXT NOT NULL,
+	body       TEXT NOT NULL,
+	created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS comments_post_id ON comments (post_id);
+
+CREATE TABLE IF NOT EXISTS slug_redirects (
+	old_slug TEXT PRIMARY KEY,
+	post_id  INTEGER NOT NULL REFERENCES posts (id) ON DELETE CASCADE
+);`
+
+	// The current time in the same shape as SQLITE_TIME_FORMAT. SQLite's clock only goes to the millisecond, so it's the
+	// end of the current one, or a post saved a moment ago would count as in the future until the next
+	SQLITE_NOW = "(strftime('%Y-%m-%d %H:%M:%f', 'now') || '999999')"
+	// Matches posts the public can see, published, not deleted and not scheduled for later
+	SQLITE_VISIBLE = "published AND deleted_at IS NULL AND published_at <= " + SQLITE_NOW
+
+	// Matches published posts containing the query anywhere in their header or content, ignoring case.
+	// Cruder than Postgres' full text search, but plenty for trying the blog out locally
+	SQLITE_SEARCH_MATCH = SQLITE_VISIBLE + " AND (header LIKE '%' || ?1 || '%' ESCAPE '\\' OR content LIKE '%' || ?1 || '%' ESCAPE '\\')" // ?1 is escaped with likeEscaper
+	SQLITE_TAG_MATCH    = SQLITE_VISIBLE + " AND id IN (SELECT post_tags.post_id FROM post_tags JOIN tags ON tags.id = post_tags.tag_id WHERE tags.name = ?1)"
+	SQLITE_AUTHOR_MATCH = SQLITE_VISIBLE + " AND lower(author) = lower(?1)"
+
+	SQLITE_LIST_POSTS_SQL    = "SELECT " + POST_COLUMNS + " FROM posts WHERE " + SQLITE_VISIBLE + " ORDER BY published_at DESC, id DESC;"
+	SQLITE_LIST_ALL_SQL      = "SELECT " + POST_COLUMNS + " FROM posts ORDER BY updated_at DESC, id DESC;" // Deleted posts too, so they can be restored
+	SQLITE_RECENT_POSTS_SQL  = "SELECT " + POST_COLUMNS + " FROM posts WHERE " + SQLITE_VISIBLE + " ORDER BY published_at DESC, id DESC LIMIT ?1;"
+	SQLITE_COUNT_POSTS_SQL   = "SELECT COUNT(*) FROM posts WHERE " + SQLITE_VISIBLE + ";"
+	SQLITE_PAGE_POSTS_SQL    = "SELECT " + POST_COLUMNS + " FROM posts WHERE " + SQLITE_VISIBLE + " ORDER BY published_at DESC, id DESC LIMIT ?1 OFFSET ?2;"
+	SQLITE_PAGE_OLDEST_SQL   = "SELECT " + POST_COLUMNS + " FROM posts WHERE " + SQLITE_VISIBLE + " ORDER BY published_at, id LIMIT ?1 OFFSET ?2;"
+	SQLITE_PAGE_TITLE_SQL    = "SELECT " + POST_COLUMNS + " FROM posts WHERE " + SQLITE_VISIBLE + " ORDER BY lower(header), id LIMIT ?1 OFFSET ?2;"
+	SQLITE_COUNT_SEARCH_SQL  = "SELECT COUNT(*) FROM posts WHERE " + SQLITE_SEARCH_MATCH + ";"
+	SQLITE_SEARCH_POSTS_SQL  = "SELECT " + POST_COLUMNS + " FROM posts WHERE " + SQLITE_SEARCH_MATCH + " ORDER BY published_at DESC, id DESC LIMIT ?2 OFFSET ?3;"
+	SQLITE_COUNT_TAGGED_SQL  = "SELECT COUNT(*) FROM posts WHERE " + SQLITE_TAG_MATCH + ";"
+	SQLITE_TAGGED_POSTS_SQL  = "SELECT " + POST_COLUMNS + " FROM posts WHERE " + SQLITE_TAG_MATCH + " ORDER BY published_at DESC, id DESC LIMIT ?2 OFFSET ?3;"
+	SQLITE_COUNT_AUTHOR_SQL  = "SELECT COUNT(*) FROM posts WHERE " + SQLITE_AUTHOR_MATCH + ";"
+	SQLITE_AUTHOR_POSTS_SQL  = "SELECT " + POST_COLUMNS + " FROM posts WHERE " + SQLITE_AUTHOR_MATCH + " ORDER BY published_at DESC, id DESC LIMIT ?2 OFFSET ?3;"
+	SQLITE_SUMMARIES_SQL     = "SELECT posts.header, posts.slug, COALESCE(group_concat(tags.name), '') FROM posts LEFT JOIN post_tags ON post_tags.post_id = posts.id LEFT JOIN tags ON tags.id = post_tags.tag_id WHERE " + SQLITE_VISIBLE + " GROUP BY posts.id ORDER BY posts.published_at DESC, posts.id DESC;"
+	SQLITE_ARCHIVE_POSTS_SQL = "SELECT header, slug, published_at FROM posts WHERE " + SQLITE_VISIBLE + " ORDER BY published_at DESC, id DESC;"
+	SQLITE_SITEMAP_POSTS_SQL = "SELECT slug, updated_at FROM posts WHERE " + SQLITE_VISIBLE + " AND NOT no_index ORDER BY published_at DESC, id DESC;"
+	SQLITE_EXPORT_POSTS_SQL  = "SELECT " + POST_COLUMNS + ", COALESCE((SELECT group_concat(tags.name) FROM tags JOIN post_tags ON post_tags.tag_id = tags.id WHERE post_tags.post_id = posts.id), '') FROM posts WHERE deleted_at IS NULL ORDER BY id;"
+	SQLITE_GET_POST_SQL      = "SELECT " + POST_COLUMNS + " FROM posts WHERE slug = ?1 AND deleted_at IS NULL;"
+	SQLITE_SLUG_EXISTS_SQL   = "SELECT EXISTS (SELECT 1 FROM posts WHERE slug = ?1);"
+	SQLITE_CREATE_POST_SQL   = "INSERT INTO posts (header, content, slug, author, created_at, updated_at, published, published_at, canonical_url, no_index, announced) VALUES (?1, ?2, ?3, ?4, ?5, ?5, ?6, ?7, ?8, ?9, ?6 AND ?7 < ?5) ON CONFLICT (slug) DO NOTHING;"
+	SQLITE_UPDATE_POST_SQL   = "UPDATE posts SET (header, content, author, updated_at, published_at, canonical_url, no_index) = (?1, ?2, ?3, ?5, COALESCE(?6, published_at), ?8, ?9) WHERE slug = ?4 AND deleted_at IS NULL AND (?7 IS NULL OR updated_at = ?7);"
+	SQLITE_DELETE_POST_SQL   = "UPDATE posts SET deleted_at = ?2 WHERE slug = ?1 AND deleted_at IS NULL;"
+	SQLITE_RESTORE_POST_SQL  = "UPDATE posts SET deleted_at = NULL WHERE slug = ?1 AND deleted_at IS NOT NULL;"
+	SQLITE_PURGE_POST_SQL    = "DELETE FROM posts WHERE slug = ?1 AND deleted_at IS NOT NULL;"
+	SQLITE_PUBLISH_POST_SQL  = "UPDATE posts SET (published, updated_at, published_at) = (1, ?2, CASE WHEN NOT published AND published_at = created_at THEN ?2 ELSE published_at END) WHERE slug = ?1 AND deleted_at IS NULL;"
+	SQLITE_RECORD_VIEW_SQL   = "UPDATE posts SET views = views + 1 WHERE slug = ?1 AND deleted_at IS NULL RETURNING views;"
+	SQLITE_PREVIEW_TOKEN_SQL = "UPDATE posts SET preview_token = CASE WHEN preview_token = '' THEN ?2 ELSE preview_token END WHERE slug = ?1 AND deleted_at IS NULL RETURNING preview_token;"
+	SQLITE_POST_EXISTS_SQL   = "SELECT EXISTS (SELECT 1 FROM posts WHERE slug = ?1 AND deleted_at IS NULL);"
+
+	SQLITE_STATS_SQL      = "SELECT COUNT(*), COUNT(*) FILTER (WHERE published AND published_at <= " + SQLITE_NOW + "), COUNT(*) FILTER (WHERE published AND published_at > " + SQLITE_NOW + "), COALESCE(SUM(views), 0), MAX(published_at) FILTER (WHERE published AND published_at <= " + SQLITE_NOW + ") FROM posts WHERE deleted_at IS NULL;"
+	SQLITE_NEWLY_LIVE_SQL = "UPDATE posts SET announced = 1 WHERE " + SQLITE_VISIBLE + " AND NOT announced RETURNING header, slug;"
+
+	SQLITE_POST_TAGS_SQL       = "SELECT tags.name FROM tags JOIN post_tags ON post_tags.tag_id = tags.id JOIN posts ON posts.id = post_tags.post_id WHERE posts.slug = ?1 ORDER BY tags.name;"
+	SQLITE_PAGE_TAGS_SQL       = "SELECT posts.slug, tags.name FROM tags JOIN post_tags ON post_tags.tag_id = tags.id JOIN posts ON posts.id = post_tags.post_id WHERE posts.slug IN (SELECT value FROM json_each(?1)) ORDER BY tags.name;"
+	SQLITE_CLEAR_POST_TAGS_SQL = "DELETE FROM post_tags WHERE post_id = (SELECT id FROM posts WHERE slug = ?1);"
+	SQLITE_CREATE_TAG_SQL      = "INSERT INTO tags (name) VALUES (?1) ON CONFLICT (name) DO NOTHING;"
+	SQLITE_TAG_POST_SQL        = "INSERT INTO post_tags (post_id, tag_id) SELECT posts.id, tags.id FROM posts, tags WHERE posts.slug = ?1 AND tags.name = ?2;"
+
+	SQLITE_RENAME_POST_SQL    = "UPDATE posts SET slug = ?2 WHERE slug = ?1 AND deleted_at IS NULL;"
+	SQLITE_ADD_REDIRECT_SQL   = "INSERT INTO slug_redirects (old_slug, post_id) SELECT ?1, id FROM posts WHERE slug = ?2 ON CONFLICT (old_slug) DO UPDATE SET post_id = excluded.post_id;"
+	SQLITE_CLEAR_REDIRECT_SQL = "DELETE FROM slug_redirects WHERE old_slug = ?1;"
+	SQLITE_REDIRECT_SQL       = "SELECT posts.slug FROM slug_redirects JOIN posts ON posts.id = slug_redirects.post_id WHERE slug_redirects.old_slug = ?1 AND posts.deleted_at IS NULL;"
+
+	SQLITE_POST_COMMENTS_SQL = "SELECT comments.author, comments.body, comments.created_at FROM comments JOIN posts ON posts.id = comments.post_id WHERE posts.slug = ?1 ORDER BY comments.created_at, comments.id;"
+	SQLITE_ADD_COMMENT_SQL   = "INSERT INTO comments (post_id, author, body, created_at) SELECT id, ?2, ?3, ?4 FROM posts WHERE slug = ?1 AND " + SQLITE_VISIBLE + ";"
+)
+
+// Columns added to SQLITE_SCHEMA since it was first written, so files created before them are brought up to date
+var sqliteAddedColumns = []string{
+	"ALTER TABLE posts ADD COLUMN deleted_at TEXT;",
+	"ALTER TABLE posts ADD COLUMN canonical_url TEXT NOT NULL DEFAULT '';",
+	"ALTER TABLE posts ADD COLUMN no_index INTEGER NOT NULL DEFAULT 0;",
+	"ALTER TABLE posts ADD COLUMN preview_token TEXT NOT NULL DEFAULT '';",
+	// The UPDATE only runs when the column's added, as SQLite stops at the ALTER's error once it's there
+	"ALTER TABLE posts ADD COLUMN announced INTEGER NOT NULL DEFAULT 0; UPDATE posts SET announced = 1 WHERE " + SQLITE_VISIBLE + ";",
+}
+
+// Escapes LIKE's wildcards in a search, so searching for "100%" or "snake_case" only matches that text
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// The SQLite version of pagePostsSQL
+var sqlitePagePostsSQL = map[PostSort]string{
+	SORT_NEWEST: SQLITE_PAGE_POSTS_SQL,
+	SORT_OLDEST: SQLITE_PAGE_OLDEST_SQL,
+	SORT_TITLE:  SQLITE_PAGE_TITLE_SQL,
+}
+
+// The PostStore backed by a SQLite file, for local development. It behaves like PGPostStore bar searching more crudely
+type SQLitePostStore struct {
+	db      *sql.DB
+	perPage int
+}
+
+func NewSQLitePostStore(db *sql.DB, perPage int) *SQLitePostStore {
+	return &SQLitePostStore{db: db, perPage: perPage}
+}
+
+// Opens the SQLite file at path, creating it and the schema if they don't exist yet
+func openSQLite(ctx context.Context, path string) (*sql.DB, error) {
+	db := sql.OpenDB(sqliteConnector{path})
+	// SQLite only lets one connection write at a time, and foreign keys are switched on per connection,
+	// so holding a single one open avoids both "database is locked" errors and tags outliving their posts
+	db.SetMaxOpenConns(1)
+	if _, err := db.ExecContext(ctx, "PRAGMA foreign_keys = ON;"); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if _, err := db.ExecContext(ctx, SQLITE_SCHEMA); err != nil {
+		db.Close()
+		return nil, err
+	}
+	for _, column := range sqliteAddedColumns {
+		// SQLite can't ADD COLUMN IF NOT EXISTS, so one that's already there is the only error to expect
+		if _, err := db.ExecContext(ctx, column); err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+			db.Close()
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// Opens connections to the SQLite file at path, without the driver's support for contexts. When a query's context is
+// done the driver interrupts whatever its connection is running, which can happen after the query's finished and
+// so interrupts the next one. Without it database/sql only checks the context before each query and while it waits
+// for the connection, so a query that's started always runs to the end
+type sqliteConnector struct {
+	path string
+}
+
+func (c sqliteConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Driver().Open(c.path)
+	if err != nil {
+		return nil, err
+	}
+	return uninterruptedConn{conn.(sqliteConn)}, nil
+}
+
+func (c sqliteConnector) Driver() driver.Driver {
+	return &sqlite.Driver{}
+}
+
+// The methods of the driver's connections that run queries with context.Background
+type sqliteConn interface {
+	driver.Conn
+	driver.Execer
+	driver.Queryer
+}
+
+// One of the driver's connections with only the methods in sqliteConn, hiding the ones that take a context
+type uninterruptedConn struct {
+	sqliteConn
+}
+
+// Checks the SQLite file can still be read, for the health check
+func (s *SQLitePostStore) Ping(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, "SELECT 1;")
+	return err
+}
+
+// Loads every published post newest first, or with includeDrafts every post at all, most recently edited first
+func (s *SQLitePostStore) List(ctx context.Context, includeDrafts bool) ([]Post, error) {
+	query := SQLITE_LIST_POSTS_SQL
+	if includeDrafts {
+		query = SQLITE_LIST_ALL_SQL
+	}
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return sqliteScanPosts(rows)
+}
+
+// Loads the newest published posts, at most limit of them
+func (s *SQLitePostStore) Recent(ctx context.Context, limit int) ([]Post, error) {
+	rows, err := s.db.QueryContext(ctx, SQLITE_RECENT_POSTS_SQL, limit)
+	if err != nil {
+		return nil, err
+	}
+	return sqliteScanPosts(rows)
+}
+
+// Loads a single page of perPage posts in sort order, clamping page into the range of pages that actually exist
+func (s *SQLitePostStore) Page(ctx context.Context, page, perPage int, sort PostSort) (HomePage, error) {
+	query, ok := sqlitePagePostsSQL[sort]
+	if !ok {
+		return HomePage{}, fmt.Errorf("unknown sort %q", sort)
+	}
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, SQLITE_COUNT_POSTS_SQL).Scan(&total); err != nil {
+		return HomePage{}, err
+	}
+
+	homePage := paginate(page, total, perPage)
+	homePage.Sort = sort
+	rows, err := s.db.QueryContext(ctx, query, perPage, homePage.offset(perPage))
+	if err != nil {
+		return HomePage{}, err
+	}
+	if homePage.Posts, err = sqliteScanPosts(rows); err != nil {
+		return HomePage{}, err
+	}
+	return homePage, s.pageTags(ctx, homePage.Posts)
+}
+
+// Loads a single page of the posts containing query, newest first as there's no ranking without full text search
+func (s *SQLitePostStore) Search(ctx context.Context, query string, page int) (SearchPage, error) {
+	var total int
+	pattern := likeEscaper.Replace(query)
+	if err := s.db.QueryRowContext(ctx, SQLITE_COUNT_SEARCH_SQL, pattern).Scan(&total); err != nil {
+		return SearchPage{}, err
+	}
+
+	results := SearchPage{HomePage: paginate(page, total, s.perPage), Query: query}
+	rows, err := s.db.QueryContext(ctx, SQLITE_SEARCH_POSTS_SQL, pattern, s.perPage, results.offset(s.perPage))
+	if err != nil {
+		return SearchPage{}, err
+	}
+	results.Posts, err = sqliteScanPosts(rows)
+	return results, err
+}
+
+// Loads a single page of the posts tagged name, newest first
+func (s *SQLitePostStore) Tagged(ctx context.Context, name string, page int) (TagPage, error) {
+	var total int
+	if err := s.db.QueryRowContext(ctx, SQLITE_COUNT_TAGGED_SQL, name).Scan(&total); err != nil {
+		return TagPage{}, err
+	}
+
+	tagPage := TagPage{HomePage: paginate(page, total, s.perPage), Tag: name}
+	rows, err := s.db.QueryContext(ctx, SQLITE_TAGGED_POSTS_SQL, name, s.perPage, tagPage.offset(s.perPage))
+	if err != nil {
+		return TagPage{}, err
+	}
+	tagPage.Posts, err = sqliteScanPosts(rows)
+	return tagPage, err
+}
+
+// Loads a single page of the posts written by name, newest first
+func (s *SQLitePostStore) ByAuthor(ctx context.Context, name string, page int) (AuthorPage, error) {
+	var total int
+	if err := s.db.QueryRowContext(ctx, SQLITE_COUNT_AUTHOR_SQL, name).Scan(&total); err != nil {
+		return AuthorPage{}, err
+	}
+
+	authorPage := AuthorPage{HomePage: paginate(page, total, s.perPage), Author: name}
+	rows, err := s.db.QueryContext(ctx, SQLITE_AUTHOR_POSTS_SQL, name, s.perPage, authorPage.offset(s.perPage))
+	if err != nil {
+		return AuthorPage{}, err
+	}
+	authorPage.Posts, err = sqliteScanPosts(rows)
+	return authorPage, err
+}
+
+// Loads just the slug and updated_at of every published post
+func (s *SQLitePostStore) Sitemap(ctx context.Context) ([]Post, error) {
+	rows, err := s.db.QueryContext(ctx, SQLITE_SITEMAP_POSTS_SQL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []Post{}
+	for rows.Next() {
+		var p Post
+		if err := rows.Scan(&p.Slug, sqliteTime{&p.UpdatedAt}); err != nil {
+			return nil, err
+		}
+		posts = append(posts, p)
+	}
+	return posts, rows.Err()
+}
+
+// Loads the header, slug and publish date of every published post, newest first
+func (s *SQLitePostStore) Archive(ctx context.Context) ([]Post, error) {
+	rows, err := s.db.QueryContext(ctx, SQLITE_ARCHIVE_POSTS_SQL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []Post{}
+	for rows.Next() {
+		var p Post
+		if err := rows.Scan(&p.Header, &p.Slug, sqliteTime{&p.PublishedAt}); err != nil {
+			return nil, err
+		}
+		posts = append(posts, p)
+	}
+	return posts, rows.Err()
+}
+
+// Loads the header, slug and tags of every published post, newest first
+func (s *SQLitePostStore) Summaries(ctx context.Context) ([]Post, error) {
+	rows, err := s.db.QueryContext(ctx, SQLITE_SUMMARIES_SQL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []Post{}
+	for rows.Next() {
+		var p Post
+		var tagNames string // Comma separated, tag names are normalized so never contain one themselves
+		if err := rows.Scan(&p.Header, &p.Slug, &tagNames); err != nil {
+			return nil, err
+		}
+		for _, name := range strings.Split(tagNames, ",") {
+			if name != "" {
+				p.Tags = append(p.Tags, Tag{Name: name})
+			}
+		}
+		posts = append(posts, p)
+	}
+	return posts, rows.Err()
+}
+
+// Calls fn with every post that isn't deleted, drafts included, in the order they were created and with their tags.
+// Posts are read a row at a time so a large blog is never all in memory, fn returning an error stops it
+func (s *SQLitePostStore) Export(ctx context.Context, fn func(Post) error) error {
+	rows, err := s.db.QueryContext(ctx, SQLITE_EXPORT_POSTS_SQL)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p Post
+		var tagNames string // Comma separated, tag names are normalized so never contain one themselves
+		if err := rows.Scan(&p.Header, &p.Content, &p.Slug, &p.Author, sqliteTime{&p.CreatedAt}, sqliteTime{&p.UpdatedAt}, &p.Published, &p.Views, sqliteTime{&p.PublishedAt}, sqliteNullTime{&p.DeletedAt}, &p.CanonicalURL, &p.NoIndex, &p.PreviewToken, &tagNames); err != nil {
+			return err
+		}
+		p.Tags = []Tag{}
+		for _, name := range strings.Split(tagNames, ",") {
+			if name != "" {
+				p.Tags = append(p.Tags, Tag{Name: name})
+			}
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Loads a single post, drafts included, found is false if no row matched slug
+func (s *SQLitePostStore) Get(ctx context.Context, slug string) (p Post, found bool, err error) {
+	p, err = sqliteScanPost(s.db.QueryRowContext(ctx, SQLITE_GET_POST_SQL, slug))
+	if err == sql.ErrNoRows {
+		return Post{}, false, nil
+	}
+	if err != nil {
+		return Post{}, false, err
+	}
+
+	p.Tags, err = s.tags(ctx, p.Slug)
+	if err != nil {
+		return Post{}, false, err
+	}
+	return p, true, nil
+}
+
+// Reports whether a post already uses slug, if we can't tell it's assumed free and the insert will catch any conflict
+func (s *SQLitePostStore) SlugExists(ctx context.Context, slug string) bool {
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, SQLITE_SLUG_EXISTS_SQL, slug).Scan(&exists); err != nil {
+		logf(ctx, "Failed to check if slug %q exists: %v", slug, err)
+		return false
+	}
+	return exists
+}
+
+// Saves a new post and its tags, published_at is now unless post.PublishedAt is set. errSlugTaken if another post already has its slug
+func (s *SQLitePostStore) Create(ctx context.Context, post Post) error {
+	now := time.Now()
+	publishedAt := post.PublishedAt
+	if publishedAt.IsZero() {
+		publishedAt = now
+	}
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, SQLITE_CREATE_POST_SQL, post.Header, post.Content, post.Slug, post.Author, sqliteTimeValue(now), post.Published, sqliteTimeValue(publishedAt), post.CanonicalURL, post.NoIndex)
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			// Only ON CONFLICT skipping the insert leaves it touching nothing
+			return errSlugTaken
+		}
+		return s.setTags(ctx, tx, post)
+	})
+}
+
+// Replaces the header, content and tags of the post with post.Slug, and its published_at if post.PublishedAt is set.
+// If post.UpdatedAt is set it must still be the post's updated_at, otherwise it's errPostConflict
+func (s *SQLitePostStore) Update(ctx context.Context, post Post) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		return s.update(ctx, tx, post)
+	})
+}
+
+// Moves the post at slug to post.Slug, then saves the rest of post over it like Update. Either both happen or neither
+// does, so a conflicting edit can't leave the post renamed with its old content. errSlugTaken if post.Slug is in use
+func (s *SQLitePostStore) RenameAndUpdate(ctx context.Context, slug string, post Post) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := s.rename(ctx, tx, slug, post.Slug); err != nil {
+			return err
+		}
+		return s.update(ctx, tx, post)
+	})
+}
+
+// Deletes the post, hiding it everywhere but the admin dashboard where it can be restored or purged
+func (s *SQLitePostStore) Delete(ctx context.Context, slug string) error {
+	result, err := s.db.ExecContext(ctx, SQLITE_DELETE_POST_SQL, slug, sqliteTimeValue(time.Now()))
+	if err != nil {
+		return err
+	}
+	return expectRow(result)
+}
+
+// Brings back a deleted post as it was when it was deleted, errPostNotFound if it isn't deleted
+func (s *SQLitePostStore) Restore(ctx context.Context, slug string) error {
+	result, err := s.db.ExecContext(ctx, SQLITE_RESTORE_POST_SQL, slug)
+	if err != nil {
+		return err
+	}
+	return expectRow(result)
+}
+
+// Permanently removes a deleted post, its tags and comments go with it through their foreign keys
+func (s *SQLitePostStore) Purge(ctx context.Context, slug string) error {
+	result, err := s.db.ExecContext(ctx, SQLITE_PURGE_POST_SQL, slug)
+	if err != nil {
+		return err
+	}
+	return expectRow(result)
+}
+
+// Makes a draft visible on the homepage, feeds and its own page, dating it now if it was Undated
+func (s *SQLitePostStore) Publish(ctx context.Context, slug string) error {
+	result, err := s.db.ExecContext(ctx, SQLITE_PUBLISH_POST_SQL, slug, sqliteTimeValue(time.Now()))
+	if err != nil {
+		return err
+	}
+	return expectRow(result)
+}
+
+// The slug the post that used to be at oldSlug has now, found is false if no post that isn't deleted ever had it
+func (s *SQLitePostStore) Redirect(ctx context.Context, oldSlug string) (slug string, found bool, err error) {
+	err = s.db.QueryRowContext(ctx, SQLITE_REDIRECT_SQL, oldSlug).Scan(&slug)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	return slug, err == nil, err
+}
+
+// The token that lets anyone preview the post before it's live, generating one the first time it's asked for
+func (s *SQLitePostStore) PreviewToken(ctx context.Context, slug string) (string, error) {
+	token, err := newPreviewToken()
+	if err != nil {
+		return "", err
+	}
+	err = s.db.QueryRowContext(ctx, SQLITE_PREVIEW_TOKEN_SQL, slug, token).Scan(&token)
+	if err == sql.ErrNoRows {
+		return "", errPostNotFound
+	}
+	return token, err
+}
+
+// Counts a view of the post, returning its new total
+func (s *SQLitePostStore) RecordView(ctx context.Context, slug string) (int, error) {
+	var views int
+	err := s.db.QueryRowContext(ctx, SQLITE_RECORD_VIEW_SQL, slug).Scan(&views)
+	if err == sql.ErrNoRows {
+		return 0, errPostNotFound
+	}
+	return views, err
+}
+
+// Counts the posts and their views
+func (s *SQLitePostStore) Stats(ctx context.Context) (PostStats, error) {
+	var stats PostStats
+	err := s.db.QueryRowContext(ctx, SQLITE_STATS_SQL).Scan(&stats.Posts, &stats.Published, &stats.Scheduled, &stats.Views, sqliteNullTime{&stats.NewestPost})
+	stats.Drafts = stats.Posts - stats.Published - stats.Scheduled
+	return stats, err
+}
+
+// Loads the header and slug of the posts that have gone live since they were last asked for, marking them announced
+func (s *SQLitePostStore) NewlyLive(ctx context.Context) ([]Post, error) {
+	rows, err := s.db.QueryContext(ctx, SQLITE_NEWLY_LIVE_SQL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []Post{}
+	for rows.Next() {
+		var p Post
+		if err := rows.Scan(&p.Header, &p.Slug); err != nil {
+			return nil, err
+		}
+		posts = append(posts, p)
+	}
+	return posts, rows.Err()
+}
+
+// Loads the comments on a post, oldest first
+func (s *SQLitePostStore) Comments(ctx context.Context, slug string) ([]Comment, error) {
+	rows, err := s.db.QueryContext(ctx, SQLITE_POST_COMMENTS_SQL, slug)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []Comment{}
+	for rows.Next() {
+		var c Comment
+		if err := rows.Scan(&c.Author, &c.Body, sqliteTime{&c.CreatedAt}); err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
+
+// Saves a comment on a published post, errPostNotFound if there's no such post or it's still a draft
+func (s *SQLitePostStore) AddComment(ctx context.Context, slug string, comment Comment) error {
+	result, err := s.db.ExecContext(ctx, SQLITE_ADD_COMMENT_SQL, slug, comment.Author, comment.Body, sqliteTimeValue(time.Now()))
+	if err != nil {
+		return err
+	}
+	return expectRow(result)
+}
+
+// Runs fn in a transaction, committing only if it succeeds
+func (s *SQLitePostStore) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Loads the tags on a single post, alphabetically
+func (s *SQLitePostStore) tags(ctx context.Context, slug string) ([]Tag, error) {
+	rows, err := s.db.QueryContext(ctx, SQLITE_POST_TAGS_SQL, slug)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []Tag{}
+	for rows.Next() {
+		var tag Tag
+		if err := rows.Scan(&tag.Name); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// Fills in the tags on every post in posts with a single query, rather than a query for each post.
+// SQLite has no arrays, so the slugs are passed in as a JSON one
+func (s *SQLitePostStore) pageTags(ctx context.Context, posts []Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	slugs := make([]string, len(posts))
+	bySlug := make(map[string]*Post, len(posts))
+	for i := range posts {
+		posts[i].Tags = []Tag{}
+		slugs[i] = posts[i].Slug
+		bySlug[posts[i].Slug] = &posts[i]
+	}
+	slugsJSON, err := json.Marshal(slugs)
+	if err != nil {
+		return err
+	}
+
+	rows, err := s.db.QueryContext(ctx, SQLITE_PAGE_TAGS_SQL, string(slugsJSON))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var slug string
+		var tag Tag
+		if err := rows.Scan(&slug, &tag.Name); err != nil {
+			return err
+		}
+		bySlug[slug].Tags = append(bySlug[slug].Tags, tag)
+	}
+	return rows.Err()
+}
+
+// Replaces the tags on post with post.Tags, creating any tags that don't exist yet
+func (s *SQLitePostStore) setTags(ctx context.Context, tx *sql.Tx, post Post) error {
+	if _, err := tx.ExecContext(ctx, SQLITE_CLEAR_POST_TAGS_SQL, post.Slug); err != nil {
+		return err
+	}
+
+	for _, tag := range post.Tags {
+		if _, err := tx.ExecContext(ctx, SQLITE_CREATE_TAG_SQL, tag.Name); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, SQLITE_TAG_POST_SQL, post.Slug, tag.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// errPostNotFound if a statement changing a single post didn't touch any rows
+func expectRow(result sql.Result) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return errPostNotFound
+	}
+	return nil
+}
+
+// Update's half of Update and RenameAndUpdate
+func (s *SQLitePostStore) update(ctx context.Context, tx *sql.Tx, post Post) error {
+	var publishedAt interface{} // NULL keeps the published_at it has
+	if !post.PublishedAt.IsZero() {
+		publishedAt = sqliteTimeValue(post.PublishedAt)
+	}
+	var updatedAt interface{} // NULL saves over whatever's there
+	if !post.UpdatedAt.IsZero() {
+		updatedAt = sqliteTimeValue(post.UpdatedAt)
+	}
+	result, err := tx.ExecContext(ctx, SQLITE_UPDATE_POST_SQL, post.Header, post.Content, post.Author, post.Slug, sqliteTimeValue(time.Now()), publishedAt, updatedAt, post.CanonicalURL, post.NoIndex)
+	if err != nil {
+		return err
+	}
+	if err := expectRow(result); err != nil {
+		if !errors.Is(err, errPostNotFound) || updatedAt == nil {
+			return err
+		}
+		var exists bool
+		if err := tx.QueryRowContext(ctx, SQLITE_POST_EXISTS_SQL, post.Slug).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			return errPostConflict
+		}
+		return err
+	}
+	return s.setTags(ctx, tx, post)
+}
+
+// Moves the post at slug to newSlug, remembering slug so links to it can be redirected. errSlugTaken if newSlug is
+// in use. It leaves updated_at alone, as the update that follows it bumps it
+func (s *SQLitePostStore) rename(ctx context.Context, tx *sql.Tx, slug, newSlug string) error {
+	var taken bool
+	if err := tx.QueryRowContext(ctx, SQLITE_SLUG_EXISTS_SQL, newSlug).Scan(&taken); err != nil {
+		return err
+	}
+	if taken {
+		return errSlugTaken
+	}
+	result, err := tx.ExecContext(ctx, SQLITE_RENAME_POST_SQL, slug, newSlug)
+	if err != nil {
+		return err
+	}
+	if err := expectRow(result); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, SQLITE_CLEAR_REDIRECT_SQL, newSlug); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, SQLITE_ADD_REDIRECT_SQL, slug, newSlug)
+	return err
+}
+
+// Reads every row of a query selecting POST_COLUMNS into Posts, closing rows when done
+func sqliteScanPosts(rows *sql.Rows) ([]Post, error) {
+	defer rows.Close()
+
+	posts := []Post{}
+	for rows.Next() {
+		p, err := sqliteScanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, p)
+	}
+	return posts, rows.Err()
+}
+
+// Scans a single row selecting POST_COLUMNS, works for both QueryRow and each row of Query
+func sqliteScanPost(row interface {
+	Scan(dest ...interface{}) error
+}) (Post, error) {
+	var p Post
+	err := row.Scan(&p.Header, &p.Content, &p.Slug, &p.Author, sqliteTime{&p.CreatedAt}, sqliteTime{&p.UpdatedAt}, &p.Published, &p.Views, sqliteTime{&p.PublishedAt}, sqliteNullTime{&p.DeletedAt}, &p.CanonicalURL, &p.NoIndex, &p.PreviewToken)
+	return p, err
+}
+
+// A time as it's stored in SQLite
+func sqliteTimeValue(t time.Time) string {
+	return t.UTC().Format(SQLITE_TIME_FORMAT)
+}
+
+// Scans a timestamp stored in SQLITE_TIME_FORMAT into t
+type sqliteTime struct {
+	t *time.Time
+}
+
+func (s sqliteTime) Scan(value interface{}) error {
+	var raw string
+	switch v := value.(type) {
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	case time.Time:
+		*s.t = v
+		return nil
+	default:
+		return fmt.Errorf("can't scan %T into a time", value)
+	}
+
+	t, err := time.ParseInLocation(SQLITE_TIME_FORMAT, raw, time.UTC)
+	if err != nil {
+		return err
+	}
+	*s.t = t
+	return nil
+}
+
+// Scans a timestamp stored in SQLITE_TIME_FORMAT that may be NULL into t, leaving it nil for NULL
+type sqliteNullTime struct {
+	t **time.Time
+}
+
+func (s sqliteNullTime) Scan(value interface{}) error {
+	if value == nil {
+		*s.t = nil
+		return nil
+	}
+	var t time.Time
+	if err := (sqliteTime{&t}).Scan(value); err != nil {
+		return err
+	}
+	*s.t = &t
+	return nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// SQLite searches with LIKE, so the wildcards in a query have to be matched as the text they are
+func TestSQLiteSearchEscapesLike(t *testing.T) {
+	store := NewSQLitePostStore(openTestSQLite(t, testConfig(t).DatabaseURL), DEFAULT_POSTS_PER_PAGE)
+	createPost(t, store, Post{Header: "Going 100% in", Content: "Words", Slug: "percent", Author: "Tester", Published: true})
+	createPost(t, store, Post{Header: "Going 1000 in", Content: "Words", Slug: "thousand", Author: "Tester", Published: true})
+	createPost(t, store, Post{Header: "Naming", Content: "snake_case or camelCase", Slug: "underscore", Author: "Tester", Published: true})
+	createPost(t, store, Post{Header: "Naming again", Content: "snakeXcase", Slug: "no-underscore", Author: "Tester", Published: true})
+	createPost(t, store, Post{Header: "Windows", Content: `C:\Users`, Slug: "backslash", Author: "Tester", Published: true})
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "100%", want: []string{"percent"}},
+		{query: "snake_case", want: []string{"underscore"}},
+		{query: `C:\Users`, want: []string{"backslash"}},
+		// Unescaped these would match every post
+		{query: "%", want: []string{"percent"}},
+		{query: "_", want: []string{"underscore"}},
+	}
+	for _, tt := range tests {
+		results, err := store.Search(context.Background(), tt.query, 1)
+		if err != nil {
+			t.Fatalf("Search(%q): %v", tt.query, err)
+		}
+		if got := slugs(results.Posts); !reflect.DeepEqual(got, tt.want) && !(len(got) == 0 && len(tt.want) == 0) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+// Opens connections like sqliteConnector, counting every statement they run in queries
+type countingConnector struct {
+	sqliteConnector
+	queries *int64
+}
+
+func (c countingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.sqliteConnector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return countingConn{conn.(uninterruptedConn), c.queries}, nil
+}
+
+type countingConn struct {
+	uninterruptedConn
+	queries *int64
+}
+
+func (c countingConn) Query(query string, args []driver.Value) (driver.Rows, error) {
+	atomic.AddInt64(c.queries, 1)
+	return c.uninterruptedConn.Query(query, args)
+}
+
+func (c countingConn) Exec(query string, args []driver.Value) (driver.Result, error) {
+	atomic.AddInt64(c.queries, 1)
+	return c.uninterruptedConn.Exec(query, args)
+}
+
+// How many queries serving the home page takes with n tagged posts on it
+func homePageQueries(t *testing.T, n int) int64 {
+	t.Helper()
+	config := testConfig(t)
+	openTestSQLite(t, config.DatabaseURL) // Creates the schema
+	var queries int64
+	db := sql.OpenDB(countingConnector{sqliteConnector{config.DatabaseURL}, &queries})
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	b := NewBlog(config, NewSQLitePostStore(db, config.PostsPerPage))
+	t.Cleanup(b.Close)
+	b.markReady()
+	for i := 0; i < n; i++ {
+		slug := fmt.Sprintf("tagged-%d", i)
+		createPost(t, b.store, Post{Header: "Tagged", Content: "Words", Slug: slug, Author: "Tester", Published: true,
+			Tags: []Tag{{Name: "go"}, {Name: slug}}})
+	}
+
+	atomic.StoreInt64(&queries, 0)
+	w := do(newRouter(b), httptest.NewRequest(http.MethodGet, HOME, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s responded %d, want %d", HOME, w.Code, http.StatusOK)
+	}
+	// Each post's tags should be shown, not just loaded
+	for i := 0; i < n; i++ {
+		if want := fmt.Sprintf(`<a href="/tag/tagged-%d/">tagged-%d</a>`, i, i); !strings.Contains(w.Body.String(), want) {
+			t.Errorf("GET %s doesn't show the tag %s", HOME, want)
+		}
+	}
+	return atomic.LoadInt64(&queries)
+}
+
+// The home page loads every post's tags at once, rather than a query for each post
+func TestHomePageQueriesDontGrowWithPosts(t *testing.T) {
+	one := homePageQueries(t, 1)
+	if one == 0 {
+		t.Fatalf("GET %s ran no queries, so they aren't being counted", HOME)
+	}
+	for _, n := range []int{2, 5, DEFAULT_POSTS_PER_PAGE} {
+		if got := homePageQueries(t, n); got != one {
+			t.Errorf("GET %s with %d posts ran %d queries, want the %d it ran with 1", HOME, n, got, one)
+		}
+	}
+}
+
+// Which posts have been announced is kept in the DB, so a restart neither announces a post twice nor forgets one
+func TestNewlyLiveSurvivesRestarts(t *testing.T) {
+	ctx := context.Background()
+	path := testConfig(t).DatabaseURL
+	db := openTestSQLite(t, path)
+	store := NewSQLitePostStore(db, DEFAULT_POSTS_PER_PAGE)
+	createLivePost(t, store, "announced")
+	if got, want := newlyLive(t, store, "announced"), []string{"announced"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("NewlyLive = %v, want %v", got, want)
+	}
+	// Goes live just before the blog goes down, with nobody told yet
+	createPost(t, store, Post{Header: "Missed", Content: "Words", Slug: "missed", Author: "Tester"})
+	if err := store.Publish(ctx, "missed"); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	db.Close()
+
+	restarted := NewSQLitePostStore(openTestSQLite(t, path), DEFAULT_POSTS_PER_PAGE)
+	if got, want := newlyLive(t, restarted, "announced", "missed"), []string{"missed"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("NewlyLive after a restart = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"log"
+	"net/http"
+	"strings"
+)
+
+const STATIC_CACHE_CONTROL = "public, max-age=86400" // Assets only change on a deploy, so browsers can hold onto them for a day
+
+//go:embed static
+var staticFiles embed.FS // Bundled into the binary along with the views
+
+// Serves the embedded static/ directory under /static/, without directory listings
+func (b *Blog) staticHandler() http.Handler {
+	root, err := fs.Sub(staticFiles, "static")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fileServer := http.StripPrefix(STATIC, http.FileServer(http.FS(root)))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// fs.FS already refuses paths that escape its root, this just turns those and directories into a plain 404
+		if strings.HasSuffix(r.URL.Path, "/") || strings.Contains(r.URL.Path, "..") {
+			b.notFoundHandler(w, r)
+			return
+		}
+		w.Header().Set("Cache-Control", STATIC_CACHE_CONTROL)
+		fileServer.ServeHTTP(w, r)
+	})
+}
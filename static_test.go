@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStaticFiles(t *testing.T) {
+	router := newRouter(newFakeBlog(t, testConfig(t)))
+
+	w := do(router, httptest.NewRequest(http.MethodGet, STATIC+"style.css", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %sstyle.css responded %d, want %d", STATIC, w.Code, http.StatusOK)
+	}
+	if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/css") {
+		t.Errorf("GET %sstyle.css has Content-Type %q, want text/css", STATIC, got)
+	}
+	if got := w.Header().Get("Cache-Control"); got != STATIC_CACHE_CONTROL {
+		t.Errorf("GET %sstyle.css has Cache-Control %q, want %q", STATIC, got, STATIC_CACHE_CONTROL)
+	}
+
+	for _, path := range []string{STATIC, STATIC + "missing.css"} {
+		if w := do(router, httptest.NewRequest(http.MethodGet, path, nil)); w.Code != http.StatusNotFound {
+			t.Errorf("GET %s responded %d, want %d", path, w.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestStaticFilesStayInStatic(t *testing.T) {
+	b := newTestBlog(t, testConfig(t))
+	router := newRouter(b)
+
+	for _, path := range []string{STATIC + "../main.go", STATIC + "..%2fmain.go", STATIC + "%2e%2e/views/home.html", STATIC + "..\\main.go"} {
+		// The mux cleans the path and redirects before the static handler sees it, so try the handler on its own as well
+		for _, h := range []http.Handler{router, b.staticHandler()} {
+			w := do(h, httptest.NewRequest(http.MethodGet, path, nil))
+			if w.Code == http.StatusOK || strings.Contains(w.Body.String(), "package main") || strings.Contains(w.Body.String(), "{{") {
+				t.Errorf("GET %s responded %d with something from outside static/", path, w.Code)
+			}
+		}
+		if w := do(b.staticHandler(), httptest.NewRequest(http.MethodGet, path, nil)); w.Code != http.StatusNotFound {
+			t.Errorf("GET %s from the static handler responded %d, want %d", path, w.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestSlugScriptIsServed(t *testing.T) {
+	b := newFakeBlog(t, testConfig(t))
+	createLivePost(t, b.store, "live")
+	router := newRouter(b)
+
+	for _, path := range []string{NEW, EDIT + "live"} {
+		if body := do(router, adminRequest(http.MethodGet, path, nil)).Body.String(); !strings.Contains(body, `<script src="/static/slug.js">`) {
+			t.Errorf("GET %s doesn't load the slug script:\n%s", path, body)
+		}
+	}
+	// So the post's own slug isn't reported as taken
+	if body := do(router, adminRequest(http.MethodGet, EDIT+"live", nil)).Body.String(); !strings.Contains(body, `data-exclude="live"`) {
+		t.Errorf("GET %slive doesn't have the slug check exclude the post:\n%s", EDIT, body)
+	}
+	w := do(router, httptest.NewRequest(http.MethodGet, STATIC+"slug.js", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %sslug.js responded %d, want %d", STATIC, w.Code, http.StatusOK)
+	}
+	if got := w.Header().Get("Content-Type"); !strings.Contains(got, "javascript") {
+		t.Errorf("GET %sslug.js has Content-Type %q, want JavaScript", STATIC, got)
+	}
+}
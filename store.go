@@ -0,0 +1,684 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"         // SQL driver
+	"github.com/jackc/pgx/v4/pgxpool" // SQL connection pool
+)
+
+// Every statement the blog runs against posts. pgx prepares a statement the first time a connection
+// runs it and reuses that from then on, so keeping each one a single fixed string means it's only
+// parsed and planned once per connection rather than on every request
+const (
+	// Every query loading a Post selects these, in the order scanPost scans them
+	POST_COLUMNS = "header, content, slug, author, created_at, updated_at, published, views, published_at, deleted_at, canonical_url, no_index, preview_token"
+
+	// Matches posts the public can see, published, not deleted and not scheduled for later
+	VISIBLE = "published AND deleted_at IS NULL AND published_at <= now()"
+
+	// Matches published posts whose header or content contain every word of the query, stemmed so "running" finds "run"
+	SEARCH_MATCH = VISIBLE + " AND to_tsvector('english', header || ' ' || content) @@ plainto_tsquery('english', $1)"
+	// Matches published posts tagged with $1
+	TAG_MATCH = VISIBLE + " AND id IN (SELECT post_tags.post_id FROM post_tags JOIN tags ON tags.id = post_tags.tag_id WHERE tags.name = $1)"
+	// Matches published posts written by $1, ignoring case as it comes from whatever the url was typed as
+	AUTHOR_MATCH = VISIBLE + " AND lower(author) = lower($1)"
+
+	LIST_POSTS_SQL    = "SELECT " + POST_COLUMNS + " FROM posts WHERE " + VISIBLE + " ORDER BY published_at DESC, id DESC;"
+	LIST_ALL_SQL      = "SELECT " + POST_COLUMNS + " FROM posts ORDER BY updated_at DESC, id DESC;" // Deleted posts too, so they can be restored
+	RECENT_POSTS_SQL  = "SELECT " + POST_COLUMNS + " FROM posts WHERE " + VISIBLE + " ORDER BY published_at DESC, id DESC LIMIT $1;"
+	COUNT_POSTS_SQL   = "SELECT COUNT(*) FROM posts WHERE " + VISIBLE + ";"
+	PAGE_POSTS_SQL    = "SELECT " + POST_COLUMNS + " FROM posts WHERE " + VISIBLE + " ORDER BY published_at DESC, id DESC LIMIT $1 OFFSET $2;"
+	PAGE_OLDEST_SQL   = "SELECT " + POST_COLUMNS + " FROM posts WHERE " + VISIBLE + " ORDER BY published_at, id LIMIT $1 OFFSET $2;"
+	PAGE_TITLE_SQL    = "SELECT " + POST_COLUMNS + " FROM posts WHERE " + VISIBLE + " ORDER BY lower(header), id LIMIT $1 OFFSET $2;"
+	COUNT_SEARCH_SQL  = "SELECT COUNT(*) FROM posts WHERE " + SEARCH_MATCH + ";"
+	SEARCH_POSTS_SQL  = "SELECT " + POST_COLUMNS + " FROM posts WHERE " + SEARCH_MATCH + " ORDER BY ts_rank(to_tsvector('english', header || ' ' || content), plainto_tsquery('english', $1)) DESC, published_at DESC LIMIT $2 OFFSET $3;"
+	COUNT_TAGGED_SQL  = "SELECT COUNT(*) FROM posts WHERE " + TAG_MATCH + ";"
+	TAGGED_POSTS_SQL  = "SELECT " + POST_COLUMNS + " FROM posts WHERE " + TAG_MATCH + " ORDER BY published_at DESC, id DESC LIMIT $2 OFFSET $3;"
+	COUNT_AUTHOR_SQL  = "SELECT COUNT(*) FROM posts WHERE " + AUTHOR_MATCH + ";"
+	AUTHOR_POSTS_SQL  = "SELECT " + POST_COLUMNS + " FROM posts WHERE " + AUTHOR_MATCH + " ORDER BY published_at DESC, id DESC LIMIT $2 OFFSET $3;"
+	SUMMARIES_SQL     = "SELECT posts.header, posts.slug, COALESCE(array_agg(tags.name) FILTER (WHERE tags.name IS NOT NULL), '{}') FROM posts LEFT JOIN post_tags ON post_tags.post_id = posts.id LEFT JOIN tags ON tags.id = post_tags.tag_id WHERE " + VISIBLE + " GROUP BY posts.id ORDER BY posts.published_at DESC, posts.id DESC;"
+	ARCHIVE_POSTS_SQL = "SELECT header, slug, published_at FROM posts WHERE " + VISIBLE + " ORDER BY published_at DESC, id DESC;"
+	SITEMAP_POSTS_SQL = "SELECT slug, updated_at FROM posts WHERE " + VISIBLE + " AND NOT no_index ORDER BY published_at DESC, id DESC;"
+	EXPORT_POSTS_SQL  = "SELECT " + POST_COLUMNS + ", COALESCE((SELECT array_agg(tags.name ORDER BY tags.name) FROM tags JOIN post_tags ON post_tags.tag_id = tags.id WHERE post_tags.post_id = posts.id), '{}') FROM posts WHERE deleted_at IS NULL ORDER BY id;"
+	GET_POST_SQL      = "SELECT " + POST_COLUMNS + " FROM posts WHERE slug = $1 AND deleted_at IS NULL;"
+	SLUG_EXISTS_SQL   = "SELECT EXISTS (SELECT 1 FROM posts WHERE slug = $1);"                                                                                                                                                                                                                             // Deleted posts still hold their slug until they're purged
+	CREATE_POST_SQL   = "INSERT INTO posts (header, content, slug, author, created_at, updated_at, published, published_at, canonical_url, no_index, announced) VALUES ($1, $2, $3, $4, now(), now(), $5, COALESCE($6, now()), $7, $8, $5 AND COALESCE($6 < now(), false)) ON CONFLICT (slug) DO NOTHING;" // On Conflict used to ensure we dont dupe our slugs
+	UPDATE_POST_SQL   = "UPDATE posts SET (header, content, author, updated_at, published_at, canonical_url, no_index) = ($1, $2, $3, now(), COALESCE($5, published_at), $7, $8) WHERE slug = $4 AND deleted_at IS NULL AND ($6::timestamptz IS NULL OR updated_at = $6);"
+	DELETE_POST_SQL   = "UPDATE posts SET deleted_at = now() WHERE slug = $1 AND deleted_at IS NULL;"
+	RESTORE_POST_SQL  = "UPDATE posts SET deleted_at = NULL WHERE slug = $1 AND deleted_at IS NOT NULL;"
+	PURGE_POST_SQL    = "DELETE FROM posts WHERE slug = $1 AND deleted_at IS NOT NULL;"
+	PUBLISH_POST_SQL  = "UPDATE posts SET (published, updated_at, published_at) = (true, now(), CASE WHEN NOT published AND published_at = created_at THEN now() ELSE published_at END) WHERE slug = $1 AND deleted_at IS NULL;"
+	RECORD_VIEW_SQL   = "UPDATE posts SET views = views + 1 WHERE slug = $1 AND deleted_at IS NULL RETURNING views;" // Leaves updated_at alone, a view isn't an edit
+	// Only sets a token if the post doesn't have one, so links that have already been shared keep working
+	PREVIEW_TOKEN_SQL = "UPDATE posts SET preview_token = CASE WHEN preview_token = '' THEN $2 ELSE preview_token END WHERE slug = $1 AND deleted_at IS NULL RETURNING preview_token;"
+	POST_EXISTS_SQL   = "SELECT EXISTS (SELECT 1 FROM posts WHERE slug = $1 AND deleted_at IS NULL);"
+
+	// Every count in one pass over the table, rather than loading each post to count them
+	STATS_SQL = "SELECT COUNT(*), COUNT(*) FILTER (WHERE published AND published_at <= now()), COUNT(*) FILTER (WHERE published AND published_at > now()), COALESCE(SUM(views), 0), MAX(published_at) FILTER (WHERE published AND published_at <= now()) FROM posts WHERE deleted_at IS NULL;"
+	// Marks the posts that have gone live without being announced as announced, returning them so each is only announced once
+	NEWLY_LIVE_SQL = "UPDATE posts SET announced = true WHERE " + VISIBLE + " AND NOT announced RETURNING header, slug;"
+
+	POST_TAGS_SQL       = "SELECT tags.name FROM tags JOIN post_tags ON post_tags.tag_id = tags.id JOIN posts ON posts.id = post_tags.post_id WHERE posts.slug = $1 ORDER BY tags.name;"
+	PAGE_TAGS_SQL       = "SELECT posts.slug, tags.name FROM tags JOIN post_tags ON post_tags.tag_id = tags.id JOIN posts ON posts.id = post_tags.post_id WHERE posts.slug = ANY($1) ORDER BY tags.name;"
+	CLEAR_POST_TAGS_SQL = "DELETE FROM post_tags WHERE post_id = (SELECT id FROM posts WHERE slug = $1);"
+	CREATE_TAG_SQL      = "INSERT INTO tags (name) VALUES ($1) ON CONFLICT (name) DO NOTHING;"
+	TAG_POST_SQL        = "INSERT INTO post_tags (post_id, tag_id) SELECT posts.id, tags.id FROM posts, tags WHERE posts.slug = $1 AND tags.name = $2;"
+
+	// Renaming a post, the redirect from $1 is replaced if an earlier post had that slug, and any redirect from the new
+	// slug is cleared so a post renamed back to an old slug doesn't redirect to itself
+	RENAME_POST_SQL    = "UPDATE posts SET slug = $2 WHERE slug = $1 AND deleted_at IS NULL;"
+	ADD_REDIRECT_SQL   = "INSERT INTO slug_redirects (old_slug, post_id) SELECT $1, id FROM posts WHERE slug = $2 ON CONFLICT (old_slug) DO UPDATE SET post_id = excluded.post_id;"
+	CLEAR_REDIRECT_SQL = "DELETE FROM slug_redirects WHERE old_slug = $1;"
+	REDIRECT_SQL       = "SELECT posts.slug FROM slug_redirects JOIN posts ON posts.id = slug_redirects.post_id WHERE slug_redirects.old_slug = $1 AND posts.deleted_at IS NULL;"
+
+	POST_COMMENTS_SQL = "SELECT comments.author, comments.body, comments.created_at FROM comments JOIN posts ON posts.id = comments.post_id WHERE posts.slug = $1 ORDER BY comments.created_at, comments.id;"
+	ADD_COMMENT_SQL   = "INSERT INTO comments (post_id, author, body, created_at) SELECT id, $2, $3, now() FROM posts WHERE slug = $1 AND " + VISIBLE + ";"
+)
+
+// The orders the homepage can list posts in, picked with ?sort=
+type PostSort string
+
+const (
+	SORT_NEWEST PostSort = "newest" // The default
+	SORT_OLDEST PostSort = "oldest"
+	SORT_TITLE  PostSort = "title" // Alphabetically by header
+)
+
+// The statement loading a page of the homepage in each order. The sort picks one of these whole, it never goes into the SQL itself
+var pagePostsSQL = map[PostSort]string{
+	SORT_NEWEST: PAGE_POSTS_SQL,
+	SORT_OLDEST: PAGE_OLDEST_SQL,
+	SORT_TITLE:  PAGE_TITLE_SQL,
+}
+
+var (
+	// Returned by Create when the slug is already in use
+	errSlugTaken = errors.New("a post with that slug already exists")
+	// Returned when the post being changed doesn't exist
+	errPostNotFound = errors.New("no post has that slug")
+	// Returned by Update when the post has been saved since the editor loaded it
+	errPostConflict = errors.New("the post was changed since it was loaded")
+)
+
+// Loads and saves posts, the handlers never touch the DB any other way
+type PostStore interface {
+	List(ctx context.Context, includeDrafts bool) ([]Post, error)
+	Recent(ctx context.Context, limit int) ([]Post, error)
+	Page(ctx context.Context, page, perPage int, sort PostSort) (HomePage, error)
+	Search(ctx context.Context, query string, page int) (SearchPage, error)
+	Tagged(ctx context.Context, name string, page int) (TagPage, error)
+	ByAuthor(ctx context.Context, name string, page int) (AuthorPage, error)
+	Sitemap(ctx context.Context) ([]Post, error)
+	Archive(ctx context.Context) ([]Post, error)
+	Summaries(ctx context.Context) ([]Post, error)
+	Export(ctx context.Context, fn func(Post) error) error
+	Get(ctx context.Context, slug string) (p Post, found bool, err error)
+	SlugExists(ctx context.Context, slug string) bool
+	Create(ctx context.Context, post Post) error
+	Update(ctx context.Context, post Post) error
+	Delete(ctx context.Context, slug string) error
+	Restore(ctx context.Context, slug string) error
+	Purge(ctx context.Context, slug string) error
+	Publish(ctx context.Context, slug string) error
+	RenameAndUpdate(ctx context.Context, slug string, post Post) error
+	Redirect(ctx context.Context, oldSlug string) (slug string, found bool, err error)
+	PreviewToken(ctx context.Context, slug string) (string, error)
+	RecordView(ctx context.Context, slug string) (int, error)
+	Comments(ctx context.Context, slug string) ([]Comment, error)
+	AddComment(ctx context.Context, slug string, comment Comment) error
+	Stats(ctx context.Context) (PostStats, error)
+	NewlyLive(ctx context.Context) ([]Post, error)
+	Ping(ctx context.Context) error
+}
+
+// Totals across every post that hasn't been deleted
+type PostStats struct {
+	Posts      int        `json:"posts"`
+	Published  int        `json:"published"`
+	Scheduled  int        `json:"scheduled"` // Published to go live at a later date
+	Drafts     int        `json:"drafts"`
+	Views      int        `json:"views"`
+	NewestPost *time.Time `json:"newest_post,omitempty"` // When the most recent post was published, unset if none have been
+}
+
+// The PostStore backed by Postgres, the only place the handlers' SQL lives
+type PGPostStore struct {
+	pool    *pgxpool.Pool
+	perPage int // How many posts each page of the search, tag and author listings holds, Page is told per call
+}
+
+func NewPGPostStore(pool *pgxpool.Pool, perPage int) *PGPostStore {
+	return &PGPostStore{pool: pool, perPage: perPage}
+}
+
+// Checks Postgres is reachable, for the health check
+func (s *PGPostStore) Ping(ctx context.Context) error {
+	return s.pool.Ping(ctx)
+}
+
+// Loads every published post newest first, or with includeDrafts every post at all, most recently edited first
+func (s *PGPostStore) List(ctx context.Context, includeDrafts bool) ([]Post, error) {
+	query := LIST_POSTS_SQL
+	if includeDrafts {
+		query = LIST_ALL_SQL
+	}
+	rows, err := s.pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return scanPosts(rows)
+}
+
+// Loads the newest published posts, at most limit of them
+func (s *PGPostStore) Recent(ctx context.Context, limit int) ([]Post, error) {
+	rows, err := s.pool.Query(ctx, RECENT_POSTS_SQL, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanPosts(rows)
+}
+
+// Loads a single page of perPage posts in sort order, clamping page into the range of pages that actually exist
+func (s *PGPostStore) Page(ctx context.Context, page, perPage int, sort PostSort) (HomePage, error) {
+	query, ok := pagePostsSQL[sort]
+	if !ok {
+		return HomePage{}, fmt.Errorf("unknown sort %q", sort)
+	}
+
+	var total int
+	if err := s.pool.QueryRow(ctx, COUNT_POSTS_SQL).Scan(&total); err != nil {
+		return HomePage{}, err
+	}
+
+	homePage := paginate(page, total, perPage)
+	homePage.Sort = sort
+	rows, err := s.pool.Query(ctx, query, perPage, homePage.offset(perPage))
+	if err != nil {
+		return HomePage{}, err
+	}
+	if homePage.Posts, err = scanPosts(rows); err != nil {
+		return HomePage{}, err
+	}
+	return homePage, s.pageTags(ctx, homePage.Posts)
+}
+
+// Loads a single page of the posts matching query, best matches first
+func (s *PGPostStore) Search(ctx context.Context, query string, page int) (SearchPage, error) {
+	var total int
+	if err := s.pool.QueryRow(ctx, COUNT_SEARCH_SQL, query).Scan(&total); err != nil {
+		return SearchPage{}, err
+	}
+
+	results := SearchPage{HomePage: paginate(page, total, s.perPage), Query: query}
+	rows, err := s.pool.Query(ctx, SEARCH_POSTS_SQL, query, s.perPage, results.offset(s.perPage))
+	if err != nil {
+		return SearchPage{}, err
+	}
+	results.Posts, err = scanPosts(rows)
+	return results, err
+}
+
+// Loads a single page of the posts tagged name, newest first
+func (s *PGPostStore) Tagged(ctx context.Context, name string, page int) (TagPage, error) {
+	var total int
+	if err := s.pool.QueryRow(ctx, COUNT_TAGGED_SQL, name).Scan(&total); err != nil {
+		return TagPage{}, err
+	}
+
+	tagPage := TagPage{HomePage: paginate(page, total, s.perPage), Tag: name}
+	rows, err := s.pool.Query(ctx, TAGGED_POSTS_SQL, name, s.perPage, tagPage.offset(s.perPage))
+	if err != nil {
+		return TagPage{}, err
+	}
+	tagPage.Posts, err = scanPosts(rows)
+	return tagPage, err
+}
+
+// Loads a single page of the posts written by name, newest first
+func (s *PGPostStore) ByAuthor(ctx context.Context, name string, page int) (AuthorPage, error) {
+	var total int
+	if err := s.pool.QueryRow(ctx, COUNT_AUTHOR_SQL, name).Scan(&total); err != nil {
+		return AuthorPage{}, err
+	}
+
+	authorPage := AuthorPage{HomePage: paginate(page, total, s.perPage), Author: name}
+	rows, err := s.pool.Query(ctx, AUTHOR_POSTS_SQL, name, s.perPage, authorPage.offset(s.perPage))
+	if err != nil {
+		return AuthorPage{}, err
+	}
+	authorPage.Posts, err = scanPosts(rows)
+	return authorPage, err
+}
+
+// Loads just the slug and updated_at of every published post, the sitemap doesn't need anything else
+func (s *PGPostStore) Sitemap(ctx context.Context) ([]Post, error) {
+	rows, err := s.pool.Query(ctx, SITEMAP_POSTS_SQL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []Post{}
+	for rows.Next() {
+		var p Post
+		if err := rows.Scan(&p.Slug, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		posts = append(posts, p)
+	}
+	return posts, rows.Err()
+}
+
+// Loads the header, slug and publish date of every published post, newest first
+func (s *PGPostStore) Archive(ctx context.Context) ([]Post, error) {
+	rows, err := s.pool.Query(ctx, ARCHIVE_POSTS_SQL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []Post{}
+	for rows.Next() {
+		var p Post
+		if err := rows.Scan(&p.Header, &p.Slug, &p.PublishedAt); err != nil {
+			return nil, err
+		}
+		posts = append(posts, p)
+	}
+	return posts, rows.Err()
+}
+
+// Loads the header, slug and tags of every published post, newest first, enough to link to them without rendering each one
+func (s *PGPostStore) Summaries(ctx context.Context) ([]Post, error) {
+	rows, err := s.pool.Query(ctx, SUMMARIES_SQL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []Post{}
+	for rows.Next() {
+		var p Post
+		var tagNames []string
+		if err := rows.Scan(&p.Header, &p.Slug, &tagNames); err != nil {
+			return nil, err
+		}
+		for _, name := range tagNames {
+			p.Tags = append(p.Tags, Tag{Name: name})
+		}
+		posts = append(posts, p)
+	}
+	return posts, rows.Err()
+}
+
+// Calls fn with every post that isn't deleted, drafts included, in the order they were created and with their tags.
+// Posts are read a row at a time so a large blog is never all in memory, fn returning an error stops it
+func (s *PGPostStore) Export(ctx context.Context, fn func(Post) error) error {
+	rows, err := s.pool.Query(ctx, EXPORT_POSTS_SQL)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p Post
+		var tagNames []string
+		if err := rows.Scan(&p.Header, &p.Content, &p.Slug, &p.Author, &p.CreatedAt, &p.UpdatedAt, &p.Published, &p.Views, &p.PublishedAt, &p.DeletedAt, &p.CanonicalURL, &p.NoIndex, &p.PreviewToken, &tagNames); err != nil {
+			return err
+		}
+		p.Tags = []Tag{}
+		for _, name := range tagNames {
+			p.Tags = append(p.Tags, Tag{Name: name})
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Loads a single post, drafts included, found is false if no row matched slug rather than handing back an empty Post
+func (s *PGPostStore) Get(ctx context.Context, slug string) (p Post, found bool, err error) {
+	p, err = scanPost(s.pool.QueryRow(ctx, GET_POST_SQL, slug))
+	if err == pgx.ErrNoRows {
+		return Post{}, false, nil
+	}
+	if err != nil {
+		return Post{}, false, err
+	}
+
+	p.Tags, err = s.tags(ctx, p.Slug)
+	if err != nil {
+		return Post{}, false, err
+	}
+	return p, true, nil
+}
+
+// Reports whether a post already uses slug, if we can't tell it's assumed free and the insert will catch any conflict
+func (s *PGPostStore) SlugExists(ctx context.Context, slug string) bool {
+	var exists bool
+	if err := s.pool.QueryRow(ctx, SLUG_EXISTS_SQL, slug).Scan(&exists); err != nil {
+		logf(ctx, "Failed to check if slug %q exists: %v", slug, err)
+		return false
+	}
+	return exists
+}
+
+// Saves a new post and its tags, published_at is now unless post.PublishedAt is set. errSlugTaken if another post already has its slug
+func (s *PGPostStore) Create(ctx context.Context, post Post) error {
+	return s.inTx(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Exec(ctx, CREATE_POST_SQL, post.Header, post.Content, post.Slug, post.Author, post.Published, optionalTime(post.PublishedAt), post.CanonicalURL, post.NoIndex)
+		if err != nil {
+			return err
+		}
+		if rows.RowsAffected() == 0 {
+			// The insert has no WHERE, so the only way it touches nothing is ON CONFLICT skipping it
+			return errSlugTaken
+		}
+		return s.setTags(ctx, tx, post)
+	})
+}
+
+// Replaces the header, content and tags of the post with post.Slug, and its published_at if post.PublishedAt is set.
+// If post.UpdatedAt is set it must still be the post's updated_at, otherwise it's errPostConflict
+func (s *PGPostStore) Update(ctx context.Context, post Post) error {
+	return s.inTx(ctx, func(tx pgx.Tx) error {
+		return s.update(ctx, tx, post)
+	})
+}
+
+// Moves the post at slug to post.Slug, then saves the rest of post over it like Update. Either both happen or neither
+// does, so a conflicting edit can't leave the post renamed with its old content. errSlugTaken if post.Slug is in use
+func (s *PGPostStore) RenameAndUpdate(ctx context.Context, slug string, post Post) error {
+	return s.inTx(ctx, func(tx pgx.Tx) error {
+		if err := s.rename(ctx, tx, slug, post.Slug); err != nil {
+			return err
+		}
+		return s.update(ctx, tx, post)
+	})
+}
+
+// Deletes the post, hiding it everywhere but the admin dashboard where it can be restored or purged
+func (s *PGPostStore) Delete(ctx context.Context, slug string) error {
+	return s.execOnPost(ctx, DELETE_POST_SQL, slug)
+}
+
+// Brings back a deleted post as it was when it was deleted, errPostNotFound if it isn't deleted
+func (s *PGPostStore) Restore(ctx context.Context, slug string) error {
+	return s.execOnPost(ctx, RESTORE_POST_SQL, slug)
+}
+
+// Permanently removes a deleted post, its tags and comments go with it through their foreign keys.
+// Only deleted posts can be purged, so nothing is lost to a single click
+func (s *PGPostStore) Purge(ctx context.Context, slug string) error {
+	return s.execOnPost(ctx, PURGE_POST_SQL, slug)
+}
+
+// Makes a draft visible on the homepage, feeds and its own page, dating it now if it was Undated
+func (s *PGPostStore) Publish(ctx context.Context, slug string) error {
+	return s.execOnPost(ctx, PUBLISH_POST_SQL, slug)
+}
+
+// The slug the post that used to be at oldSlug has now, found is false if no post that isn't deleted ever had it
+func (s *PGPostStore) Redirect(ctx context.Context, oldSlug string) (slug string, found bool, err error) {
+	err = s.pool.QueryRow(ctx, REDIRECT_SQL, oldSlug).Scan(&slug)
+	if err == pgx.ErrNoRows {
+		return "", false, nil
+	}
+	return slug, err == nil, err
+}
+
+// The token that lets anyone preview the post before it's live, generating one the first time it's asked for
+func (s *PGPostStore) PreviewToken(ctx context.Context, slug string) (string, error) {
+	token, err := newPreviewToken()
+	if err != nil {
+		return "", err
+	}
+	err = s.pool.QueryRow(ctx, PREVIEW_TOKEN_SQL, slug, token).Scan(&token)
+	if err == pgx.ErrNoRows {
+		return "", errPostNotFound
+	}
+	return token, err
+}
+
+// Counts a view of the post, returning its new total. The increment happens in Postgres so concurrent views can't lose counts
+func (s *PGPostStore) RecordView(ctx context.Context, slug string) (int, error) {
+	var views int
+	err := s.pool.QueryRow(ctx, RECORD_VIEW_SQL, slug).Scan(&views)
+	if err == pgx.ErrNoRows {
+		return 0, errPostNotFound
+	}
+	return views, err
+}
+
+// Counts the posts and their views
+func (s *PGPostStore) Stats(ctx context.Context) (PostStats, error) {
+	var stats PostStats
+	err := s.pool.QueryRow(ctx, STATS_SQL).Scan(&stats.Posts, &stats.Published, &stats.Scheduled, &stats.Views, &stats.NewestPost)
+	stats.Drafts = stats.Posts - stats.Published - stats.Scheduled
+	return stats, err
+}
+
+// Loads the header and slug of the posts that have gone live since they were last asked for, marking them announced.
+// Each post is only ever returned once, even across restarts or with several copies of the blog running
+func (s *PGPostStore) NewlyLive(ctx context.Context) ([]Post, error) {
+	rows, err := s.pool.Query(ctx, NEWLY_LIVE_SQL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []Post{}
+	for rows.Next() {
+		var p Post
+		if err := rows.Scan(&p.Header, &p.Slug); err != nil {
+			return nil, err
+		}
+		posts = append(posts, p)
+	}
+	return posts, rows.Err()
+}
+
+// Loads the comments on a post, oldest first
+func (s *PGPostStore) Comments(ctx context.Context, slug string) ([]Comment, error) {
+	rows, err := s.pool.Query(ctx, POST_COMMENTS_SQL, slug)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []Comment{}
+	for rows.Next() {
+		var c Comment
+		if err := rows.Scan(&c.Author, &c.Body, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
+
+// Saves a comment on a published post, errPostNotFound if there's no such post or it's still a draft
+func (s *PGPostStore) AddComment(ctx context.Context, slug string, comment Comment) error {
+	rows, err := s.pool.Exec(ctx, ADD_COMMENT_SQL, slug, comment.Author, comment.Body)
+	if err != nil {
+		return err
+	}
+	if rows.RowsAffected() == 0 {
+		return errPostNotFound
+	}
+	return nil
+}
+
+// Runs a statement changing the single post with slug, errPostNotFound if it didn't match it
+func (s *PGPostStore) execOnPost(ctx context.Context, sql, slug string) error {
+	rows, err := s.pool.Exec(ctx, sql, slug)
+	if err != nil {
+		return err
+	}
+	if rows.RowsAffected() == 0 {
+		return errPostNotFound
+	}
+	return nil
+}
+
+// Runs fn in a transaction, committing only if it succeeds, so a post is never left with half its tags written
+func (s *PGPostStore) inTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Loads the tags on a single post, alphabetically
+func (s *PGPostStore) tags(ctx context.Context, slug string) ([]Tag, error) {
+	rows, err := s.pool.Query(ctx, POST_TAGS_SQL, slug)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []Tag{}
+	for rows.Next() {
+		var tag Tag
+		if err := rows.Scan(&tag.Name); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// Fills in the tags on every post in posts with a single query, rather than a query for each post
+func (s *PGPostStore) pageTags(ctx context.Context, posts []Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	slugs := make([]string, len(posts))
+	bySlug := make(map[string]*Post, len(posts))
+	for i := range posts {
+		posts[i].Tags = []Tag{}
+		slugs[i] = posts[i].Slug
+		bySlug[posts[i].Slug] = &posts[i]
+	}
+
+	rows, err := s.pool.Query(ctx, PAGE_TAGS_SQL, slugs)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var slug string
+		var tag Tag
+		if err := rows.Scan(&slug, &tag.Name); err != nil {
+			return err
+		}
+		bySlug[slug].Tags = append(bySlug[slug].Tags, tag)
+	}
+	return rows.Err()
+}
+
+// Replaces the tags on post with post.Tags, creating any tags that don't exist yet
+func (s *PGPostStore) setTags(ctx context.Context, tx pgx.Tx, post Post) error {
+	if _, err := tx.Exec(ctx, CLEAR_POST_TAGS_SQL, post.Slug); err != nil {
+		return err
+	}
+
+	for _, tag := range post.Tags {
+		if _, err := tx.Exec(ctx, CREATE_TAG_SQL, tag.Name); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, TAG_POST_SQL, post.Slug, tag.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Update's half of Update and RenameAndUpdate
+func (s *PGPostStore) update(ctx context.Context, tx pgx.Tx, post Post) error {
+	rows, err := tx.Exec(ctx, UPDATE_POST_SQL, post.Header, post.Content, post.Author, post.Slug, optionalTime(post.PublishedAt), optionalTime(post.UpdatedAt), post.CanonicalURL, post.NoIndex)
+	if err != nil {
+		return err
+	}
+	if rows.RowsAffected() == 0 {
+		// Postgres counts every row an UPDATE matched, even if nothing changed, so zero means there was no such post or it's moved on
+		if post.UpdatedAt.IsZero() {
+			return errPostNotFound
+		}
+		var exists bool
+		if err := tx.QueryRow(ctx, POST_EXISTS_SQL, post.Slug).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			return errPostConflict
+		}
+		return errPostNotFound
+	}
+	return s.setTags(ctx, tx, post)
+}
+
+// Moves the post at slug to newSlug, remembering slug so links to it can be redirected. errSlugTaken if newSlug is
+// in use. It leaves updated_at alone, as the update that follows it bumps it
+func (s *PGPostStore) rename(ctx context.Context, tx pgx.Tx, slug, newSlug string) error {
+	var taken bool
+	if err := tx.QueryRow(ctx, SLUG_EXISTS_SQL, newSlug).Scan(&taken); err != nil {
+		return err
+	}
+	if taken {
+		return errSlugTaken
+	}
+	rows, err := tx.Exec(ctx, RENAME_POST_SQL, slug, newSlug)
+	if err != nil {
+		return err
+	}
+	if rows.RowsAffected() == 0 {
+		return errPostNotFound
+	}
+	if _, err := tx.Exec(ctx, CLEAR_REDIRECT_SQL, newSlug); err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, ADD_REDIRECT_SQL, slug, newSlug)
+	return err
+}
+
+// Reads every row of a query selecting POST_COLUMNS into Posts, closing rows when done
+func scanPosts(rows pgx.Rows) ([]Post, error) {
+	defer rows.Close()
+
+	posts := []Post{}
+	for rows.Next() {
+		p, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, p)
+	}
+	return posts, rows.Err()
+}
+
+// A zero time as NULL, so the SQL can COALESCE it to a default
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// Scans a single row selecting POST_COLUMNS, works for both QueryRow and each row of Query
+func scanPost(row pgx.Row) (Post, error) {
+	var p Post
+	err := row.Scan(&p.Header, &p.Content, &p.Slug, &p.Author, &p.CreatedAt, &p.UpdatedAt, &p.Published, &p.Views, &p.PublishedAt, &p.DeletedAt, &p.CanonicalURL, &p.NoIndex, &p.PreviewToken)
+	return p, err
+}
@@ -0,0 +1,841 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// What every PostStore has to do, each run against the same store so they use slugs of their own
+var storeTests = []struct {
+	name string
+	test func(t *testing.T, store PostStore)
+}{
+	{"Timestamps", testTimestamps},
+	{"Tags", testTags},
+	{"DuplicateSlugs", testDuplicateSlugs},
+	{"CreateUpdateDelete", testCreateUpdateDelete},
+	{"RestoreAndPurge", testRestoreAndPurge},
+	{"StaleUpdates", testStaleUpdates},
+	{"Renames", testRenames},
+	{"PreviewTokens", testPreviewTokens},
+	{"NewlyLive", testNewlyLive},
+	{"List", testList},
+	{"Search", testSearch},
+	{"Stats", testStats},
+}
+
+// Runs every storeTest against store, so each backend is held to the same behaviour
+func storeSuite(t *testing.T, store PostStore) {
+	for _, tt := range storeTests {
+		t.Run(tt.name, func(t *testing.T) { tt.test(t, store) })
+	}
+}
+
+func TestSQLitePostStore(t *testing.T) {
+	storeSuite(t, NewSQLitePostStore(openTestSQLite(t, testConfig(t).DatabaseURL), DEFAULT_POSTS_PER_PAGE))
+}
+
+// Skipped unless TEST_DATABASE_URL points at a Postgres it can create schemas in
+func TestPGPostStore(t *testing.T) {
+	pool := openTestPostgres(t)
+	if err := runMigrations(context.Background(), pool); err != nil {
+		t.Fatalf("runMigrations: %v", err)
+	}
+	storeSuite(t, NewPGPostStore(pool, DEFAULT_POSTS_PER_PAGE))
+}
+
+// The storeTests the fakeStore implements enough of to pass, so the handler tests can trust it behaves like the real stores
+var fakeStoreTests = map[string]bool{"Timestamps": true, "DuplicateSlugs": true, "CreateUpdateDelete": true, "StaleUpdates": true, "Renames": true, "PreviewTokens": true, "NewlyLive": true, "List": true}
+
+func TestFakePostStore(t *testing.T) {
+	store := newFakeStore(DEFAULT_POSTS_PER_PAGE)
+	for _, tt := range storeTests {
+		if fakeStoreTests[tt.name] {
+			t.Run(tt.name, func(t *testing.T) { tt.test(t, store) })
+		}
+	}
+}
+
+// Loads the post at slug, failing the test if it isn't there
+func getPost(t *testing.T, store PostStore, slug string) Post {
+	t.Helper()
+	p, found, err := store.Get(context.Background(), slug)
+	if err != nil || !found {
+		t.Fatalf("Get(%q) = found %v, error %v", slug, found, err)
+	}
+	return p
+}
+
+func testTimestamps(t *testing.T, store PostStore) {
+	before := time.Now().Add(-time.Second)
+	createLivePost(t, store, "timestamps")
+	created := getPost(t, store, "timestamps")
+	if created.CreatedAt.Before(before) || created.CreatedAt.After(time.Now()) {
+		t.Errorf("CreatedAt = %v, want around now", created.CreatedAt)
+	}
+	if !created.UpdatedAt.Equal(created.CreatedAt) {
+		t.Errorf("UpdatedAt = %v on a new post, want CreatedAt %v", created.UpdatedAt, created.CreatedAt)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	created.Content = "Edited"
+	if err := store.Update(context.Background(), created); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	updated := getPost(t, store, "timestamps")
+	if !updated.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("CreatedAt changed from %v to %v on update", created.CreatedAt, updated.CreatedAt)
+	}
+	if !updated.UpdatedAt.After(created.UpdatedAt) {
+		t.Errorf("UpdatedAt = %v after an update, want after %v", updated.UpdatedAt, created.UpdatedAt)
+	}
+}
+
+func testDuplicateSlugs(t *testing.T, store PostStore) {
+	createLivePost(t, store, "duplicate")
+	err := store.Create(context.Background(), Post{Header: "Second", Content: "Words", Slug: "duplicate", Author: "Tester"})
+	if !errors.Is(err, errSlugTaken) {
+		t.Errorf("creating a second post with a slug = %v, want %v", err, errSlugTaken)
+	}
+	if p := getPost(t, store, "duplicate"); p.Header == "Second" {
+		t.Error("creating a second post with a slug saved over the first")
+	}
+}
+
+func testCreateUpdateDelete(t *testing.T, store PostStore) {
+	ctx := context.Background()
+	createPost(t, store, Post{Header: "Draft", Content: "Words", Slug: "crud", Author: "Tester"})
+	if p := getPost(t, store, "crud"); p.Header != "Draft" || p.Content != "Words" || p.Author != "Tester" || p.Published {
+		t.Errorf("Create saved %+v, want the unpublished draft it was given", p)
+	}
+
+	if err := store.Update(ctx, Post{Header: "Edited", Content: "New words", Slug: "crud", Author: "Editor"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if p := getPost(t, store, "crud"); p.Header != "Edited" || p.Content != "New words" || p.Author != "Editor" {
+		t.Errorf("Update saved %+v, want the edited post", p)
+	}
+
+	if err := store.Publish(ctx, "crud"); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if p := getPost(t, store, "crud"); !p.Live() {
+		t.Errorf("Publish left %+v, want it live", p)
+	}
+
+	if err := store.Delete(ctx, "crud"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, found, err := store.Get(ctx, "crud"); found || err != nil {
+		t.Errorf("Get after Delete = found %v, error %v, want it gone", found, err)
+	}
+
+	// Every one of them on a post that isn't there
+	missing := Post{Header: "Missing", Content: "Words", Slug: "crud", Author: "Tester"}
+	for name, err := range map[string]error{
+		"Update":  store.Update(ctx, missing),
+		"Publish": store.Publish(ctx, "crud"),
+		"Delete":  store.Delete(ctx, "crud"),
+	} {
+		if !errors.Is(err, errPostNotFound) {
+			t.Errorf("%s on a deleted post = %v, want %v", name, err, errPostNotFound)
+		}
+	}
+}
+
+func testRestoreAndPurge(t *testing.T, store PostStore) {
+	ctx := context.Background()
+	createLivePost(t, store, "soft-deleted")
+	if err := store.Delete(ctx, "soft-deleted"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	all, err := store.List(ctx, true)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if i := indexOf(slugs(all), "soft-deleted"); i < 0 || all[i].DeletedAt == nil {
+		t.Errorf("List with drafts after Delete = %v, want the deleted post there marked deleted", slugs(all))
+	}
+
+	if err := store.Restore(ctx, "soft-deleted"); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if p := getPost(t, store, "soft-deleted"); p.DeletedAt != nil || !p.Live() || p.Header != "Post soft-deleted" {
+		t.Errorf("Restore left %+v, want the live post as it was", p)
+	}
+	if err := store.Purge(ctx, "soft-deleted"); !errors.Is(err, errPostNotFound) {
+		t.Errorf("Purge on a post that isn't deleted = %v, want %v", err, errPostNotFound)
+	}
+
+	if err := store.Delete(ctx, "soft-deleted"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := store.Purge(ctx, "soft-deleted"); err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+	if all, err := store.List(ctx, true); err != nil || hasSlug(slugs(all), "soft-deleted") {
+		t.Errorf("List with drafts after Purge = %v, error %v, want the post gone for good", slugs(all), err)
+	}
+	for name, err := range map[string]error{
+		"Restore": store.Restore(ctx, "soft-deleted"),
+		"Purge":   store.Purge(ctx, "soft-deleted"),
+	} {
+		if !errors.Is(err, errPostNotFound) {
+			t.Errorf("%s on a purged post = %v, want %v", name, err, errPostNotFound)
+		}
+	}
+}
+
+func testStaleUpdates(t *testing.T, store PostStore) {
+	ctx := context.Background()
+	createLivePost(t, store, "stale")
+	original := getPost(t, store, "stale")
+	loaded := original.UpdatedAt
+
+	// The first editor to save wins
+	if err := store.Update(ctx, Post{Header: "First", Content: "Words", Slug: "stale", Author: "Tester", UpdatedAt: loaded}); err != nil {
+		t.Fatalf("Update with the updated_at it was loaded with: %v", err)
+	}
+	if p := getPost(t, store, "stale"); !p.PublishedAt.Equal(original.PublishedAt) {
+		t.Errorf("Update with the updated_at it was loaded with moved PublishedAt from %v to %v", original.PublishedAt, p.PublishedAt)
+	}
+	for name, err := range map[string]error{
+		"Update":          store.Update(ctx, Post{Header: "Second", Content: "Words", Slug: "stale", Author: "Tester", UpdatedAt: loaded}),
+		"RenameAndUpdate": store.RenameAndUpdate(ctx, "stale", Post{Header: "Second", Content: "Words", Slug: "stale-renamed", Author: "Tester", UpdatedAt: loaded}),
+	} {
+		if !errors.Is(err, errPostConflict) {
+			t.Errorf("%s with a stale updated_at = %v, want %v", name, err, errPostConflict)
+		}
+	}
+	if p := getPost(t, store, "stale"); p.Header != "First" {
+		t.Errorf("the stale updates left %+v, want the first editor's changes", p)
+	}
+	if _, found, _ := store.Get(ctx, "stale-renamed"); found {
+		t.Errorf("RenameAndUpdate with a stale updated_at renamed the post anyway")
+	}
+
+	// Without an updated_at it's saved whatever's changed
+	if err := store.Update(ctx, Post{Header: "Forced", Content: "Words", Slug: "stale", Author: "Tester"}); err != nil {
+		t.Errorf("Update without an updated_at: %v", err)
+	}
+}
+
+// Where Redirect sends oldSlug, "" if nowhere
+func redirectsTo(t *testing.T, store PostStore, oldSlug string) string {
+	t.Helper()
+	slug, found, err := store.Redirect(context.Background(), oldSlug)
+	if err != nil {
+		t.Fatalf("Redirect(%q): %v", oldSlug, err)
+	}
+	if !found {
+		return ""
+	}
+	return slug
+}
+
+func testRenames(t *testing.T, store PostStore) {
+	ctx := context.Background()
+	createLivePost(t, store, "rename-a")
+	createLivePost(t, store, "rename-taken")
+	rename := func(from, to string) error {
+		return store.RenameAndUpdate(ctx, from, Post{Header: "Renamed to " + to, Content: "Words", Slug: to, Author: "Tester"})
+	}
+
+	if err := rename("rename-a", "rename-b"); err != nil {
+		t.Fatalf("RenameAndUpdate: %v", err)
+	}
+	if p := getPost(t, store, "rename-b"); p.Header != "Renamed to rename-b" {
+		t.Errorf("RenameAndUpdate saved %+v, want it updated as well as moved", p)
+	}
+	if _, found, _ := store.Get(ctx, "rename-a"); found {
+		t.Error("the post can still be loaded from its old slug")
+	}
+
+	// Every slug it's had goes straight to the one it has now, rather than through the ones in between
+	if err := rename("rename-b", "rename-c"); err != nil {
+		t.Fatalf("RenameAndUpdate: %v", err)
+	}
+	for _, old := range []string{"rename-a", "rename-b"} {
+		if got := redirectsTo(t, store, old); got != "rename-c" {
+			t.Errorf("Redirect(%q) = %q, want rename-c", old, got)
+		}
+	}
+
+	// Renamed back to an old slug, that slug mustn't redirect to itself
+	if err := rename("rename-c", "rename-a"); err != nil {
+		t.Fatalf("RenameAndUpdate: %v", err)
+	}
+	if got := redirectsTo(t, store, "rename-a"); got != "" {
+		t.Errorf("Redirect from the slug the post has now = %q, want no redirect", got)
+	}
+	if got := redirectsTo(t, store, "rename-c"); got != "rename-a" {
+		t.Errorf("Redirect(rename-c) = %q, want rename-a", got)
+	}
+
+	// A taken slug leaves everything as it was, the update included
+	if err := rename("rename-a", "rename-taken"); !errors.Is(err, errSlugTaken) {
+		t.Errorf("renaming to a taken slug = %v, want %v", err, errSlugTaken)
+	}
+	if p := getPost(t, store, "rename-a"); p.Header != "Renamed to rename-a" {
+		t.Errorf("renaming to a taken slug saved %+v, want nothing changed", p)
+	}
+	if p := getPost(t, store, "rename-taken"); p.Header != "Post rename-taken" {
+		t.Errorf("renaming to a taken slug saved over the post with it: %+v", p)
+	}
+	if got := redirectsTo(t, store, "rename-b"); got != "rename-a" {
+		t.Errorf("renaming to a taken slug moved the redirect from rename-b to %q", got)
+	}
+
+	if err := rename("rename-missing", "rename-d"); !errors.Is(err, errPostNotFound) {
+		t.Errorf("renaming a missing post = %v, want %v", err, errPostNotFound)
+	}
+	if got := redirectsTo(t, store, "rename-never"); got != "" {
+		t.Errorf("Redirect from a slug no post has had = %q, want no redirect", got)
+	}
+
+	// Deleted posts aren't redirected to
+	if err := store.Delete(ctx, "rename-a"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if got := redirectsTo(t, store, "rename-b"); got != "" {
+		t.Errorf("Redirect to a deleted post = %q, want no redirect", got)
+	}
+}
+
+func testPreviewTokens(t *testing.T, store PostStore) {
+	ctx := context.Background()
+	createPost(t, store, Post{Header: "Draft", Content: "Words", Slug: "token-a", Author: "Tester"})
+	createPost(t, store, Post{Header: "Draft", Content: "Words", Slug: "token-b", Author: "Tester"})
+
+	token, err := store.PreviewToken(ctx, "token-a")
+	if err != nil {
+		t.Fatalf("PreviewToken: %v", err)
+	}
+	if len(token) < 40 {
+		t.Errorf("PreviewToken = %q, want one too long to guess", token)
+	}
+	// Made once, so links already shared keep working
+	if again, err := store.PreviewToken(ctx, "token-a"); err != nil || again != token {
+		t.Errorf("PreviewToken a second time = %q, %v, want the first token %q", again, err, token)
+	}
+	if p := getPost(t, store, "token-a"); p.PreviewToken != token {
+		t.Errorf("Get loaded preview token %q, want %q", p.PreviewToken, token)
+	}
+	if other, err := store.PreviewToken(ctx, "token-b"); err != nil || other == token {
+		t.Errorf("PreviewToken for another post = %q, %v, want a token of its own", other, err)
+	}
+	if _, err := store.PreviewToken(ctx, "token-missing"); !errors.Is(err, errPostNotFound) {
+		t.Errorf("PreviewToken for a missing post = %v, want %v", err, errPostNotFound)
+	}
+}
+
+// The slugs NewlyLive returns that are in mine, as other tests' posts go live in the same store
+func newlyLive(t *testing.T, store PostStore, mine ...string) []string {
+	t.Helper()
+	posts, err := store.NewlyLive(context.Background())
+	if err != nil {
+		t.Fatalf("NewlyLive: %v", err)
+	}
+	var got []string
+	for _, p := range posts {
+		if indexOf(mine, p.Slug) >= 0 {
+			got = append(got, p.Slug)
+		}
+	}
+	sort.Strings(got)
+	return got
+}
+
+func testNewlyLive(t *testing.T, store PostStore) {
+	ctx := context.Background()
+	mine := []string{"announce-draft", "announce-now", "announce-backdated", "announce-scheduled"}
+	createPost(t, store, Post{Header: "Draft", Content: "Words", Slug: "announce-draft", Author: "Tester"})
+	createPost(t, store, Post{Header: "Now", Content: "Words", Slug: "announce-now", Author: "Tester", Published: true})
+	// Live before anyone could have been told, like an imported post
+	createPost(t, store, Post{Header: "Backdated", Content: "Words", Slug: "announce-backdated", Author: "Tester", Published: true, PublishedAt: time.Now().Add(-48 * time.Hour)})
+	createPost(t, store, Post{Header: "Scheduled", Content: "Words", Slug: "announce-scheduled", Author: "Tester", Published: true, PublishedAt: time.Now().Add(48 * time.Hour)})
+
+	if got, want := newlyLive(t, store, mine...), []string{"announce-now"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("NewlyLive = %v, want %v", got, want)
+	}
+	if got := newlyLive(t, store, mine...); len(got) != 0 {
+		t.Errorf("NewlyLive a second time = %v, want each post only once", got)
+	}
+
+	if err := store.Publish(ctx, "announce-draft"); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if got, want := newlyLive(t, store, mine...), []string{"announce-draft"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("NewlyLive after publishing a draft = %v, want %v", got, want)
+	}
+
+	// Edits to a post that's already been announced don't announce it again
+	if err := store.Update(ctx, Post{Header: "Edited", Content: "Words", Slug: "announce-now", Author: "Tester"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got := newlyLive(t, store, mine...); len(got) != 0 {
+		t.Errorf("NewlyLive after editing a live post = %v, want nothing", got)
+	}
+}
+
+// The slugs of posts, in order
+func slugs(posts []Post) []string {
+	var slugs []string
+	for _, p := range posts {
+		slugs = append(slugs, p.Slug)
+	}
+	return slugs
+}
+
+// Where want is in slugs, -1 if it isn't
+func indexOf(slugs []string, want string) int {
+	for i, slug := range slugs {
+		if slug == want {
+			return i
+		}
+	}
+	return -1
+}
+
+// Whether slugs has want in it
+func hasSlug(slugs []string, want string) bool {
+	return indexOf(slugs, want) >= 0
+}
+
+func testList(t *testing.T, store PostStore) {
+	ctx := context.Background()
+	newest := time.Now().Add(-time.Minute)
+	createPost(t, store, Post{Header: "Older", Content: "Words", Slug: "list-older", Author: "Tester", Published: true, PublishedAt: newest.Add(-time.Second)})
+	createPost(t, store, Post{Header: "Newer", Content: "Words", Slug: "list-newer", Author: "Tester", Published: true, PublishedAt: newest})
+	createPost(t, store, Post{Header: "Draft", Content: "Words", Slug: "list-draft", Author: "Tester"})
+
+	live, err := store.List(ctx, false)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if got := slugs(live); !hasSlug(got, "list-older") || !hasSlug(got, "list-newer") || hasSlug(got, "list-draft") {
+		t.Errorf("List without drafts = %v, want the live posts and not the draft", got)
+	}
+	all, err := store.List(ctx, true)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if got := slugs(all); !hasSlug(got, "list-draft") {
+		t.Errorf("List with drafts = %v, want the draft as well", got)
+	}
+
+	recent, err := store.Recent(ctx, len(live))
+	if err != nil {
+		t.Fatalf("Recent: %v", err)
+	}
+	if got := slugs(recent); !reflect.DeepEqual(got, slugs(live)) {
+		t.Errorf("Recent(%d) = %v, want every live post newest first like List, %v", len(live), got, slugs(live))
+	}
+	if got := slugs(live); indexOf(got, "list-newer") > indexOf(got, "list-older") {
+		t.Errorf("List = %v, want list-newer before list-older", got)
+	}
+	if recent, err := store.Recent(ctx, 1); err != nil || len(recent) != 1 {
+		t.Errorf("Recent(1) = %d posts, error %v, want 1", len(recent), err)
+	}
+}
+
+func testSearch(t *testing.T, store PostStore) {
+	ctx := context.Background()
+	createPost(t, store, Post{Header: "Zebrafish care", Content: "Words", Slug: "search-header", Author: "Tester", Published: true})
+	createPost(t, store, Post{Header: "Pets", Content: "Feeding a quokka", Slug: "search-content", Author: "Tester", Published: true})
+	createPost(t, store, Post{Header: "Zebrafish draft", Content: "Not yet", Slug: "search-draft", Author: "Tester"})
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "zebrafish", want: []string{"search-header"}},
+		{query: "quokka", want: []string{"search-content"}},
+		{query: "axolotl"},
+	}
+	for _, tt := range tests {
+		results, err := store.Search(ctx, tt.query, 1)
+		if err != nil {
+			t.Fatalf("Search(%q): %v", tt.query, err)
+		}
+		if got := slugs(results.Posts); !reflect.DeepEqual(got, tt.want) && !(len(got) == 0 && len(tt.want) == 0) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+		if results.TotalPosts != len(tt.want) || results.Query != tt.query {
+			t.Errorf("Search(%q) counted %d posts for %q, want %d", tt.query, results.TotalPosts, results.Query, len(tt.want))
+		}
+	}
+}
+
+// Counts what it adds against what was there already, as the other storeTests share the store
+func testStats(t *testing.T, store PostStore) {
+	ctx := context.Background()
+	before, err := store.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	createLivePost(t, store, "stats-live")
+	createPost(t, store, Post{Header: "Draft", Content: "Words", Slug: "stats-draft", Author: "Tester"})
+	createPost(t, store, Post{Header: "Later", Content: "Words", Slug: "stats-later", Author: "Tester", Published: true, PublishedAt: time.Now().Add(time.Hour)})
+	if _, err := store.RecordView(ctx, "stats-live"); err != nil {
+		t.Fatalf("RecordView: %v", err)
+	}
+
+	after, err := store.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	got := PostStats{Posts: after.Posts - before.Posts, Published: after.Published - before.Published, Scheduled: after.Scheduled - before.Scheduled, Drafts: after.Drafts - before.Drafts, Views: after.Views - before.Views}
+	if want := (PostStats{Posts: 3, Published: 1, Scheduled: 1, Drafts: 1, Views: 1}); got != want {
+		t.Errorf("Stats went up by %+v, want %+v", got, want)
+	}
+	if after.NewestPost == nil {
+		t.Errorf("Stats has no newest post after one was published")
+	}
+}
+
+// An in-memory PostStore for testing handlers without a DB. It only implements what the post pages and forms use,
+// anything else panics on the nil PostStore it embeds so a test that needs more fails loudly rather than passing
+type fakeStore struct {
+	PostStore
+
+	perPage   int
+	mu        sync.Mutex
+	posts     []*Post          // Oldest first, deleted ones too
+	redirects map[string]*Post // By the slug the post used to have
+	comments  map[*Post][]Comment
+	announced map[*Post]bool
+}
+
+func newFakeStore(perPage int) *fakeStore {
+	return &fakeStore{perPage: perPage, redirects: map[string]*Post{}, comments: map[*Post][]Comment{}, announced: map[*Post]bool{}}
+}
+
+// The post at slug, nil if there isn't one
+func (s *fakeStore) find(slug string) *Post {
+	for _, p := range s.posts {
+		if p.Slug == slug {
+			return p
+		}
+	}
+	return nil
+}
+
+// The post at slug unless it's deleted
+func (s *fakeStore) findUndeleted(slug string) *Post {
+	if p := s.find(slug); p != nil && p.DeletedAt == nil {
+		return p
+	}
+	return nil
+}
+
+// Copies of the posts readers can see, in sort order. Ties go to the last created when newest first like the SQL,
+// otherwise to the first
+func (s *fakeStore) visible(order PostSort) []Post {
+	posts := []Post{}
+	for _, p := range s.posts {
+		if p.DeletedAt == nil && p.Live() {
+			posts = append(posts, s.copyOf(p))
+		}
+	}
+	switch order {
+	case SORT_OLDEST:
+		sort.SliceStable(posts, func(i, j int) bool { return posts[i].PublishedAt.Before(posts[j].PublishedAt) })
+	case SORT_TITLE:
+		sort.SliceStable(posts, func(i, j int) bool { return strings.ToLower(posts[i].Header) < strings.ToLower(posts[j].Header) })
+	default:
+		for i, j := 0, len(posts)-1; i < j; i, j = i+1, j-1 {
+			posts[i], posts[j] = posts[j], posts[i]
+		}
+		sort.SliceStable(posts, func(i, j int) bool { return posts[i].PublishedAt.After(posts[j].PublishedAt) })
+	}
+	return posts
+}
+
+// p as the store would load it, with nothing shared with what's kept
+func (s *fakeStore) copyOf(p *Post) Post {
+	post := *p
+	post.Tags = append([]Tag(nil), p.Tags...)
+	return post
+}
+
+// tags as the store keeps them, alphabetically and without duplicates
+func fakeTags(tags []Tag) []Tag {
+	seen := map[string]bool{}
+	kept := []Tag{}
+	for _, tag := range tags {
+		if !seen[tag.Name] {
+			seen[tag.Name] = true
+			kept = append(kept, tag)
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].Name < kept[j].Name })
+	return kept
+}
+
+func (s *fakeStore) List(ctx context.Context, includeDrafts bool) ([]Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !includeDrafts {
+		return s.visible(SORT_NEWEST), nil
+	}
+	posts := []Post{}
+	for i := len(s.posts) - 1; i >= 0; i-- {
+		posts = append(posts, s.copyOf(s.posts[i]))
+	}
+	sort.SliceStable(posts, func(i, j int) bool { return posts[i].UpdatedAt.After(posts[j].UpdatedAt) })
+	return posts, nil
+}
+
+func (s *fakeStore) Recent(ctx context.Context, limit int) ([]Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	posts := s.visible(SORT_NEWEST)
+	if len(posts) > limit {
+		posts = posts[:limit]
+	}
+	return posts, nil
+}
+
+// page of perPage posts, clamped into the pages there are like the SQL
+func (s *fakeStore) pageOf(posts []Post, page, perPage int) HomePage {
+	homePage := paginate(page, len(posts), perPage)
+	start := homePage.offset(perPage)
+	end := start + perPage
+	if end > len(posts) {
+		end = len(posts)
+	}
+	homePage.Posts = posts[start:end]
+	return homePage
+}
+
+func (s *fakeStore) Page(ctx context.Context, page, perPage int, order PostSort) (HomePage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := pagePostsSQL[order]; !ok {
+		return HomePage{}, fmt.Errorf("unknown sort %q", order)
+	}
+	homePage := s.pageOf(s.visible(order), page, perPage)
+	homePage.Sort = order
+	return homePage, nil
+}
+
+func (s *fakeStore) ByAuthor(ctx context.Context, name string, page int) (AuthorPage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	posts := []Post{}
+	for _, p := range s.visible(SORT_NEWEST) {
+		if strings.EqualFold(p.Author, name) {
+			posts = append(posts, p)
+		}
+	}
+	return AuthorPage{HomePage: s.pageOf(posts, page, s.perPage), Author: name}, nil
+}
+
+func (s *fakeStore) Summaries(ctx context.Context) ([]Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	posts := []Post{}
+	for _, p := range s.visible(SORT_NEWEST) {
+		posts = append(posts, Post{Header: p.Header, Slug: p.Slug, Tags: p.Tags})
+	}
+	return posts, nil
+}
+
+func (s *fakeStore) Get(ctx context.Context, slug string) (Post, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.findUndeleted(slug)
+	if p == nil {
+		return Post{}, false, nil
+	}
+	return s.copyOf(p), true, nil
+}
+
+func (s *fakeStore) SlugExists(ctx context.Context, slug string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.find(slug) != nil
+}
+
+func (s *fakeStore) Create(ctx context.Context, post Post) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.find(post.Slug) != nil {
+		return errSlugTaken
+	}
+	now := time.Now()
+	p := post
+	p.CreatedAt, p.UpdatedAt, p.DeletedAt = now, now, nil
+	p.Views, p.PreviewToken, p.Body = 0, "", ""
+	if p.PublishedAt.IsZero() {
+		p.PublishedAt = now
+	}
+	p.Tags = fakeTags(post.Tags)
+	s.posts = append(s.posts, &p)
+	// Backdated posts were live before anyone could be told, so they never are
+	s.announced[&p] = p.Published && !post.PublishedAt.IsZero() && post.PublishedAt.Before(now)
+	return nil
+}
+
+func (s *fakeStore) Update(ctx context.Context, post Post) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, err := s.updatable(post)
+	if err != nil {
+		return err
+	}
+	s.update(p, post)
+	return nil
+}
+
+func (s *fakeStore) RenameAndUpdate(ctx context.Context, slug string, post Post) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.find(post.Slug) != nil {
+		return errSlugTaken
+	}
+	moved := post
+	moved.Slug = slug
+	p, err := s.updatable(moved)
+	if err != nil {
+		return err
+	}
+	p.Slug = post.Slug
+	delete(s.redirects, post.Slug)
+	s.redirects[slug] = p
+	s.update(p, post)
+	return nil
+}
+
+// The post that post would be saved over, or why it can't be
+func (s *fakeStore) updatable(post Post) (*Post, error) {
+	p := s.findUndeleted(post.Slug)
+	if p == nil {
+		return nil, errPostNotFound
+	}
+	if !post.UpdatedAt.IsZero() && !post.UpdatedAt.Equal(p.UpdatedAt) {
+		return nil, errPostConflict
+	}
+	return p, nil
+}
+
+func (s *fakeStore) update(p *Post, post Post) {
+	p.Header, p.Content, p.Author = post.Header, post.Content, post.Author
+	p.CanonicalURL, p.NoIndex = post.CanonicalURL, post.NoIndex
+	p.Tags = fakeTags(post.Tags)
+	p.UpdatedAt = time.Now()
+	if !post.PublishedAt.IsZero() {
+		p.PublishedAt = post.PublishedAt
+	}
+}
+
+func (s *fakeStore) Delete(ctx context.Context, slug string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.findUndeleted(slug)
+	if p == nil {
+		return errPostNotFound
+	}
+	now := time.Now()
+	p.DeletedAt = &now
+	return nil
+}
+
+func (s *fakeStore) Publish(ctx context.Context, slug string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.findUndeleted(slug)
+	if p == nil {
+		return errPostNotFound
+	}
+	now := time.Now()
+	if p.Undated() {
+		p.PublishedAt = now
+	}
+	p.Published, p.UpdatedAt = true, now
+	return nil
+}
+
+func (s *fakeStore) Redirect(ctx context.Context, oldSlug string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.redirects[oldSlug]; ok && p.DeletedAt == nil {
+		return p.Slug, true, nil
+	}
+	return "", false, nil
+}
+
+func (s *fakeStore) PreviewToken(ctx context.Context, slug string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.findUndeleted(slug)
+	if p == nil {
+		return "", errPostNotFound
+	}
+	if p.PreviewToken == "" {
+		token, err := newPreviewToken()
+		if err != nil {
+			return "", err
+		}
+		p.PreviewToken = token
+	}
+	return p.PreviewToken, nil
+}
+
+func (s *fakeStore) RecordView(ctx context.Context, slug string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.findUndeleted(slug)
+	if p == nil {
+		return 0, errPostNotFound
+	}
+	p.Views++
+	return p.Views, nil
+}
+
+func (s *fakeStore) Comments(ctx context.Context, slug string) ([]Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	comments := []Comment{}
+	if p := s.find(slug); p != nil {
+		comments = append(comments, s.comments[p]...)
+	}
+	return comments, nil
+}
+
+func (s *fakeStore) AddComment(ctx context.Context, slug string, comment Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.findUndeleted(slug)
+	if p == nil || !p.Live() {
+		return errPostNotFound
+	}
+	comment.CreatedAt = time.Now()
+	s.comments[p] = append(s.comments[p], comment)
+	return nil
+}
+
+func (s *fakeStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (s *fakeStore) NewlyLive(ctx context.Context) ([]Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	posts := []Post{}
+	for _, p := range s.posts {
+		if p.DeletedAt == nil && p.Live() && !s.announced[p] {
+			s.announced[p] = true
+			posts = append(posts, Post{Header: p.Header, Slug: p.Slug})
+		}
+	}
+	return posts, nil
+}
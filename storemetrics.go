@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+const DEFAULT_SLOW_QUERY_THRESHOLD = 500 * time.Millisecond // Store calls taking longer than this are logged, override with SLOW_QUERY_THRESHOLD
+
+// Wraps a PostStore to time every call into it, recording how long each operation took in the metrics and logging those
+// that took longer than slow. Only the operation's name is logged and never its arguments, as they can be whole posts
+type timedStore struct {
+	store   PostStore
+	metrics *Metrics
+	slow    time.Duration
+}
+
+func newTimedStore(store PostStore, metrics *Metrics, slow time.Duration) *timedStore {
+	return &timedStore{store: store, metrics: metrics, slow: slow}
+}
+
+// Records the operation that began at start, meant to be deferred so it's timed however the operation returns
+func (s *timedStore) observe(ctx context.Context, operation string, start time.Time) {
+	elapsed := time.Since(start)
+	s.metrics.observeQuery(operation, elapsed)
+	if elapsed > s.slow {
+		s.metrics.slowQuery(operation)
+		logf(ctx, "Slow query: %s took %s", operation, elapsed)
+	}
+}
+
+func (s *timedStore) List(ctx context.Context, includeDrafts bool) ([]Post, error) {
+	defer s.observe(ctx, "List", time.Now())
+	return s.store.List(ctx, includeDrafts)
+}
+
+func (s *timedStore) Recent(ctx context.Context, limit int) ([]Post, error) {
+	defer s.observe(ctx, "Recent", time.Now())
+	return s.store.Recent(ctx, limit)
+}
+
+func (s *timedStore) Page(ctx context.Context, page, perPage int, sort PostSort) (HomePage, error) {
+	defer s.observe(ctx, "Page", time.Now())
+	return s.store.Page(ctx, page, perPage, sort)
+}
+
+func (s *timedStore) Search(ctx context.Context, query string, page int) (SearchPage, error) {
+	defer s.observe(ctx, "Search", time.Now())
+	return s.store.Search(ctx, query, page)
+}
+
+func (s *timedStore) Tagged(ctx context.Context, name string, page int) (TagPage, error) {
+	defer s.observe(ctx, "Tagged", time.Now())
+	return s.store.Tagged(ctx, name, page)
+}
+
+func (s *timedStore) ByAuthor(ctx context.Context, name string, page int) (AuthorPage, error) {
+	defer s.observe(ctx, "ByAuthor", time.Now())
+	return s.store.ByAuthor(ctx, name, page)
+}
+
+func (s *timedStore) Sitemap(ctx context.Context) ([]Post, error) {
+	defer s.observe(ctx, "Sitemap", time.Now())
+	return s.store.Sitemap(ctx)
+}
+
+func (s *timedStore) Archive(ctx context.Context) ([]Post, error) {
+	defer s.observe(ctx, "Archive", time.Now())
+	return s.store.Archive(ctx)
+}
+
+func (s *timedStore) Summaries(ctx context.Context) ([]Post, error) {
+	defer s.observe(ctx, "Summaries", time.Now())
+	return s.store.Summaries(ctx)
+}
+
+// Left untimed, as it takes as long as fn does with each post, which is usually sending it to a client
+func (s *timedStore) Export(ctx context.Context, fn func(Post) error) error {
+	return s.store.Export(ctx, fn)
+}
+
+func (s *timedStore) Get(ctx context.Context, slug string) (Post, bool, error) {
+	defer s.observe(ctx, "Get", time.Now())
+	return s.store.Get(ctx, slug)
+}
+
+func (s *timedStore) SlugExists(ctx context.Context, slug string) bool {
+	defer s.observe(ctx, "SlugExists", time.Now())
+	return s.store.SlugExists(ctx, slug)
+}
+
+func (s *timedStore) Create(ctx context.Context, post Post) error {
+	defer s.observe(ctx, "Create", time.Now())
+	return s.store.Create(ctx, post)
+}
+
+func (s *timedStore) Update(ctx context.Context, post Post) error {
+	defer s.observe(ctx, "Update", time.Now())
+	return s.store.Update(ctx, post)
+}
+
+func (s *timedStore) Delete(ctx context.Context, slug string) error {
+	defer s.observe(ctx, "Delete", time.Now())
+	return s.store.Delete(ctx, slug)
+}
+
+func (s *timedStore) Restore(ctx context.Context, slug string) error {
+	defer s.observe(ctx, "Restore", time.Now())
+	return s.store.Restore(ctx, slug)
+}
+
+func (s *timedStore) Purge(ctx context.Context, slug string) error {
+	defer s.observe(ctx, "Purge", time.Now())
+	return s.store.Purge(ctx, slug)
+}
+
+func (s *timedStore) Publish(ctx context.Context, slug string) error {
+	defer s.observe(ctx, "Publish", time.Now())
+	return s.store.Publish(ctx, slug)
+}
+
+func (s *timedStore) RenameAndUpdate(ctx context.Context, slug string, post Post) error {
+	defer s.observe(ctx, "RenameAndUpdate", time.Now())
+	return s.store.RenameAndUpdate(ctx, slug, post)
+}
+
+func (s *timedStore) Redirect(ctx context.Context, oldSlug string) (string, bool, error) {
+	defer s.observe(ctx, "Redirect", time.Now())
+	return s.store.Redirect(ctx, oldSlug)
+}
+
+func (s *timedStore) PreviewToken(ctx context.Context, slug string) (string, error) {
+	defer s.observe(ctx, "PreviewToken", time.Now())
+	return s.store.PreviewToken(ctx, slug)
+}
+
+func (s *timedStore) RecordView(ctx context.Context, slug string) (int, error) {
+	defer s.observe(ctx, "RecordView", time.Now())
+	return s.store.RecordView(ctx, slug)
+}
+
+func (s *timedStore) Comments(ctx context.Context, slug string) ([]Comment, error) {
+	defer s.observe(ctx, "Comments", time.Now())
+	return s.store.Comments(ctx, slug)
+}
+
+func (s *timedStore) AddComment(ctx context.Context, slug string, comment Comment) error {
+	defer s.observe(ctx, "AddComment", time.Now())
+	return s.store.AddComment(ctx, slug, comment)
+}
+
+func (s *timedStore) Stats(ctx context.Context) (PostStats, error) {
+	defer s.observe(ctx, "Stats", time.Now())
+	return s.store.Stats(ctx)
+}
+
+func (s *timedStore) NewlyLive(ctx context.Context) ([]Post, error) {
+	defer s.observe(ctx, "NewlyLive", time.Now())
+	return s.store.NewlyLive(ctx)
+}
+
+func (s *timedStore) Ping(ctx context.Context) error {
+	defer s.observe(ctx, "Ping", time.Now())
+	return s.store.Ping(ctx)
+}
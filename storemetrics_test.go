@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A PostStore whose Get takes delay, standing in for a query that's slow
+type slowGetStore struct {
+	PostStore
+	delay time.Duration
+}
+
+func (s *slowGetStore) Get(ctx context.Context, slug string) (Post, bool, error) {
+	time.Sleep(s.delay)
+	return s.PostStore.Get(ctx, slug)
+}
+
+func TestSlowQueriesAreLogged(t *testing.T) {
+	logs := captureLog(t)
+	config := testConfig(t)
+	config.SlowQuery = 20 * time.Millisecond
+	b := NewBlog(config, &slowGetStore{PostStore: newFakeStore(DEFAULT_POSTS_PER_PAGE), delay: 50 * time.Millisecond})
+	t.Cleanup(b.Close)
+	b.markReady()
+	createLivePost(t, b.store, "secret-slug")
+	router := newRouter(b)
+
+	do(router, httptest.NewRequest(http.MethodGet, HOME, nil))
+	do(router, httptest.NewRequest(http.MethodGet, POST+"secret-slug", nil))
+
+	metrics := scrape(t, b)
+	for _, want := range []string{
+		`goblog_db_slow_queries_total{operation="Get"} 1`,
+		`goblog_db_query_duration_seconds_count{operation="Get"} 1`,
+		`goblog_db_query_duration_seconds_count{operation="Page"} 1`,
+	} {
+		if !strings.Contains(metrics, want) {
+			t.Errorf("GET %s doesn't report %s", METRICS, want)
+		}
+	}
+	if strings.Contains(metrics, `goblog_db_slow_queries_total{operation="Page"}`) {
+		t.Errorf("GET %s counts the fast Page query as slow", METRICS)
+	}
+
+	got := logs.String()
+	if !strings.Contains(got, "Slow query: Get took") {
+		t.Errorf("the slow Get wasn't logged:\n%s", got)
+	}
+	if strings.Contains(got, "Slow query: Page") || strings.Contains(got, "Slow query: Create") {
+		t.Errorf("a fast query was logged as slow:\n%s", got)
+	}
+	if strings.Contains(got, "secret-slug") {
+		t.Errorf("the slow query was logged with its arguments:\n%s", got)
+	}
+}
+
+func TestSlowQueriesAreCounted(t *testing.T) {
+	logs := captureLog(t)
+	b := newTestBlog(t, testConfig(t))
+	// Anything at all is slow
+	b.store = newTimedStore(b.store.(*timedStore).store, b.metrics, 0)
+
+	do(newRouter(b), httptest.NewRequest(http.MethodGet, HOME, nil))
+	if !strings.Contains(scrape(t, b), `goblog_db_slow_queries_total{operation="Page"} 1`) {
+		t.Errorf("GET %s doesn't count the slow Page query", METRICS)
+	}
+	if !strings.Contains(logs.String(), "Slow query: Page took") {
+		t.Errorf("the slow Page query wasn't logged:\n%s", logs)
+	}
+}
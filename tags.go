@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// A label posts can be grouped under, a post can have many tags and a tag many posts
+type Tag struct {
+	Name string `json:"name"` // Normalized like a slug, so it's safe to use in the /tag/ url
+}
+
+// Type used to parse templates on the page listing a tag's posts
+type TagPage struct {
+	HomePage
+	Tag string
+}
+
+// Splits the comma separated tags field of the post forms into Tags
+func parseTags(raw string) []Tag {
+	var tags []Tag
+	for _, name := range strings.Split(raw, ",") {
+		tags = append(tags, Tag{Name: name})
+	}
+	return normalizeTags(tags)
+}
+
+// The post's tags joined back into the comma separated form the post forms take them in
+func (p Post) TagList() string {
+	names := make([]string, len(p.Tags))
+	for i, tag := range p.Tags {
+		names[i] = tag.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// Normalizes every tag name, dropping any that end up empty or duplicated
+func normalizeTags(tags []Tag) []Tag {
+	seen := map[string]bool{}
+	normalized := []Tag{}
+	for _, tag := range tags {
+		name, err := normalizeSlug(tag.Name)
+		if err != nil || seen[name] {
+			continue
+		}
+		seen[name] = true
+		normalized = append(normalized, Tag{Name: name})
+	}
+	return normalized
+}
+
+// Handles /tag/<name>/, listing the published posts with that tag
+func (b *Blog) tagHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSuffix(strings.TrimPrefix(strings.ToLower(r.URL.Path), TAG), "/")
+	if name == "" || strings.Contains(name, "/") {
+		b.notFoundHandler(w, r)
+		return
+	}
+
+	ctx, cancel := queryContext(r)
+	defer cancel()
+
+	tagPage, err := b.store.Tagged(ctx, name, requestedPage(r))
+	if err != nil {
+		b.renderError(w, r, dbErrorStatus(err), fmt.Errorf("failed to load posts tagged %q: %w", name, err))
+		return
+	}
+	b.prepare(tagPage.Posts)
+
+	b.renderTemplate(w, r, "tag.html", tagPage)
+}
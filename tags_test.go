@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want []Tag
+	}{
+		{raw: "", want: []Tag{}},
+		{raw: "go", want: []Tag{{"go"}}},
+		{raw: "Go, Web Dev,go,, ??", want: []Tag{{"go"}, {"web-dev"}}},
+	}
+	for _, tt := range tests {
+		if got := parseTags(tt.raw); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseTags(%q) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func testTags(t *testing.T, store PostStore) {
+	ctx := context.Background()
+	createPost(t, store, Post{Header: "Tagged", Content: "Words", Slug: "tagged", Author: "Tester", Published: true, Tags: []Tag{{"web"}, {"go"}}})
+	if got := getPost(t, store, "tagged").Tags; !reflect.DeepEqual(got, []Tag{{"go"}, {"web"}}) {
+		t.Errorf("Tags = %v after Create, want [go web]", got)
+	}
+
+	p := getPost(t, store, "tagged")
+	p.Tags = []Tag{{"databases"}, {"go"}}
+	if err := store.Update(ctx, p); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got := getPost(t, store, "tagged").Tags; !reflect.DeepEqual(got, []Tag{{"databases"}, {"go"}}) {
+		t.Errorf("Tags = %v after Update, want [databases go]", got)
+	}
+
+	for tag, want := range map[string]int{"go": 1, "databases": 1, "web": 0} {
+		page, err := store.Tagged(ctx, tag, 1)
+		if err != nil {
+			t.Fatalf("Tagged(%q): %v", tag, err)
+		}
+		if len(page.Posts) != want {
+			t.Errorf("Tagged(%q) has %d posts, want %d", tag, len(page.Posts), want)
+		}
+	}
+}
+
+func TestTagPage(t *testing.T) {
+	b := newTestBlog(t, testConfig(t))
+	createPost(t, b.store, Post{Header: "About Go", Content: "Words", Slug: "about-go", Author: "Tester", Published: true, Tags: []Tag{{"go"}}})
+	createPost(t, b.store, Post{Header: "About Rust", Content: "Words", Slug: "about-rust", Author: "Tester", Published: true, Tags: []Tag{{"rust"}}})
+	router := newRouter(b)
+
+	w := do(router, httptest.NewRequest(http.MethodGet, TAG+"Go/", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "About Go") || strings.Contains(w.Body.String(), "About Rust") {
+		t.Errorf("GET %sGo/ responded %d, want just the post tagged go:\n%s", TAG, w.Code, w.Body.String())
+	}
+	if w := do(router, httptest.NewRequest(http.MethodGet, TAG, nil)); w.Code != http.StatusNotFound {
+		t.Errorf("GET %s responded %d, want %d", TAG, w.Code, http.StatusNotFound)
+	}
+}
+
+func TestTagPagesNeedATag(t *testing.T) {
+	b := newClosedDBBlog(t)
+	for _, path := range []string{TAG, TAG + "go/extra"} {
+		if w := do(newRouter(b), httptest.NewRequest(http.MethodGet, path, nil)); w.Code != http.StatusNotFound {
+			t.Errorf("GET %s responded %d, want %d", path, w.Code, http.StatusNotFound)
+		}
+	}
+}
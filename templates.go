@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//go:embed views/*.html
+var viewFiles embed.FS // Bundled into the binary, so it runs without a views/ directory alongside it
+
+// Views starting with this aren't pages, they define templates the pages share like "pagination"
+const PARTIAL_PREFIX = "_"
+
+// Functions every view can call, on top of the data it's rendered with
+func (b *Blog) templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		// The branding for the header, footer and <title>, so the handlers don't each have to pass it in
+		"site": func() Site { return b.config.Site },
+		// Dates in the DISPLAY_TIMEZONE and DATE_FORMAT
+		"formatDate":     b.formatDate,
+		"formatDateTime": b.formatDateTime,
+		// The zone the post forms take publish dates in
+		"displayTimezone": func() *time.Location { return b.config.DisplayTimezone },
+		// Times relative to now, like "3 days ago". Only for pages that are never cached, or it'd go stale
+		"humanizeTime": humanizeTime,
+	}
+}
+
+// Every view parsed with the partials and keyed by file name e.g. "home.html". NewBlog parses them once at startup
+func parseTemplates(funcs template.FuncMap) map[string]*template.Template {
+	entries, err := fs.ReadDir(viewFiles, "views")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	parsed := map[string]*template.Template{}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), PARTIAL_PREFIX) {
+			continue
+		}
+		// A broken template is a bug in the build, so fail on startup rather than on the first request that needs it
+		parsed[entry.Name()] = template.Must(template.New(entry.Name()).Funcs(funcs).ParseFS(viewFiles, "views/"+entry.Name(), "views/"+PARTIAL_PREFIX+"*.html"))
+	}
+	return parsed
+}
+
+// Responds with the pre-parsed view called name, executed with data
+func (b *Blog) renderTemplate(w http.ResponseWriter, r *http.Request, name string, data interface{}) {
+	b.renderTemplateStatus(w, r, http.StatusOK, name, data)
+}
+
+// Like renderTemplate, but responds with status. The page is executed in full before any of it is written, so a view
+// that fails partway through gets the error page and a 500 rather than half a page with the status already sent
+func (b *Blog) renderTemplateStatus(w http.ResponseWriter, r *http.Request, status int, name string, data interface{}) {
+	page, err := b.executeTemplate(name, data)
+	if err != nil {
+		logf(r.Context(), "Failed to execute %s: %v", name, err)
+		status = http.StatusInternalServerError
+		// Whatever the handler set for caching the page it meant to send doesn't apply to the error page
+		w.Header().Del("ETag")
+		w.Header().Del("Last-Modified")
+		w.Header().Set("Cache-Control", "no-store")
+		if page, err = b.executeTemplate("500.html", ErrorPage{Status: status, StatusText: http.StatusText(status)}); err != nil {
+			logf(r.Context(), "Failed to execute 500.html: %v", err)
+			http.Error(w, "Failed to load the page.", status)
+			return
+		}
+	}
+	// Set rather than sniffed from the page, as it's needed before the first write to decide whether to gzip it
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(page)
+}
+
+func (b *Blog) executeTemplate(name string, data interface{}) ([]byte, error) {
+	t, ok := b.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("no template called %s", name)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Type used to parse the error page
+type ErrorPage struct {
+	Status     int
+	StatusText string // e.g. "Internal Server Error"
+}
+
+// Logs err and responds with the friendly error page, for when something's gone wrong on our end rather than with the request.
+// err never reaches the reader, it can say as much about the internals as is useful in the logs
+func (b *Blog) renderError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	logf(r.Context(), "Responding with a %d: %v", status, err)
+	b.renderTemplateStatus(w, r, status, "500.html", ErrorPage{Status: status, StatusText: http.StatusText(status)})
+}
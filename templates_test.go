@@ -0,0 +1,110 @@
+package main
+
+import (
+	"html/template"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestTemplatesAreParsedOnce(t *testing.T) {
+	b := newTestBlog(t, testConfig(t))
+	createLivePost(t, b.store, "live")
+
+	entries, err := fs.ReadDir(viewFiles, "views")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed := map[string]*template.Template{}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), PARTIAL_PREFIX) {
+			if _, ok := b.templates[entry.Name()]; ok {
+				t.Errorf("partial %s was parsed as a page", entry.Name())
+			}
+			continue
+		}
+		tmpl, ok := b.templates[entry.Name()]
+		if !ok {
+			t.Errorf("%s wasn't parsed by NewBlog", entry.Name())
+		}
+		parsed[entry.Name()] = tmpl
+	}
+
+	// Run from somewhere without a views/ directory, so any handler parsing from disk would fail
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	router := newRouter(b)
+	for _, path := range []string{HOME, HOME, POST + "live", POST + "missing", ABOUT} {
+		if w := do(router, httptest.NewRequest(http.MethodGet, path, nil)); w.Code == http.StatusInternalServerError {
+			t.Errorf("GET %s responded %d without a views/ directory", path, w.Code)
+		}
+	}
+	for name, tmpl := range parsed {
+		if b.templates[name] != tmpl {
+			t.Errorf("%s was parsed again while serving", name)
+		}
+	}
+}
+
+func TestTemplateErrorsRespondWith500(t *testing.T) {
+	logs := captureLog(t)
+	b := newFakeBlog(t, testConfig(t))
+	createLivePost(t, b.store, "live")
+	// Fails halfway through, after some of the page has been executed
+	b.templates["post.html"] = template.Must(template.New("post.html").Parse(`<h1>{{ .Header }}</h1>{{ .NoSuchField }}`))
+
+	w := do(newRouter(b), httptest.NewRequest(http.MethodGet, POST+"live", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("GET %slive with a broken view responded %d, want %d", POST, w.Code, http.StatusInternalServerError)
+	}
+	body := w.Body.String()
+	if strings.Contains(body, "<h1>Post live</h1>") {
+		t.Errorf("GET %slive sent the half of the page that executed:\n%s", POST, body)
+	}
+	if !strings.Contains(body, "Sorry! Something went wrong on our end") || !strings.HasSuffix(strings.TrimSpace(body), "</html>") {
+		t.Errorf("GET %slive should get the whole 500 page:\n%s", POST, body)
+	}
+	if got := w.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("GET %slive has Content-Type %q, want text/html; charset=utf-8", POST, got)
+	}
+	if !strings.Contains(logs.String(), "Failed to execute post.html") || !strings.Contains(logs.String(), "NoSuchField") {
+		t.Errorf("the template error wasn't logged:\n%s", logs)
+	}
+}
+
+func TestTemplateErrorsArentCached(t *testing.T) {
+	captureLog(t)
+	b := newFakeBlog(t, testConfig(t))
+	b.templates["broken.html"] = template.Must(template.New("broken.html").Parse(`{{ .NoSuchField }}`))
+
+	w := httptest.NewRecorder()
+	w.Header().Set("ETag", `"abc"`)
+	w.Header().Set("Last-Modified", "Mon, 01 Mar 2021 00:00:00 GMT")
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	b.renderTemplateStatus(w, httptest.NewRequest(http.MethodGet, HOME, nil), http.StatusNotFound, "broken.html", struct{}{})
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("renderTemplateStatus with a broken view responded %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	if w.Header().Get("ETag") != "" || w.Header().Get("Last-Modified") != "" || w.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("the 500 page kept the broken page's caching headers: %v", w.Header())
+	}
+
+	// Without even the 500 page there's still a 500
+	delete(b.templates, "500.html")
+	w = httptest.NewRecorder()
+	b.renderTemplate(w, httptest.NewRequest(http.MethodGet, HOME, nil), "broken.html", struct{}{})
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "Failed to load the page.") {
+		t.Errorf("renderTemplate without a 500 page responded %d %q, want %d", w.Code, w.Body.String(), http.StatusInternalServerError)
+	}
+}
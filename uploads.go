@@ -0,0 +1,153 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	DEFAULT_UPLOAD_DIR      = "uploads" // Where uploaded images are saved, override with UPLOAD_DIR
+	DEFAULT_MAX_UPLOAD_SIZE = 5 << 20   // Bytes an uploaded image may be, override with MAX_UPLOAD_SIZE
+
+	UPLOAD_FIELD         = "image"                               // The multipart field the upload form sends the file in
+	UPLOAD_CACHE_CONTROL = "public, max-age=31536000, immutable" // Every upload gets a new name, so a url's content never changes
+)
+
+// The image types authors can upload and the extension each is saved with. SVG is left out as it can carry script
+var imageExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// Saves uploaded images, the handlers never write them anywhere else so an object store could stand in for the disk
+type ImageStore interface {
+	Save(name string, image io.Reader) error
+}
+
+// The ImageStore writing to a directory on disk, which is served under UPLOADS
+type DirImageStore struct {
+	dir string
+}
+
+func NewDirImageStore(dir string) *DirImageStore {
+	return &DirImageStore{dir: dir}
+}
+
+// Writes image to the file called name, which must not exist yet
+func (s *DirImageStore) Save(name string, image io.Reader) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	// O_EXCL so a name collision fails rather than overwriting an image a post already embeds
+	f, err := os.OpenFile(filepath.Join(s.dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, image); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	return f.Close()
+}
+
+// Handles the image upload form, saving the image under a new random name and responding with the url to embed it at
+func (b *Blog) uploadHandler(w http.ResponseWriter, r *http.Request) {
+	maxSize := b.config.MaxUploadSize
+	// Leaves room for the rest of the form around the image, anything past that is refused without being read
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+1<<20)
+	if err := r.ParseMultipartForm(maxSize); err != nil {
+		// Almost always the body being cut off for going over the limit
+		b.generateResulTemplate(w, r, http.StatusRequestEntityTooLarge, &CRUDResult{Message: fmt.Sprintf("Sorry! That upload couldn't be read, images can be at most %d KB", maxSize>>10)})
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+	if !validCSRF(r) {
+		http.Error(w, "This form has expired, please go back, refresh and try again.", http.StatusForbidden)
+		return
+	}
+
+	file, header, err := r.FormFile(UPLOAD_FIELD)
+	if err != nil {
+		b.generateResulTemplate(w, r, http.StatusBadRequest, &CRUDResult{Message: "Sorry! Please pick an image to upload"})
+		return
+	}
+	defer file.Close()
+	if header.Size > maxSize {
+		b.generateResulTemplate(w, r, http.StatusRequestEntityTooLarge, &CRUDResult{Message: fmt.Sprintf("Sorry! Images can be at most %d KB", maxSize>>10)})
+		return
+	}
+
+	ext, err := imageExtension(file)
+	if err != nil {
+		b.generateResulTemplate(w, r, http.StatusUnsupportedMediaType, &CRUDResult{Message: "Sorry! " + err.Error()})
+		return
+	}
+	name, err := uploadName(ext)
+	if err != nil {
+		logf(r.Context(), "Failed to generate an upload name: %v", err)
+		b.generateResulTemplate(w, r, http.StatusInternalServerError, &CRUDResult{Message: "Sorry! Something went wrong saving your image, please try again"})
+		return
+	}
+	if err := b.images.Save(name, file); err != nil {
+		logf(r.Context(), "Failed to save upload %s: %v", name, err)
+		b.generateResulTemplate(w, r, http.StatusInternalServerError, &CRUDResult{Message: "Sorry! Something went wrong saving your image, please try again"})
+		return
+	}
+
+	url := UPLOADS + name
+	w.Header().Set("Location", url)
+	b.generateResulTemplate(w, r, http.StatusCreated, &CRUDResult{Message: fmt.Sprintf("Your image is uploaded, embed it in a post with ![](%s)", url)})
+}
+
+// Works out what type of image file is from its first bytes rather than trusting the name or type the browser sent,
+// rewinding it afterwards so it can be saved in full
+func imageExtension(file io.ReadSeeker) (string, error) {
+	head := make([]byte, 512) // All DetectContentType ever looks at
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", errors.New("that image couldn't be read")
+	}
+	contentType := http.DetectContentType(head[:n])
+	ext, ok := imageExtensions[contentType]
+	if !ok {
+		return "", fmt.Errorf("only PNG, JPEG, GIF and WebP images can be uploaded, that was %s", contentType)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", errors.New("that image couldn't be read")
+	}
+	return ext, nil
+}
+
+// A random file name with ext, nothing from the client goes into it so it can't collide or climb out of the upload dir
+func uploadName(ext string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b) + ext, nil
+}
+
+// Serves the images saved in dir under UPLOADS, without directory listings
+func (b *Blog) uploadsHandler(dir string) http.Handler {
+	fileServer := http.StripPrefix(UPLOADS, http.FileServer(http.Dir(dir)))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/") || strings.Contains(r.URL.Path, "..") {
+			b.notFoundHandler(w, r)
+			return
+		}
+		w.Header().Set("Cache-Control", UPLOAD_CACHE_CONTROL)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		fileServer.ServeHTTP(w, r)
+	})
+}
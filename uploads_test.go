@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// A 1x1 transparent PNG
+var testPNG, _ = base64.StdEncoding.DecodeString("iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNkYPhfDwAChwGA60e6kgAAAABJRU5ErkJggg==")
+
+// The upload form POSTing image as a file called name, logged in as the admin and with a valid CSRF token
+func uploadRequest(t *testing.T, name string, image []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField(CSRF_FIELD, TEST_CSRF_TOKEN)
+	if image != nil {
+		part, err := form.CreateFormFile(UPLOAD_FIELD, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(image)
+	}
+	form.Close()
+
+	req := adminRequest(http.MethodPost, UPLOAD, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.AddCookie(&http.Cookie{Name: CSRF_COOKIE, Value: TEST_CSRF_TOKEN})
+	return req
+}
+
+func TestUploadImage(t *testing.T) {
+	config := testConfig(t)
+	b := newFakeBlog(t, config)
+	router := newRouter(b)
+
+	// The name the browser sends is ignored, so it can't put the file anywhere else
+	w := do(router, uploadRequest(t, "../../escape.png", testPNG))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST %s responded %d, want %d:\n%s", UPLOAD, w.Code, http.StatusCreated, w.Body.String())
+	}
+	location := w.Header().Get("Location")
+	if !strings.HasPrefix(location, UPLOADS) || !strings.HasSuffix(location, ".png") || strings.Contains(location, "escape") {
+		t.Fatalf("POST %s Location = %q, want a new .png under %s", UPLOAD, location, UPLOADS)
+	}
+	if !strings.Contains(w.Body.String(), "![]("+location+")") {
+		t.Errorf("POST %s doesn't say how to embed the image:\n%s", UPLOAD, w.Body.String())
+	}
+	saved, err := os.ReadFile(filepath.Join(config.UploadDir, strings.TrimPrefix(location, UPLOADS)))
+	if err != nil || !bytes.Equal(saved, testPNG) {
+		t.Errorf("the uploaded image wasn't saved to UPLOAD_DIR as sent, error %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(filepath.Dir(config.UploadDir)), "escape.png")); err == nil {
+		t.Errorf("the upload was saved outside UPLOAD_DIR")
+	}
+
+	w = do(router, httptest.NewRequest(http.MethodGet, location, nil))
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), testPNG) {
+		t.Errorf("GET %s responded %d, want %d with the image", location, w.Code, http.StatusOK)
+	}
+	if got := w.Header().Get("Content-Type"); got != "image/png" {
+		t.Errorf("GET %s Content-Type = %q, want image/png", location, got)
+	}
+	if got := w.Header().Get("Cache-Control"); got != UPLOAD_CACHE_CONTROL {
+		t.Errorf("GET %s Cache-Control = %q, want %q", location, got, UPLOAD_CACHE_CONTROL)
+	}
+
+	// A second upload of the same image gets a name of its own
+	if w := do(router, uploadRequest(t, "escape.png", testPNG)); w.Header().Get("Location") == location {
+		t.Errorf("uploading the image again reused %s", location)
+	}
+}
+
+func TestUploadRejects(t *testing.T) {
+	config := testConfig(t)
+	config.MaxUploadSize = 1 << 10
+	b := newFakeBlog(t, config)
+	router := newRouter(b)
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{name: "no image", req: uploadRequest(t, "", nil), want: http.StatusBadRequest},
+		{name: "text", req: uploadRequest(t, "notes.png", []byte("just some words, not a picture")), want: http.StatusUnsupportedMediaType},
+		{name: "svg", req: uploadRequest(t, "logo.svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`)), want: http.StatusUnsupportedMediaType},
+		{name: "too big", req: uploadRequest(t, "big.png", append(append([]byte{}, testPNG...), make([]byte, 2<<10)...)), want: http.StatusRequestEntityTooLarge},
+		{name: "far too big", req: uploadRequest(t, "huge.png", append(append([]byte{}, testPNG...), make([]byte, 2<<20)...)), want: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := do(router, tt.req); w.Code != tt.want {
+				t.Errorf("POST %s responded %d, want %d", UPLOAD, w.Code, tt.want)
+			}
+		})
+	}
+
+	req := uploadRequest(t, "pixel.png", testPNG)
+	req.Header.Del("Cookie")
+	if w := do(router, req); w.Code != http.StatusForbidden {
+		t.Errorf("POST %s without a CSRF cookie responded %d, want %d", UPLOAD, w.Code, http.StatusForbidden)
+	}
+
+	if entries, _ := os.ReadDir(config.UploadDir); len(entries) != 0 {
+		t.Errorf("UPLOAD_DIR has %d files, want the rejected uploads not to be saved", len(entries))
+	}
+	for _, path := range []string{UPLOADS, UPLOADS + "missing.png"} {
+		if w := do(router, httptest.NewRequest(http.MethodGet, path, nil)); w.Code != http.StatusNotFound {
+			t.Errorf("GET %s responded %d, want %d", path, w.Code, http.StatusNotFound)
+		}
+	}
+}
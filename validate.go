@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	MAX_HEADER_LENGTH  = 200   // Characters allowed in a post's header
+	MAX_CONTENT_LENGTH = 50000 // Characters allowed in a post's content
+	MAX_AUTHOR_LENGTH  = 100   // Characters allowed in a post's author
+
+	PUBLISHED_AT_FORMAT      = "2006-01-02"       // A publish date without a time, what a date input sends
+	PUBLISHED_AT_TIME_FORMAT = "2006-01-02T15:04" // How the post forms submit the publish date and time in UTC, what a datetime-local input sends
+	UPDATED_AT_FORMAT        = time.RFC3339Nano   // How the edit form holds on to the updated_at it was loaded with, to the nanosecond so it compares equal
+)
+
+// Checks a post being saved fits within the length limits, naming the field that doesn't
+func validatePost(post Post) error {
+	if n := utf8.RuneCountInString(post.Header); n > MAX_HEADER_LENGTH {
+		return fmt.Errorf("the header is %d characters long, it can be at most %d", n, MAX_HEADER_LENGTH)
+	}
+	if n := utf8.RuneCountInString(post.Content); n > MAX_CONTENT_LENGTH {
+		return fmt.Errorf("the content is %d characters long, it can be at most %d", n, MAX_CONTENT_LENGTH)
+	}
+	if n := utf8.RuneCountInString(post.Author); n > MAX_AUTHOR_LENGTH {
+		return fmt.Errorf("the author is %d characters long, it can be at most %d", n, MAX_AUTHOR_LENGTH)
+	}
+	return nil
+}
+
+// Checks a post's canonical url is a full http or https url, as search engines ignore relative ones. Empty is fine, it means
+// the post is the original
+func parseCanonicalURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("the canonical url should be a full url like https://example.com/post, got %q", raw)
+	}
+	return u.String(), nil
+}
+
+// Parses the publish date from a post form, with or without a time, as a time in loc. An empty one is the zero time so the
+// store keeps its default
+func parsePublishedAt(raw string, loc *time.Location) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation(PUBLISHED_AT_TIME_FORMAT, raw, loc); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation(PUBLISHED_AT_FORMAT, raw, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("the publish date should be a date like 2021-03-14, or a date and time like 2021-03-14T09:30, got %q", raw)
+	}
+	return t, nil
+}
+
+// Parses the updated_at the edit form was loaded with, an empty one is the zero time so the post is saved whatever's changed
+func parseUpdatedAt(raw string) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(UPDATED_AT_FORMAT, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("the form's last updated time should look like %s, got %q", UPDATED_AT_FORMAT, raw)
+	}
+	return t, nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidatePost(t *testing.T) {
+	tests := []struct {
+		name    string
+		post    Post
+		wantErr string // Empty for a valid post
+	}{
+		{name: "empty", post: Post{}},
+		{name: "at the limits", post: Post{
+			Header:  strings.Repeat("h", MAX_HEADER_LENGTH),
+			Content: strings.Repeat("c", MAX_CONTENT_LENGTH),
+			Author:  strings.Repeat("a", MAX_AUTHOR_LENGTH),
+		}},
+		{name: "limits count characters not bytes", post: Post{Header: strings.Repeat("é", MAX_HEADER_LENGTH)}},
+		{name: "header too long", post: Post{Header: strings.Repeat("h", MAX_HEADER_LENGTH+1)}, wantErr: "the header"},
+		{name: "content too long", post: Post{Content: strings.Repeat("c", MAX_CONTENT_LENGTH+1)}, wantErr: "the content"},
+		{name: "author too long", post: Post{Author: strings.Repeat("a", MAX_AUTHOR_LENGTH+1)}, wantErr: "the author"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePost(tt.post)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validatePost = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validatePost = %v, want an error about %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOverlongPostsArentSaved(t *testing.T) {
+	// They're turned away before Postgres is touched, so nothing can be saved
+	b := newClosedDBBlog(t)
+	header := strings.Repeat("h", MAX_HEADER_LENGTH+1)
+	router := newRouter(b)
+
+	w := do(router, formRequest(SAVE+SAVE_ADD, url.Values{"header": {header}, "content": {"Words"}, "slug": {"overlong"}}))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("POST %s with an overlong header responded %d, want %d", SAVE+SAVE_ADD, w.Code, http.StatusBadRequest)
+	}
+	if !strings.Contains(w.Body.String(), "the header is") {
+		t.Errorf("POST %s with an overlong header didn't say the header was too long:\n%s", SAVE+SAVE_ADD, w.Body.String())
+	}
+
+	w = do(router, apiRequest(http.MethodPost, API_POSTS, `{"header": "`+header+`", "content": "Words", "slug": "overlong"}`))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("POST %s with an overlong header responded %d, want %d", API_POSTS, w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestParsePublishedAt(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    time.Time
+		wantErr bool
+	}{
+		{raw: ""},
+		{raw: "2021-03-14", want: time.Date(2021, 3, 14, 0, 0, 0, 0, time.UTC)},
+		{raw: "2021-03-14T09:30", want: time.Date(2021, 3, 14, 9, 30, 0, 0, time.UTC)},
+		{raw: "14/03/2021", wantErr: true},
+		{raw: "2021-02-30", wantErr: true},
+		{raw: "2021-03-14 09:30", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parsePublishedAt(tt.raw, time.UTC)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parsePublishedAt(%q) = %v, want an error", tt.raw, got)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parsePublishedAt(%q) = %v, %v, want %v", tt.raw, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseCanonicalURL(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{raw: ""},
+		{raw: "  ", want: ""},
+		{raw: "https://elsewhere.example.com/post", want: "https://elsewhere.example.com/post"},
+		{raw: " http://elsewhere.example.com/post?id=1 ", want: "http://elsewhere.example.com/post?id=1"},
+		{raw: "/post/relative", wantErr: true},
+		{raw: "elsewhere.example.com/post", wantErr: true},
+		{raw: "javascript:alert(1)", wantErr: true},
+		{raw: "ftp://elsewhere.example.com/post", wantErr: true},
+		{raw: "https://", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseCanonicalURL(tt.raw)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseCanonicalURL(%q) = %q, want an error", tt.raw, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseCanonicalURL(%q) = %q, %v, want %q", tt.raw, got, err, tt.want)
+		}
+	}
+}
+
+func TestCanonicalURLAndNoIndex(t *testing.T) {
+	b := newTestBlog(t, testConfig(t))
+	createLivePost(t, b.store, "plain")
+	createLivePost(t, b.store, "cross-posted")
+	router := newRouter(b)
+
+	form := url.Values{"header": {"Cross-posted"}, "content": {"Words"}, "slug": {"cross-posted"},
+		"canonical_url": {"https://elsewhere.example.com/original"}, "no_index": {"on"}}
+	if w := do(router, formRequest(SAVE+SAVE_UPDATE, form)); w.Code != http.StatusSeeOther {
+		t.Fatalf("saving the canonical url and noindex responded %d, want %d", w.Code, http.StatusSeeOther)
+	}
+	if p := getPost(t, b.store, "cross-posted"); p.CanonicalURL != "https://elsewhere.example.com/original" || !p.NoIndex {
+		t.Errorf("the edit form saved canonical url %q and noindex %v, want both", p.CanonicalURL, p.NoIndex)
+	}
+
+	body := do(router, httptest.NewRequest(http.MethodGet, POST+"cross-posted", nil)).Body.String()
+	for _, want := range []string{`<link rel="canonical" href="https://elsewhere.example.com/original">`, `<meta name="robots" content="noindex">`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("GET %scross-posted is missing %s", POST, want)
+		}
+	}
+	body = do(router, httptest.NewRequest(http.MethodGet, POST+"plain", nil)).Body.String()
+	if strings.Contains(body, `rel="canonical"`) || strings.Contains(body, "noindex") {
+		t.Errorf("GET %splain has a canonical url or noindex it wasn't given", POST)
+	}
+
+	// Unticking the box indexes it again
+	form.Del("no_index")
+	form.Set("canonical_url", "")
+	do(router, formRequest(SAVE+SAVE_UPDATE, form))
+	if p := getPost(t, b.store, "cross-posted"); p.CanonicalURL != "" || p.NoIndex {
+		t.Errorf("clearing the canonical url and noindex left %q and %v", p.CanonicalURL, p.NoIndex)
+	}
+
+	form.Set("canonical_url", "/relative")
+	if w := do(router, formRequest(SAVE+SAVE_UPDATE, form)); w.Code != http.StatusBadRequest {
+		t.Errorf("saving a relative canonical url responded %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+const VIEW_DEBOUNCE = 30 * time.Minute // An IP viewing the same post again within this isn't counted twice
+
+// Remembers which IPs have recently viewed which posts, so refreshing a post doesn't inflate its views
+type viewDebouncer struct {
+	mu     sync.Mutex
+	seen   map[string]time.Time // Keyed by ip and slug, when that IP's view of the post was last counted
+	window time.Duration
+}
+
+// Starts a debouncer whose cleanup runs until stop is closed
+func newViewDebouncer(window time.Duration, stop <-chan struct{}) *viewDebouncer {
+	d := &viewDebouncer{seen: map[string]time.Time{}, window: window}
+	go d.cleanup(stop)
+	return d
+}
+
+// Reports whether this view of slug from ip should be counted, remembering it if so
+func (d *viewDebouncer) shouldCount(ip, slug string) bool {
+	key := ip + " " + slug // Slugs can't contain spaces, so this can't collide
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if last, ok := d.seen[key]; ok && time.Since(last) < d.window {
+		return false
+	}
+	d.seen[key] = time.Now()
+	return true
+}
+
+// Periodically forgets views older than the window, so the map doesn't grow forever, until stop is closed
+func (d *viewDebouncer) cleanup(stop <-chan struct{}) {
+	ticker := time.NewTicker(d.window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			d.mu.Lock()
+			for key, last := range d.seen {
+				if time.Since(last) > d.window {
+					delete(d.seen, key)
+				}
+			}
+			d.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestViewDebouncer(t *testing.T) {
+	d := &viewDebouncer{seen: map[string]time.Time{}, window: 50 * time.Millisecond}
+	steps := []struct {
+		ip, slug string
+		want     bool
+	}{
+		{"192.0.2.1", "post", true},
+		{"192.0.2.1", "post", false},
+		{"192.0.2.2", "post", true},
+		{"192.0.2.1", "other", true},
+	}
+	for _, step := range steps {
+		if got := d.shouldCount(step.ip, step.slug); got != step.want {
+			t.Errorf("shouldCount(%q, %q) = %v, want %v", step.ip, step.slug, got, step.want)
+		}
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if !d.shouldCount("192.0.2.1", "post") {
+		t.Error("shouldCount didn't count a view once the window had passed")
+	}
+}
+
+func TestViewDebouncerCleanup(t *testing.T) {
+	d := &viewDebouncer{seen: map[string]time.Time{}, window: 10 * time.Millisecond}
+	d.shouldCount("192.0.2.1", "post")
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		d.cleanup(stop)
+		close(stopped)
+	}()
+
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(5 * time.Millisecond) {
+		d.mu.Lock()
+		left := len(d.seen)
+		d.mu.Unlock()
+		if left == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("cleanup still remembers %d views long after the window", left)
+		}
+	}
+
+	close(stop)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Error("cleanup kept running after stop was closed")
+	}
+}
+
+func TestPostViewsAreCounted(t *testing.T) {
+	b := newFakeBlog(t, testConfig(t))
+	createLivePost(t, b.store, "popular")
+	router := newRouter(b)
+
+	var body string
+	for _, ip := range []string{"192.0.2.1", "192.0.2.2", "192.0.2.1"} {
+		req := httptest.NewRequest(http.MethodGet, POST+"popular", nil)
+		req.RemoteAddr = ip + ":1234"
+		body = do(router, req).Body.String()
+	}
+	if got := getPost(t, b.store, "popular").Views; got != 2 {
+		t.Errorf("two readers opening the post, one twice, counted %d views, want 2", got)
+	}
+	if !strings.Contains(body, "2 views") {
+		t.Errorf("GET %spopular doesn't show its 2 views", POST)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	WEBHOOK_SIGNATURE_HEADER = "X-Blog-Signature" // sha256= then the hex HMAC-SHA256 of the body, keyed with WEBHOOK_SECRET
+	WEBHOOK_ATTEMPTS         = 3                  // Tries at delivering each event before it's given up on
+	WEBHOOK_TIMEOUT          = 10 * time.Second   // How long each try gets
+	WEBHOOK_RETRY_DELAY      = 2 * time.Second    // Wait before the first retry, doubling each time
+)
+
+// What the webhook is sent when a post goes live
+type PublishEvent struct {
+	Slug  string `json:"slug"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// Tells other services about posts going live, e.g. to rebuild a static copy of the blog. An interface so it can be
+// faked in tests
+type Webhook interface {
+	Published(ctx context.Context, event PublishEvent) error
+}
+
+// The Webhook the configuration asks for, nil when there isn't one
+func newWebhook(config Config) Webhook {
+	if config.WebhookURL == "" {
+		return nil
+	}
+	return &httpWebhook{url: config.WebhookURL, secret: []byte(config.WebhookSecret), client: &http.Client{Timeout: WEBHOOK_TIMEOUT}}
+}
+
+// POSTs each event as JSON to url, signed with secret so the receiver can tell it came from the blog
+type httpWebhook struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+// Delivers event, retrying failed tries with a growing delay until WEBHOOK_ATTEMPTS runs out or ctx is done. The
+// error is from the last try
+func (h *httpWebhook) Published(ctx context.Context, event PublishEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	delay := WEBHOOK_RETRY_DELAY
+	for attempt := 1; ; attempt++ {
+		err = h.deliver(ctx, body)
+		if err == nil || attempt == WEBHOOK_ATTEMPTS {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func (h *httpWebhook) deliver(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WEBHOOK_SIGNATURE_HEADER, webhookSignature(h.secret, body))
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("the webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+// What WEBHOOK_SIGNATURE_HEADER is set to for body, which receivers should recompute and compare in constant time
+func webhookSignature(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Tells the webhook about every post that's gone live since it was last told, and empties the page cache if any have.
+// Which posts it's been told about is kept in the DB, so posts going live while the blog was down are still sent once
+// it's back
+func (b *Blog) announceLive(ctx context.Context) error {
+	posts, err := b.store.NewlyLive(ctx)
+	if err != nil {
+		return err
+	}
+	if len(posts) > 0 {
+		b.cache.invalidate()
+	}
+	for _, p := range posts {
+		b.sendPublished(ctx, p)
+	}
+	return nil
+}
+
+// Sends the webhook the post in the background, so nothing waits on it. ctx is only used for its request ID in the
+// logs, as the request will usually be long gone by the time it's sent
+func (b *Blog) sendPublished(ctx context.Context, p Post) {
+	if b.webhook == nil {
+		return
+	}
+	event := PublishEvent{Slug: p.Slug, Title: p.Header, URL: b.absoluteURL(nil, POST+p.Slug)}
+	go func() {
+		// Long enough for every try and the waits between them
+		sendCtx, cancel := context.WithTimeout(context.Background(), WEBHOOK_ATTEMPTS*WEBHOOK_TIMEOUT+(1<<WEBHOOK_ATTEMPTS)*WEBHOOK_RETRY_DELAY)
+		defer cancel()
+		if err := b.webhook.Published(sendCtx, event); err != nil {
+			logf(ctx, "Failed to send post %q to the webhook: %v", p.Slug, err)
+		}
+	}()
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// A Webhook that passes on the events it's sent, so tests can wait for those sent in the background
+type fakeWebhook struct {
+	sent chan PublishEvent
+}
+
+func newFakeWebhook() *fakeWebhook {
+	return &fakeWebhook{sent: make(chan PublishEvent, 10)}
+}
+
+func (h *fakeWebhook) Published(ctx context.Context, event PublishEvent) error {
+	h.sent <- event
+	return nil
+}
+
+// The next event sent, failing the test if there isn't one soon
+func (h *fakeWebhook) next(t *testing.T) PublishEvent {
+	t.Helper()
+	select {
+	case event := <-h.sent:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("the webhook wasn't sent anything")
+		return PublishEvent{}
+	}
+}
+
+// Fails the test if the webhook's sent anything
+func (h *fakeWebhook) none(t *testing.T, what string) {
+	t.Helper()
+	select {
+	case event := <-h.sent:
+		t.Errorf("%s sent the webhook %+v", what, event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// A Blog at https://blog.example.com sending its webhook to a fakeWebhook
+func newWebhookBlog(t *testing.T) (*Blog, *fakeWebhook) {
+	t.Helper()
+	config := testConfig(t)
+	config.BaseURL = "https://blog.example.com"
+	b := newFakeBlog(t, config)
+	webhook := newFakeWebhook()
+	b.webhook = webhook
+	return b, webhook
+}
+
+func TestPublishingSendsTheWebhook(t *testing.T) {
+	b, webhook := newWebhookBlog(t)
+	router := newRouter(b)
+
+	do(router, formRequest(SAVE+SAVE_ADD, url.Values{"header": {"Hello world"}, "content": {"Words"}, "slug": {"hello"}}))
+	webhook.none(t, "saving a draft")
+
+	do(router, formRequest(SAVE+SAVE_PUBLISH, url.Values{"slug": {"hello"}}))
+	want := PublishEvent{Slug: "hello", Title: "Hello world", URL: "https://blog.example.com/post/hello"}
+	if got := webhook.next(t); got != want {
+		t.Errorf("publishing sent the webhook %+v, want %+v", got, want)
+	}
+
+	do(router, formRequest(SAVE+SAVE_UPDATE, url.Values{"header": {"Edited"}, "content": {"Words"}, "slug": {"hello"}}))
+	do(router, formRequest(SAVE+SAVE_PUBLISH, url.Values{"slug": {"hello"}}))
+	webhook.none(t, "editing and publishing a post that's already live")
+}
+
+func TestCreatingThroughTheAPISendsTheWebhook(t *testing.T) {
+	b, webhook := newWebhookBlog(t)
+	router := newRouter(b)
+
+	do(router, apiRequest(http.MethodPost, API_POSTS, `{"header":"Live","slug":"live","content":"Words","published":true}`))
+	if got := webhook.next(t); got.Slug != "live" || got.URL != "https://blog.example.com/post/live" {
+		t.Errorf("creating a live post sent the webhook %+v, want it", got)
+	}
+
+	// Backdated posts, like ones imported from another blog, were live before anyone could be told
+	do(router, apiRequest(http.MethodPost, API_POSTS, `{"header":"Old","slug":"old","content":"Words","published":true,"published_at":"2019-01-01T00:00:00Z"}`))
+	do(router, apiRequest(http.MethodPost, API_POSTS, `{"header":"Draft","slug":"draft","content":"Words"}`))
+	webhook.none(t, "creating a backdated post and a draft")
+}
+
+func TestNewWebhook(t *testing.T) {
+	config := testConfig(t)
+	if h := newWebhook(config); h != nil {
+		t.Errorf("newWebhook without WEBHOOK_URL = %v, want none", h)
+	}
+	config.WebhookURL, config.WebhookSecret = "https://hooks.example.com/blog", "shh"
+	if h, ok := newWebhook(config).(*httpWebhook); !ok || h.url != config.WebhookURL || string(h.secret) != "shh" {
+		t.Errorf("newWebhook = %+v, want an httpWebhook to %s", h, config.WebhookURL)
+	}
+}
+
+func TestHTTPWebhook(t *testing.T) {
+	var tries int32
+	received := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		// The first try fails, so it has to be retried
+		if atomic.AddInt32(&tries, 1) == 1 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("the webhook was sent a %s of %s, want a POST of application/json", r.Method, r.Header.Get("Content-Type"))
+		}
+		if got, want := r.Header.Get(WEBHOOK_SIGNATURE_HEADER), webhookSignature([]byte("shh"), body); got != want {
+			t.Errorf("the webhook was signed %q, want %q", got, want)
+		}
+		received <- body
+	}))
+	defer server.Close()
+
+	h := &httpWebhook{url: server.URL, secret: []byte("shh"), client: server.Client()}
+	event := PublishEvent{Slug: "hello", Title: "Hello world", URL: "https://blog.example.com/post/hello"}
+	if err := h.Published(context.Background(), event); err != nil {
+		t.Fatalf("Published: %v", err)
+	}
+	if tries := atomic.LoadInt32(&tries); tries != 2 {
+		t.Errorf("the webhook was tried %d times, want 2", tries)
+	}
+
+	var payload map[string]string
+	if err := json.Unmarshal(<-received, &payload); err != nil {
+		t.Fatalf("the webhook was sent something that isn't JSON: %v", err)
+	}
+	want := map[string]string{"slug": "hello", "title": "Hello world", "url": "https://blog.example.com/post/hello"}
+	for key, value := range want {
+		if payload[key] != value {
+			t.Errorf("the webhook's %s is %q, want %q", key, payload[key], value)
+		}
+	}
+}
+
+func TestHTTPWebhookGivesUp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "broken", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	h := &httpWebhook{url: server.URL, client: server.Client()}
+
+	// Done before the first retry, so it's given up on rather than waited out
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := h.Published(ctx, PublishEvent{Slug: "hello"})
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("Published to a broken webhook = %v, want the status it responded", err)
+	}
+	if elapsed := time.Since(start); elapsed > WEBHOOK_RETRY_DELAY {
+		t.Errorf("Published took %s, want it to stop when its context was done", elapsed)
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	body := []byte(`{"slug":"hello"}`)
+	sig := webhookSignature([]byte("shh"), body)
+	if !strings.HasPrefix(sig, "sha256=") || len(sig) != len("sha256=")+64 {
+		t.Errorf("webhookSignature = %q, want sha256= and a hex SHA-256", sig)
+	}
+	if webhookSignature([]byte("shh"), body) != sig {
+		t.Error("webhookSignature isn't the same for the same body")
+	}
+	if webhookSignature([]byte("other"), body) == sig || webhookSignature([]byte("shh"), []byte(`{"slug":"bye"}`)) == sig {
+		t.Error("webhookSignature is the same for a different secret or body")
+	}
+}